@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backups of the index and work tree are kept as refs under backupRefPrefix
+// so they survive a crash and are never garbage collected while referenced.
+// Any feature that mutates the index must call createBackup first.
+const (
+	backupRefPrefix = "refs/gitcommit/backup/"
+	maxBackups      = 5
+)
+
+func gitRun(args ...string) (string, error) {
+	output, err := gitCommand(args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// createBackup records the current index and work tree as a commit under
+// refs/gitcommit/backup and prints how to get back to it.
+func createBackup() (string, error) {
+	// `git stash create` makes a dangling stash commit (work tree, with the
+	// index as second parent) without touching anything. It prints nothing
+	// when there are no local changes.
+	var sha string
+	if hasHead() {
+		var err error
+		if sha, err = gitRun("stash", "create", "gitcommit backup"); err != nil {
+			return "", fmt.Errorf("error creating backup: %v", err)
+		}
+	}
+	if sha == "" {
+		tree, err := gitRun("write-tree")
+		if err != nil {
+			return "", fmt.Errorf("error creating backup: %v", err)
+		}
+		args := []string{"commit-tree", tree, "-m", "gitcommit backup"}
+		if hasHead() {
+			args = append(args, "-p", "HEAD")
+		}
+		sha, err = gitRun(args...)
+		if err != nil {
+			return "", fmt.Errorf("error creating backup: %v", err)
+		}
+	}
+
+	ref := backupRefPrefix + time.Now().UTC().Format("20060102T150405.000000000")
+	if _, err := gitRun("update-ref", ref, sha); err != nil {
+		return "", fmt.Errorf("error saving backup: %v", err)
+	}
+	pruneBackups(maxBackups)
+	fmt.Printf("Saved a backup of your index and work tree. If anything goes wrong, run:\n  gitcommit restore-snapshot\n")
+	return ref, nil
+}
+
+// listBackups returns backup refs, newest first.
+func listBackups() ([]string, error) {
+	output, err := gitRun("for-each-ref", "--format=%(refname)", backupRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if output == "" {
+		return nil, nil
+	}
+	refs := strings.Split(output, "\n")
+	sort.Sort(sort.Reverse(sort.StringSlice(refs)))
+	return refs, nil
+}
+
+// pruneBackups keeps only the newest keep backups.
+func pruneBackups(keep int) {
+	refs, err := listBackups()
+	if err != nil {
+		return
+	}
+	for i := keep; i < len(refs); i++ {
+		gitRun("update-ref", "-d", refs[i])
+	}
+}
+
+// dropBackup deletes a backup once the operation it protected has finished.
+func dropBackup(ref string) {
+	if ref != "" {
+		gitRun("update-ref", "-d", ref)
+	}
+}
+
+// restoreBackup puts the index and tracked work tree files back the way
+// they were when the backup was taken. Commits made since are left alone.
+func restoreBackup(ref string) error {
+	sha, err := gitRun("rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("backup %s not found", ref)
+	}
+	indexTree := sha + "^{tree}"
+	if _, err := gitRun("rev-parse", "--verify", "--quiet", sha+"^2"); err == nil {
+		// Stash commit: the index is recorded as the second parent.
+		indexTree = sha + "^2^{tree}"
+	}
+
+	if _, err := gitRun("restore", "--source="+sha, "--worktree", "--", ":/"); err != nil {
+		return fmt.Errorf("error restoring work tree: %v", err)
+	}
+	if _, err := gitRun("read-tree", indexTree); err != nil {
+		return fmt.Errorf("error restoring index: %v", err)
+	}
+	return nil
+}
+
+// runRestoreSnapshot implements `gitcommit restore-snapshot [ref]`.
+func runRestoreSnapshot(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: gitcommit restore-snapshot [ref]")
+	}
+	var ref string
+	if len(args) == 1 {
+		ref = args[0]
+	} else {
+		refs, err := listBackups()
+		if err != nil {
+			return err
+		}
+		if len(refs) == 0 {
+			return fmt.Errorf("no backups found")
+		}
+		ref = refs[0]
+	}
+
+	head, _ := gitRun("rev-parse", "--verify", "--quiet", ref+"^1")
+	if err := restoreBackup(ref); err != nil {
+		return err
+	}
+	fmt.Printf("Restored index and work tree from %s.\n", ref)
+	if current, _ := gitRun("rev-parse", "--verify", "--quiet", "HEAD"); head != "" && current != head {
+		fmt.Printf("Commits made since the backup were kept; to undo them run:\n  git reset --soft %s\n", head)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// batchNoQuestions is added to every batch request, since nobody is there
+// to answer a question.
+const batchNoQuestions = "This commit is made unattended; don't ask questions. If something is unclear, write the best message the diff supports."
+
+// batchEntry is one repository in a batch manifest.
+type batchEntry struct {
+	Repo string
+	// All commits every tracked change, as with -a, instead of what is
+	// staged.
+	All bool
+}
+
+// batchResult is the outcome for one repository, as listed in the report.
+type batchResult struct {
+	Repo    string `json:"repo"`
+	Status  string `json:"status"` // committed, suggested, skipped or failed
+	Commit  string `json:"commit,omitempty"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// parseBatchManifest reads a manifest: one repository per line, optionally
+// followed by "all" to commit all tracked changes rather than the staged
+// ones. Relative paths are relative to the manifest; blank lines and lines
+// starting with # are ignored.
+func parseBatchManifest(text, dir string) ([]batchEntry, error) {
+	var entries []batchEntry
+	for i, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		e := batchEntry{Repo: fields[0]}
+		for _, f := range fields[1:] {
+			switch f {
+			case "all":
+				e.All = true
+			case "staged":
+			default:
+				return nil, fmt.Errorf("line %d: unknown option %q (use all or staged)", i+1, f)
+			}
+		}
+		if !filepath.IsAbs(e.Repo) {
+			e.Repo = filepath.Join(dir, e.Repo)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("the manifest lists no repositories")
+	}
+	return entries, nil
+}
+
+// batchCommit writes and, unless dryRun, commits a message in one
+// repository. It reuses the server's request handling, which already runs
+// one request per repository without prompting.
+func batchCommit(s *server, e batchEntry, intent string, context []string, dryRun, noVerify bool) batchResult {
+	r := batchResult{Repo: e.Repo}
+	fail := func(err error) batchResult {
+		r.Status, r.Error = "failed", err.Error()
+		if errors.Is(err, errNoStagedChanges) {
+			r.Status = "skipped"
+		}
+		return r
+	}
+	res, err := s.generate(generateParams{Repo: e.Repo, Message: intent, All: e.All, Context: context})
+	if err != nil {
+		return fail(err)
+	}
+	g := res.(generateResult)
+	if g.Message == "" {
+		r.Status, r.Error = "failed", fmt.Sprintf("Claude asked a question instead of writing a message: %s", strings.TrimSpace(g.Question))
+		return r
+	}
+	r.Message = g.Message
+	if problems := validateMessage(g.Message); len(problems) > 0 {
+		r.Status, r.Error = "failed", "the message has problems: "+strings.Join(problems, "; ")
+		return r
+	}
+	if dryRun {
+		r.Status = "suggested"
+		return r
+	}
+	if _, err := s.commit(commitParams{Repo: e.Repo, Message: g.Message, All: e.All, NoVerify: noVerify}); err != nil {
+		return fail(err)
+	}
+	r.Commit, _ = gitRun("-C", e.Repo, "rev-parse", "--short", "HEAD")
+	r.Status = "committed"
+	return r
+}
+
+// runBatch implements `gitcommit batch [--message text] [--style text]
+// [--report file] [--dry-run] [--no-verify] manifest`. It never prompts:
+// a repository that fails is reported and the rest carry on.
+func runBatch(args []string, profileName string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	intent := fs.String("message", "", "what the change is, shared by every repository (e.g. the codemod that made it)")
+	style := fs.String("style", "", "style instructions shared by every message")
+	reportPath := fs.String("report", "", "also write the results as JSON to this file")
+	dryRun := fs.Bool("dry-run", false, "write the messages but don't commit")
+	noVerify := fs.Bool("no-verify", false, "skip git hooks when committing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: gitcommit batch [--message text] [--style text] [--report file] [--dry-run] [--no-verify] manifest")
+	}
+	manifest, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return fmt.Errorf("error reading manifest: %v", err)
+	}
+	entries, err := parseBatchManifest(string(data), filepath.Dir(manifest))
+	if err != nil {
+		return fmt.Errorf("%s: %v", fs.Arg(0), err)
+	}
+
+	apiKey, opts, err := subcommandAPI(profileName)
+	if err != nil {
+		return err
+	}
+	s, err := newServer(profileName, apiKey, opts)
+	if err != nil {
+		return err
+	}
+	context := []string{batchNoQuestions}
+	if *style != "" {
+		context = append(context, "Style for this message: "+*style)
+	}
+
+	var results []batchResult
+	counts := map[string]int{}
+	for i, e := range entries {
+		r := batchCommit(s, e, *intent, context, *dryRun, *noVerify)
+		results = append(results, r)
+		counts[r.Status]++
+		subject, _, _ := strings.Cut(r.Message, "\n")
+		switch r.Status {
+		case "committed":
+			fmt.Printf("[%d/%d] %s: committed %s %s\n", i+1, len(entries), e.Repo, r.Commit, subject)
+		case "suggested":
+			fmt.Printf("[%d/%d] %s: would commit:\n%s\n", i+1, len(entries), e.Repo, "    "+strings.ReplaceAll(r.Message, "\n", "\n    "))
+		default:
+			fmt.Printf("[%d/%d] %s: %s: %s\n", i+1, len(entries), e.Repo, r.Status, r.Error)
+		}
+	}
+
+	fmt.Printf("\n%d repositories: %d committed, %d suggested, %d skipped, %d failed\n",
+		len(entries), counts["committed"], counts["suggested"], counts["skipped"], counts["failed"])
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding report: %v", err)
+		}
+		if err := os.WriteFile(*reportPath, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("error writing report: %v", err)
+		}
+	}
+	if counts["failed"] > 0 {
+		return fmt.Errorf("%d of %d repositories failed", counts["failed"], len(entries))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bodyLimit caps the body of a message for changes of up to
+// MaxChangedLines added plus removed lines.
+type bodyLimit struct {
+	MaxChangedLines int `json:"max_changed_lines"`
+	MaxBodyLines    int `json:"max_body_lines"`
+}
+
+// defaultBodyLimits keep tiny changes to a subject, or a subject and a
+// couple of lines; larger changes are left alone.
+var defaultBodyLimits = []bodyLimit{
+	{MaxChangedLines: 3, MaxBodyLines: 0},
+	{MaxChangedLines: 10, MaxBodyLines: 2},
+	{MaxChangedLines: 40, MaxBodyLines: 6},
+}
+
+// maxShortenings bounds the automatic "make it shorter" requests for one
+// run, after which a long body is shown as it is.
+const maxShortenings = 2
+
+// bodyLineLimit returns the body lines allowed for a change of n lines:
+// the tightest bucket n fits in, or -1 for no limit.
+func bodyLineLimit(limits []bodyLimit, n int) int {
+	if limits == nil {
+		limits = defaultBodyLimits
+	}
+	best := -1
+	bestSize := 0
+	for _, l := range limits {
+		if n <= l.MaxChangedLines && (best < 0 || l.MaxChangedLines < bestSize) {
+			best, bestSize = l.MaxBodyLines, l.MaxChangedLines
+		}
+	}
+	return best
+}
+
+// bodyLimitTokens is the response cap for a body limit; a short message
+// never needs the default 4096 tokens.
+func bodyLimitTokens(limit int) int {
+	if limit == 0 {
+		return 256
+	}
+	return 512
+}
+
+// bodyLimitPrompt asks for a message in proportion to a small change.
+func bodyLimitPrompt(changed, limit int) string {
+	if limit == 0 {
+		return fmt.Sprintf("This is a small change (%d changed lines). Write only a subject line, with no body.", changed)
+	}
+	return fmt.Sprintf("This is a small change (%d changed lines). Write a subject and at most %d body lines; don't pad it out.", changed, limit)
+}
+
+// bodyLines counts the non-blank body lines of a message, leaving out
+// trailers.
+func bodyLines(message string) int {
+	_, body := splitMessage(message)
+	n := 0
+	for _, line := range strings.Split(body, "\n") {
+		if strings.TrimSpace(line) != "" && !trailerLineRe.MatchString(line) {
+			n++
+		}
+	}
+	return n
+}
+
+// shortenPrompt asks again for a suggestion whose body was out of
+// proportion to the change.
+func shortenPrompt(message string, limit int) string {
+	want := fmt.Sprintf("a body of at most %d lines", limit)
+	if limit == 0 {
+		want = "only a subject line"
+	}
+	return fmt.Sprintf("\n\nYou suggested:\n```\n%s\n```\nThat is too long for a change this small. Make it shorter: %s.", message, want)
+}
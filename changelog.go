@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultChangelogValues are the Changelog: trailer categories when the
+// repository doesn't list its own.
+var defaultChangelogValues = []string{"added", "changed", "fixed", "removed", "none"}
+
+// changelogSchema describes the Changelog: and Component: trailers a
+// repository wants, from the [changelog] table in its .gitcommit.toml:
+//
+//	[changelog]
+//	values = "added,changed,fixed,removed,none"
+//	required = true
+//	components = "api,cli,docs"
+//	component_required = false
+type changelogSchema struct {
+	Values   []string
+	Required bool
+	// Component asks for a Component: trailer too. Components, when set,
+	// are the only values allowed for it.
+	Component         bool
+	Components        []string
+	ComponentRequired bool
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// loadChangelogSchema reads the repository's trailer schema. It returns
+// nil when the trailers are neither asked for (-changelog) nor required
+// by the repository.
+func loadChangelogSchema(enabled bool) (*changelogSchema, error) {
+	values, _, err := readRepoFile()
+	if err != nil {
+		return nil, err
+	}
+	s := &changelogSchema{
+		Values:            splitList(values["changelog.values"]),
+		Required:          values["changelog.required"] == "true",
+		Components:        splitList(values["changelog.components"]),
+		ComponentRequired: values["changelog.component_required"] == "true",
+	}
+	if !enabled && !s.Required && !s.ComponentRequired {
+		return nil, nil
+	}
+	if len(s.Values) == 0 {
+		s.Values = defaultChangelogValues
+	}
+	s.Component = values["changelog.component"] == "true" || len(s.Components) > 0 || s.ComponentRequired
+	return s, nil
+}
+
+func allowed(list []string, v string) bool {
+	for _, a := range list {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+// prompt asks the model to propose the trailers.
+func (s *changelogSchema) prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "End the message with a trailer line \"Changelog: <category>\" for release notes, where the category is exactly one of: %s.", strings.Join(s.Values, ", "))
+	if allowed(s.Values, "none") {
+		b.WriteString(" Use none for changes users won't notice.")
+	}
+	if s.Component {
+		b.WriteString(" Follow it with \"Component: <component>\" naming the part of the project changed")
+		if len(s.Components) > 0 {
+			fmt.Fprintf(&b, ", exactly one of: %s", strings.Join(s.Components, ", "))
+		}
+		b.WriteString(".")
+	}
+	return b.String()
+}
+
+// trailerValue returns the value of the named trailer in the message's
+// last paragraph, or "" if it has none.
+func trailerValue(message, key string) string {
+	paragraphs := strings.Split(strings.TrimRight(message, "\n"), "\n\n")
+	if len(paragraphs) < 2 {
+		return ""
+	}
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// setTrailer sets the named trailer, replacing one already in the
+// trailer block or starting a block if there is none.
+func setTrailer(message, key, value string) string {
+	message = strings.TrimRight(message, "\n")
+	paragraphs := strings.Split(message, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if len(paragraphs) > 1 && isTrailerBlock(last) {
+		var kept []string
+		for _, line := range strings.Split(last, "\n") {
+			if k, _, _ := strings.Cut(line, ":"); !strings.EqualFold(strings.TrimSpace(k), key) {
+				kept = append(kept, line)
+			}
+		}
+		kept = append(kept, key+": "+value)
+		paragraphs[len(paragraphs)-1] = strings.Join(kept, "\n")
+		return strings.Join(paragraphs, "\n\n")
+	}
+	return appendToBody(message, key+": "+value)
+}
+
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerLineRe.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// confirm shows the proposed trailer values and asks for each to be
+// accepted or replaced by an allowed one, then writes them into the
+// message. A proposal outside the allowed values is not offered.
+func (s *changelogSchema) confirm(message string) string {
+	category := strings.ToLower(trailerValue(message, "Changelog"))
+	if !allowed(s.Values, category) {
+		category = ""
+	}
+	category = askTrailer("Changelog", category, s.Values, true)
+	message = setTrailer(message, "Changelog", category)
+	if !s.Component {
+		return message
+	}
+	component := strings.ToLower(trailerValue(message, "Component"))
+	if len(s.Components) > 0 && !allowed(s.Components, component) {
+		component = ""
+	}
+	if component = askTrailer("Component", component, s.Components, s.ComponentRequired); component != "" {
+		message = setTrailer(message, "Component", component)
+	}
+	return message
+}
+
+// askTrailer asks for a trailer value until it is one of choices (any
+// value when choices is empty). Enter accepts the proposal, or skips an
+// optional trailer that has none.
+func askTrailer(key, proposed string, choices []string, required bool) string {
+	for {
+		var answer string
+		switch {
+		case proposed != "":
+			answer = ask("%s: %s (Enter to accept, or type another): ", key, proposed)
+		case len(choices) > 0:
+			answer = ask("%s (%s): ", key, strings.Join(choices, "/"))
+		default:
+			answer = ask("%s (Enter for none): ", key)
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer == "" {
+			answer = proposed
+		}
+		if answer == "" && !required {
+			return ""
+		}
+		if answer != "" && (len(choices) == 0 || allowed(choices, answer)) {
+			return answer
+		}
+		if len(choices) > 0 {
+			fmt.Printf("%s must be one of: %s\n", key, strings.Join(choices, ", "))
+		} else {
+			fmt.Printf("%s is required.\n", key)
+		}
+	}
+}
+
+// problems lists what the message lacks for the schema: a missing
+// required trailer, or a value that isn't allowed.
+func (s *changelogSchema) problems(message string) []string {
+	var problems []string
+	check := func(key, value string, choices []string, required bool) {
+		switch {
+		case value == "" && required:
+			if len(choices) > 0 {
+				problems = append(problems, fmt.Sprintf("add a %s: trailer (one of %s)", key, strings.Join(choices, ", ")))
+			} else {
+				problems = append(problems, fmt.Sprintf("add a %s: trailer", key))
+			}
+		case value != "" && len(choices) > 0 && !allowed(choices, strings.ToLower(value)):
+			problems = append(problems, fmt.Sprintf("%s: %q isn't one of %s", key, value, strings.Join(choices, ", ")))
+		}
+	}
+	check("Changelog", trailerValue(message, "Changelog"), s.Values, s.Required)
+	if s.Component {
+		check("Component", trailerValue(message, "Component"), s.Components, s.ComponentRequired)
+	}
+	return problems
+}
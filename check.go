@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkStep is one part of a -check run and how it went.
+type checkStep struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// checkEnv prepares the variables the check's git commands and hooks run
+// with. For -a it stages the tracked changes into a copy of the index, as
+// git commit -a does, so the real index is left alone.
+func checkEnv(all bool) ([]string, func(), error) {
+	env := []string{"GIT_EDITOR=:"}
+	if !all {
+		return env, func() {}, nil
+	}
+	index, err := gitRun("rev-parse", "--git-path", "index")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error locating the index: %v", err)
+	}
+	tmp, err := os.CreateTemp("", "gitcommit-index-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error copying the index: %v", err)
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+	src, err := os.Open(index)
+	if err == nil {
+		_, err = io.Copy(tmp, src)
+		src.Close()
+	}
+	tmp.Close()
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error copying the index: %v", err)
+	}
+	env = append(env, "GIT_INDEX_FILE="+tmp.Name())
+	cmd := gitCommandEnv(env, "add", "-u")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error staging tracked changes: %v\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return env, cleanup, nil
+}
+
+// runCheckHook runs one hook through git hook run, from the top of the
+// work tree as git commit does. Missing hooks are reported, not run.
+func runCheckHook(name, dir, top string, env []string, args ...string) checkStep {
+	step := checkStep{Name: name + " hook", OK: true}
+	if _, ok := findHook(dir, name); !ok {
+		step.Detail = "not installed"
+		return step
+	}
+	cmd := gitCommandEnv(env, append([]string{"hook", "run", name, "--"}, args...)...)
+	cmd.Dir = top
+	output, err := cmd.CombinedOutput()
+	out := strings.TrimSpace(string(output))
+	if err != nil {
+		step.OK = false
+		step.Detail = fmt.Sprintf("would reject the commit (%v)", err)
+		if out != "" {
+			step.Detail += "\n" + out
+		}
+		return step
+	}
+	step.Detail = "passed"
+	if out != "" {
+		step.Detail += "\n" + out
+	}
+	return step
+}
+
+// checkCommit goes through what `git commit` would do with message without
+// creating a commit: git commit --dry-run for the changes, the message
+// cleanup, and the pre-commit, prepare-commit-msg and commit-msg hooks run
+// against the message in a temporary file. It returns every step, stopping
+// at the first failure as git would, and the message as the hooks left it.
+func checkCommit(message string, all, noVerify bool, cleanup string) ([]checkStep, string, error) {
+	env, done, err := checkEnv(all)
+	if err != nil {
+		return nil, "", err
+	}
+	defer done()
+	var steps []checkStep
+
+	cmd := gitCommandEnv(env, "commit", "--dry-run", "--short")
+	output, err := cmd.Output()
+	if err != nil {
+		steps = append(steps, checkStep{Name: "changes", Detail: "nothing to commit"})
+		return steps, message, nil
+	}
+	n := 0
+	for _, line := range strings.Split(string(output), "\n") {
+		if len(line) > 2 && line[0] != ' ' && line[0] != '?' {
+			n++
+		}
+	}
+	steps = append(steps, checkStep{Name: "changes", OK: true, Detail: fmt.Sprintf("%d %s to commit", n, plural(n, "path", "paths"))})
+
+	if noVerify {
+		steps = append(steps, checkStep{Name: "hooks", OK: true, Detail: "skipped (-no-verify)"})
+	} else {
+		dir, err := resolveHooksDir()
+		if err != nil {
+			return nil, "", err
+		}
+		top, err := gitRun("rev-parse", "--show-toplevel")
+		if err != nil {
+			return nil, "", fmt.Errorf("error locating work tree: %v", err)
+		}
+		step := runCheckHook("pre-commit", dir, top, env)
+		steps = append(steps, step)
+		if !step.OK {
+			return steps, message, nil
+		}
+
+		f, err := os.CreateTemp("", "gitcommit-check-*.txt")
+		if err != nil {
+			return nil, "", fmt.Errorf("error creating temp file: %v", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(message)
+		f.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("error writing to temp file: %v", err)
+		}
+		msgFile, _ := filepath.Abs(f.Name())
+		for _, h := range []struct {
+			name string
+			args []string
+		}{
+			{"prepare-commit-msg", []string{msgFile, "message"}},
+			{"commit-msg", []string{msgFile}},
+		} {
+			step := runCheckHook(h.name, dir, top, env, h.args...)
+			steps = append(steps, step)
+			if !step.OK {
+				return steps, message, nil
+			}
+		}
+		data, err := os.ReadFile(msgFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading the message back: %v", err)
+		}
+		message = string(data)
+	}
+
+	cleaned, err := stripspace(message, cleanup)
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.TrimSpace(cleaned) == "" {
+		steps = append(steps, checkStep{Name: "message", Detail: "empty after cleanup; git would abort the commit"})
+	} else {
+		steps = append(steps, checkStep{Name: "message", OK: true, Detail: "not empty after cleanup"})
+	}
+	return steps, message, nil
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// printCheck reports the steps and whether the commit would succeed,
+// naming the step that would stop it. It returns true on success.
+func printCheck(steps []checkStep, intended, final string) bool {
+	fmt.Println("\nCheck (nothing was committed):")
+	failed := ""
+	for _, s := range steps {
+		mark := "ok  "
+		if !s.OK {
+			mark = "FAIL"
+			failed = s.Name
+		}
+		first, rest, _ := strings.Cut(s.Detail, "\n")
+		fmt.Printf("  %s %s: %s\n", mark, s.Name, first)
+		if rest != "" {
+			fmt.Printf("       %s\n", strings.ReplaceAll(rest, "\n", "\n       "))
+		}
+	}
+	if failed == "" && strings.TrimSpace(final) != strings.TrimSpace(intended) {
+		fmt.Printf("The hooks would change the message to:\n%s\n", strings.TrimSpace(final))
+	}
+	if failed != "" {
+		fmt.Printf("Result: the commit would fail (%s).\n", failed)
+		return false
+	}
+	fmt.Println("Result: the commit would succeed.")
+	return true
+}
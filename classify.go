@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// responseKind is what a model response is taken to be.
+type responseKind int
+
+const (
+	responseQuestion responseKind = iota
+	responseMessage
+)
+
+func (k responseKind) String() string {
+	if k == responseMessage {
+		return "a commit message"
+	}
+	return "a question"
+}
+
+// minConfidence is the classification confidence below which the user is
+// asked whether a response is a message or a question instead of guessing.
+const minConfidence = 0.6
+
+// classification is the outcome of classifyResponse. Message holds the
+// candidate commit message whenever one was found, even if the response is
+// classified as a question, so the user can still pick it.
+type classification struct {
+	Kind       responseKind
+	Message    string
+	Confidence float64
+	Reason     string
+}
+
+// preambleRe matches a lead-in line such as "Here is your commit message:".
+var preambleRe = regexp.MustCompile(`(?i)^(?:here(?:'s| is)|sure|okay|ok|certainly)\b[^\n]*:$`)
+
+// frontmatterKeyRe matches a YAML "key: value" line.
+var frontmatterKeyRe = regexp.MustCompile(`^[A-Za-z_][\w-]*:`)
+
+// stripFrontmatter drops a leading YAML frontmatter block ("---" lines
+// around key: value pairs), which some responses start with.
+func stripFrontmatter(text string) string {
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		return text
+	}
+	block, after, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		return text
+	}
+	for _, line := range strings.Split(block, "\n") {
+		if line != "" && !frontmatterKeyRe.MatchString(line) && !strings.HasPrefix(line, " ") {
+			return text
+		}
+	}
+	return after
+}
+
+// stripPreamble removes a lead-in line from the start of text.
+func stripPreamble(text string) (string, bool) {
+	first, rest, _ := strings.Cut(text, "\n")
+	if !preambleRe.MatchString(strings.TrimSpace(first)) {
+		return text, false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// outsideFence returns the text of response outside its first fenced block.
+func outsideFence(response, message string) string {
+	i := strings.Index(response, message)
+	if i < 0 {
+		return response
+	}
+	return response[:i] + response[i+len(message):]
+}
+
+// hasQuestion reports whether any line of text ends with a question mark.
+func hasQuestion(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasSuffix(strings.TrimSpace(line), "?") {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeMessage reports whether unfenced text has the shape of a commit
+// message: a short first line, then a blank line and a body.
+func looksLikeMessage(text string) bool {
+	subject, body := splitMessage(text)
+	return subject != "" && len(subject) <= maxSubjectLength && !strings.HasSuffix(subject, "?") &&
+		strings.TrimSpace(body) != "" && strings.Contains(text, "\n\n")
+}
+
+// classifyResponse decides whether a response is a commit message or a
+// clarifying question. It tolerates the usual oddities: CRLF line endings,
+// zero-width characters around fences, YAML frontmatter, a "Here is your
+// commit message:" lead-in with or without a fence, and fences nested in
+// the message. Responses that mix a message with a question, or whose only
+// candidate is unfenced, get a low confidence so the caller can ask the
+// user. The message is not sanitized here; offerSanitized shows the user
+// what sanitizing would change.
+func classifyResponse(response string) classification {
+	clean := stripFrontmatter(strings.TrimSpace(strings.ReplaceAll(response, "\r\n", "\n")))
+
+	if message := extractCommitMessage(clean); message != "" {
+		message, _ = stripPreamble(message)
+		if message == "" {
+			return classification{Kind: responseQuestion, Confidence: 0.5, Reason: "the fenced block has only a lead-in"}
+		}
+		if hasQuestion(outsideFence(clean, message)) {
+			return classification{Kind: responseMessage, Message: message, Confidence: 0.4, Reason: "the response has both a message and a question"}
+		}
+		if subject, body := splitMessage(message); strings.HasSuffix(subject, "?") && strings.TrimSpace(body) == "" {
+			return classification{Kind: responseMessage, Message: message, Confidence: 0.5, Reason: "the fenced message reads like a question"}
+		}
+		return classification{Kind: responseMessage, Message: message, Confidence: 0.95, Reason: "fenced message"}
+	}
+
+	// A fence that is never closed, as in a response cut off at the token
+	// limit, still opens the candidate message.
+	if first, rest, ok := strings.Cut(clean, "\n"); ok && strings.HasPrefix(strings.TrimSpace(first), "```") && !strings.Contains(rest, "```") {
+		clean = strings.TrimSpace(rest)
+	}
+
+	if rest, ok := stripPreamble(clean); ok && rest != "" {
+		if hasQuestion(rest) {
+			return classification{Kind: responseMessage, Message: rest, Confidence: 0.4, Reason: "an unfenced message after a lead-in, with a question"}
+		}
+		return classification{Kind: responseMessage, Message: rest, Confidence: 0.7, Reason: "an unfenced message after a lead-in"}
+	}
+	if looksLikeMessage(clean) && !hasQuestion(clean) {
+		return classification{Kind: responseQuestion, Message: clean, Confidence: 0.5, Reason: "no fence, but the text is shaped like a commit message"}
+	}
+	return classification{Kind: responseQuestion, Confidence: 0.9, Reason: "no commit message"}
+}
+
+// confirmKind asks the user what a borderline response is. Answering m
+// treats it as a message, using the whole response when no candidate
+// message was found.
+func confirmKind(response string, c classification) classification {
+	fmt.Printf("\nThe response is ambiguous (%s):\n%s\n", c.Reason, strings.TrimSpace(response))
+	for {
+		switch getUserInput("Treat it as a commit message (m) or a question to answer (q)? ") {
+		case "m":
+			c.Kind = responseMessage
+			if c.Message == "" {
+				c.Message = strings.TrimSpace(strings.ReplaceAll(response, "\r\n", "\n"))
+			}
+			c.Confidence = 1
+			return c
+		case "q":
+			c.Kind = responseQuestion
+			c.Confidence = 1
+			return c
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// readFuzzString reads a one-string file from a Go fuzz corpus.
+func readFuzzString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, value, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+	if header != "go test fuzz v1" || !strings.HasPrefix(value, "string(") {
+		t.Fatalf("%s is not a one-string fuzz corpus file", path)
+	}
+	s, err := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(value, "string("), ")"))
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return s
+}
+
+func TestClassifyResponse(t *testing.T) {
+	tests := []struct {
+		name, response string
+		kind           responseKind
+		confidence     float64
+		message        string
+	}{
+		{"fenced", "```\nFix the parser\n\nIt dropped tokens.\n```", responseMessage, 0.95, "Fix the parser\n\nIt dropped tokens."},
+		{"fenced with language", "```text\nFix the parser\n```", responseMessage, 0.95, "Fix the parser"},
+		{"fenced question", "```\nShould this be a fix?\n```", responseMessage, 0.5, "Should this be a fix?"},
+		{"unfenced message", "Fix the parser\n\nIt dropped tokens.", responseQuestion, 0.5, "Fix the parser\n\nIt dropped tokens."},
+		{"unfenced question", "Which bug does this fix?", responseQuestion, 0.9, ""},
+		{"unfenced question with body", "Which bug does this fix?\n\nThe diff touches two parsers.", responseQuestion, 0.9, ""},
+		{"preamble fenced", "Here is your commit message:\n```\nFix the parser\n```", responseMessage, 0.95, "Fix the parser"},
+		{"preamble in fence", "```\nHere's the commit message:\nFix the parser\n```", responseMessage, 0.95, "Fix the parser"},
+		{"preamble unfenced", "Sure, here it is:\nFix the parser\n\nIt dropped tokens.", responseMessage, 0.7, "Fix the parser\n\nIt dropped tokens."},
+		{"preamble unfenced with question", "Okay:\nFix the parser\n\nOr is it a refactor?", responseMessage, 0.4, "Fix the parser\n\nOr is it a refactor?"},
+		{"only preamble fenced", "```\nHere is your commit message:\n```", responseQuestion, 0.5, ""},
+		{"mixed question after", "```\nFix the parser\n```\nDid you also mean to change the lexer?", responseMessage, 0.4, "Fix the parser"},
+		{"mixed question before", "Is this for the release branch?\n```\nFix the parser\n```", responseMessage, 0.4, "Fix the parser"},
+		{"frontmatter", "---\ntype: fix\nscope: parser\n---\n```\nfix(parser): Keep the last token\n```", responseMessage, 0.95, "fix(parser): Keep the last token"},
+		{"frontmatter unfenced", "---\ntype: fix\n---\nHere is the message:\nfix: Keep the last token", responseMessage, 0.7, "fix: Keep the last token"},
+		{"rule not frontmatter", "---\nnot yaml at all\n---\nWhat changed?", responseQuestion, 0.9, ""},
+	}
+	for _, tt := range tests {
+		c := classifyResponse(tt.response)
+		if c.Kind != tt.kind || c.Confidence != tt.confidence || c.Message != tt.message {
+			t.Errorf("%s: got %v (confidence %.2f, %s) with message %q; want %v (confidence %.2f) with message %q",
+				tt.name, c.Kind, c.Confidence, c.Reason, c.Message, tt.kind, tt.confidence, tt.message)
+		}
+	}
+}
+
+// TestResponseCorpus checks the responses in the fuzz corpus against what
+// they should be taken for. Confident means the run goes on without asking
+// the user. The reply-* entries are whole replies in the shape the API
+// gives them, with the lead-ins, closing remarks and nested fences models
+// write; they were written by hand to match, not recorded from the API.
+func TestResponseCorpus(t *testing.T) {
+	tests := map[string]struct {
+		kind      responseKind
+		confident bool
+		message   string
+	}{
+		"nested-fences":         {responseMessage, true, "Add a usage example to the README\n\nShow the client setup:\n\n```go\nc := client.New()\nc.Run()\n```\n\nand how to stop it."},
+		"question-and-message":  {responseMessage, false, "Fix the retry loop\n\nStop retrying on 4xx responses."},
+		"preamble-fenced":       {responseMessage, true, "Rename config loader\n\nThe old name suggested it also wrote the file."},
+		"preamble-inside-fence": {responseMessage, true, "Rename config loader"},
+		"preamble-unfenced":     {responseMessage, true, "Bump the timeout to 30s\n\nSlow CI runners hit the old limit."},
+		"yaml-frontmatter":      {responseMessage, true, "fix(api): Handle empty pages\n\nThe paginator stopped on an empty page instead of ending."},
+		"zero-width":            {responseMessage, true, "Drop the unused\u200b import"},
+		"plain-question":        {responseQuestion, true, ""},
+		"unfenced-message":      {responseQuestion, false, "Add retries to the uploader\n\nUploads now retry three times with backoff."},
+		"crlf":                  {responseMessage, true, "Normalize line endings\n\nThe editor wrote CRLF."},
+		"single-line-fence":     {responseMessage, true, "Update the changelog"},
+		"unterminated-fence":    {responseQuestion, false, "Fix the parser\n\nIt dropped the last token."},
+		"fenced-question":       {responseMessage, false, "Is this a refactor or a fix?"},
+		"only-lead-in-fenced":   {responseQuestion, false, ""},
+
+		"reply-fenced":               {responseMessage, true, "Handle nil options in NewClient\n\nNewClient dereferenced opts before checking it, so callers passing nil\ngot a panic instead of the defaults. Fall back to defaultOptions when\nopts is nil."},
+		"reply-unfenced":             {responseQuestion, false, "Add pagination to the /users endpoint\n\nThe handler returned every user in one response, which timed out for\nlarge tenants. Accept page and per_page query parameters, defaulting\nto 50 users per page."},
+		"reply-nested-fences":        {responseMessage, true, "Document the retry settings\n\nAdd a section to docs/config.md with the new options:\n\n```yaml\nretry:\n  attempts: 3\n  backoff: 200ms\n```\n\nThe defaults match the old hard-coded behavior."},
+		"reply-preamble":             {responseMessage, true, "Remove the deprecated --legacy flag\n\nThe flag has printed a deprecation warning since v2.3, and nothing in\nthe repository uses it any more."},
+		"reply-question":             {responseQuestion, true, ""},
+		"reply-message-and-question": {responseMessage, false, "Raise the upload limit to 50 MB"},
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "fuzz", "FuzzClassifyResponse", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := 0
+	for _, f := range files {
+		name := filepath.Base(f)
+		want, ok := tests[name]
+		if !ok {
+			// Inputs added by go test -fuzz have no expected outcome.
+			continue
+		}
+		seen++
+		c := classifyResponse(readFuzzString(t, f))
+		if c.Kind != want.kind || (c.Confidence >= minConfidence) != want.confident || c.Message != want.message {
+			t.Errorf("%s: got %v (confidence %.2f, %s) with message %q; want %v, confident %v, message %q",
+				name, c.Kind, c.Confidence, c.Reason, c.Message, want.kind, want.confident, want.message)
+		}
+	}
+	if seen != len(tests) {
+		t.Errorf("found %d of the %d corpus files", seen, len(tests))
+	}
+}
+
+// TestClassifyLeavesSanitizingToUser checks that invisible characters in a
+// response reach offerSanitized, where the user can keep or drop them.
+func TestClassifyLeavesSanitizingToUser(t *testing.T) {
+	c := classifyResponse(readFuzzString(t, filepath.Join("testdata", "fuzz", "FuzzClassifyResponse", "zero-width")))
+	withInput(t, "n\ny\n")
+	if got := offerSanitized(c.Message, false); got != c.Message {
+		t.Errorf("declining gave %q, want %q", got, c.Message)
+	}
+	if got := offerSanitized(c.Message, false); got != "Drop the unused import" {
+		t.Errorf("accepting gave %q", got)
+	}
+}
+
+func FuzzClassifyResponse(f *testing.F) {
+	f.Add("```\nFix it\n```")
+	f.Fuzz(func(t *testing.T, response string) {
+		if message := extractCommitMessage(response); message != "" {
+			if message != strings.TrimSpace(message) {
+				t.Errorf("extracted message %q has surrounding space", message)
+			}
+			if !strings.Contains(strings.ReplaceAll(response, "\r\n", "\n"), message) {
+				t.Errorf("extracted message %q is not part of the response", message)
+			}
+		}
+
+		c := classifyResponse(response)
+		if c.Confidence <= 0 || c.Confidence > 1 {
+			t.Errorf("confidence %v out of range", c.Confidence)
+		}
+		if c.Kind == responseMessage && c.Message == "" {
+			t.Error("classified as a message without one")
+		}
+		if !utf8.ValidString(c.Message) {
+			t.Errorf("message %q is not valid UTF-8", c.Message)
+		}
+		if strings.Contains(c.Message, "\r\n") {
+			t.Errorf("message %q kept CRLF line endings", c.Message)
+		}
+		if again := classifyResponse(response); again != c {
+			t.Errorf("classification changed between runs: %+v, then %+v", c, again)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// commitOptions controls how `git commit` is invoked.
+type commitOptions struct {
+	All      bool
+	NoVerify bool
+	// Cleanup is passed as --cleanup when set; otherwise git applies
+	// commit.cleanup itself.
+	Cleanup string
+	// Sign forces signing on or off; nil leaves it to commit.gpgsign.
+	Sign *bool
+	// Date, when set, is used as both the author and committer date, or
+	// only the author date with AuthorDateOnly.
+	Date           string
+	AuthorDateOnly bool
+	// HooksPath, when set, overrides core.hooksPath for the commit.
+	HooksPath string
+	// Encoding, when set, overrides i18n.commitEncoding for the commit. The
+	// message must already be encoded in it.
+	Encoding string
+}
+
+// gitCommit creates the commit with the given message. The message is
+// passed on stdin rather than as an argument so its content can never be
+// interpreted by anything along the way. It returns git's output, which
+// includes anything printed by hooks.
+func gitCommit(message string, opts commitOptions) (string, error) {
+	args, env := opts.gitArgs()
+	cmd := gitCommandEnv(env, args...)
+	cmd.Stdin = strings.NewReader(message)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return string(output), fmt.Errorf("error making commit: %v\n%s", err, out)
+		}
+		return string(output), fmt.Errorf("error making commit: %v", err)
+	}
+	return string(output), nil
+}
+
+// gitArgs returns the arguments and extra environment for the git commit
+// that reads the message from stdin.
+func (opts commitOptions) gitArgs() (args, env []string) {
+	if opts.HooksPath != "" {
+		args = append(args, "-c", "core.hooksPath="+opts.HooksPath)
+	}
+	if opts.Encoding != "" {
+		args = append(args, "-c", "i18n.commitEncoding="+opts.Encoding)
+	}
+	args = append(args, "commit")
+	if opts.All {
+		args = append(args, "-a")
+	}
+	if opts.NoVerify {
+		args = append(args, "--no-verify")
+	}
+	if opts.Cleanup != "" {
+		args = append(args, "--cleanup="+opts.Cleanup)
+	}
+	if opts.Sign != nil {
+		if *opts.Sign {
+			args = append(args, "-S")
+		} else {
+			args = append(args, "--no-gpg-sign")
+		}
+	}
+	if opts.Date != "" {
+		args = append(args, "--date="+opts.Date)
+	}
+	args = append(args, "-F", "-")
+	if opts.Date != "" && !opts.AuthorDateOnly {
+		env = append(env, "GIT_COMMITTER_DATE="+opts.Date)
+	}
+	return args, env
+}
+
+// runBeforeCommit runs a -run-before command through the shell. A failure
+// includes the command's output, since that is what explains it.
+func runBeforeCommit(command string) error {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return fmt.Errorf("%q failed: %v\n%s", command, err, out)
+		}
+		return fmt.Errorf("%q failed: %v", command, err)
+	}
+	return nil
+}
+
+// stripspace applies the cleanup `git commit -F` does to a message that is
+// not opened in an editor: whitespace cleanup, unless the cleanup mode says
+// otherwise.
+func stripspace(message, cleanup string) (string, error) {
+	args := []string{"stripspace"}
+	switch cleanup {
+	case "verbatim":
+		return message, nil
+	case "strip":
+		args = append(args, "--strip-comments")
+	}
+	cmd := gitCommand(args...)
+	cmd.Stdin = strings.NewReader(message)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error running git stripspace: %v", err)
+	}
+	return string(output), nil
+}
+
+// lastCommitMessage returns the full message of HEAD, as UTF-8 whatever
+// encoding it was committed in.
+func lastCommitMessage() (string, error) {
+	output, err := gitCommand("-c", "i18n.logOutputEncoding=UTF-8", "log", "-1", "--format=%B").Output()
+	if err != nil {
+		return "", fmt.Errorf("error reading committed message: %v", err)
+	}
+	return string(output), nil
+}
+
+// verifyCommittedMessage reads back the message of the commit just created
+// and warns if it differs from what we intended beyond git's usual cleanup.
+// When message hooks ran, the difference is attributed to them and their
+// output is shown, since that is the usual cause.
+func verifyCommittedMessage(intended, commitOutput, cleanup string, hooksRan bool) {
+	expected, err := stripspace(intended, cleanup)
+	if err != nil {
+		fmt.Printf("Warning: could not verify commit message: %v\n", err)
+		return
+	}
+	actual, err := lastCommitMessage()
+	if err != nil {
+		fmt.Printf("Warning: could not verify commit message: %v\n", err)
+		return
+	}
+	// %B output carries a trailing newline of its own.
+	expected = strings.TrimRight(expected, "\n")
+	actual = strings.TrimRight(actual, "\n")
+	if actual == expected {
+		return
+	}
+
+	fmt.Println("\nWarning: the committed message differs from the one gitcommit intended.")
+	if hooksRan {
+		if _, hooks, err := findCommitHooks(); err == nil {
+			for _, h := range hooks {
+				if h.Name == "prepare-commit-msg" || h.Name == "commit-msg" {
+					fmt.Printf("The %s hook (%s) may have rewritten it.\n", h.Name, h.Path)
+				}
+			}
+		}
+		if out := strings.TrimSpace(commitOutput); out != "" {
+			fmt.Printf("Output from git commit and its hooks:\n%s\n", out)
+		}
+	}
+	fmt.Println("Differences (- intended, + committed):")
+	for _, line := range lineDiff(expected, actual) {
+		fmt.Println(colorDiffLine(line))
+	}
+}
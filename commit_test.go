@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMessageRoundTrip follows awkward messages from the model's response
+// to the commit: they must come out of git log byte for byte as sanitized.
+func TestMessageRoundTrip(t *testing.T) {
+	testRepo(t)
+	long := "Rewrite the scheduler\n\n" + strings.Repeat("Each worker now pulls from its own queue and steals from the others when idle.\n", 130)
+	tests := []struct {
+		name, message, want string
+	}{
+		{"plain", "Fix the parser", ""},
+		{"backticks", "Rename `cfg` to `config`\n\nUse ``double`` ticks and a lone ` too.", ""},
+		{"nested fences", "Add an example to the docs\n\nUsage:\n\n```go\nclient := New()\nclient.Run()\n```\n\nThat's all.", ""},
+		{"shell metacharacters", "Quote $(rm -rf ~) and `id` safely\n\nAlso $HOME, ${PATH}, 'single', \"double\", \\backslash, a | b; c && d > e < f & g * ? [x] ~ !!", ""},
+		{"crlf", "Fix line endings\r\n\r\nWindows editors write CRLF.", "Fix line endings\n\nWindows editors write CRLF."},
+		{"emoji", "Add family emoji 👨\u200d👩\u200d👧 and flags 🇩🇪 🏳\ufe0f\u200d🌈", ""},
+		{"rtl", "Translate the menu\n\nעברית: שמור קובץ\nالعربية: حفظ الملف\nفارسی: می\u200cخواهم", ""},
+		{"tabs and trailers", "Indent with tabs\n\n\tkeep\tthese\n\nSigned-off-by: Test <test@example.com>", ""},
+		{"long body", strings.TrimRight(long, "\n"), ""},
+	}
+	for i, tt := range tests {
+		want := tt.want
+		if want == "" {
+			want = tt.message
+		}
+		response := "Here is the commit message:\n\n```\n" + tt.message + "\n```\n"
+		extracted := extractCommitMessage(response)
+		if extracted != strings.ReplaceAll(tt.message, "\r\n", "\n") {
+			t.Errorf("%s: extracted %q, want %q", tt.name, extracted, tt.message)
+			continue
+		}
+		sanitized, _ := sanitizeMessage(extracted, false)
+		if sanitized != want {
+			t.Errorf("%s: sanitized to %q, want %q", tt.name, sanitized, want)
+			continue
+		}
+		writeFile(t, "file", strings.Repeat("x", i+1))
+		mustGit(t, "add", "file")
+		if _, err := gitCommit(sanitized, commitOptions{}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		committed, err := lastCommitMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// git ends the stored message with a newline, and %B adds another.
+		if committed != want+"\n\n" {
+			t.Errorf("%s: committed %q, want %q", tt.name, committed, want+"\n\n")
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// minCompressBytes is the request size from which bodies are gzipped;
+// smaller ones gain too little to be worth it.
+const minCompressBytes = 8 * 1024
+
+// Settings used by -low-bandwidth.
+const (
+	lowBandwidthDiffBytes = 20000
+	lowBandwidthMaxTokens = 1024
+)
+
+// gzipRejected remembers endpoints that refused a compressed body, so the
+// rest of the run (or a server's lifetime) doesn't try again.
+var (
+	gzipRejectedMu sync.Mutex
+	gzipRejected   = map[string]bool{}
+)
+
+func compressionRejected(url string) bool {
+	gzipRejectedMu.Lock()
+	defer gzipRejectedMu.Unlock()
+	return gzipRejected[url]
+}
+
+func markCompressionRejected(url string) {
+	gzipRejectedMu.Lock()
+	defer gzipRejectedMu.Unlock()
+	gzipRejected[url] = true
+}
+
+// rejectsCompression reports whether a status may mean the endpoint
+// doesn't accept Content-Encoding: gzip. Gateways answer 415; some reply
+// 400 because they try to parse the compressed bytes as JSON.
+func rejectsCompression(status int) bool {
+	return status == http.StatusUnsupportedMediaType || status == http.StatusBadRequest
+}
+
+func gzipBody(body []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(body); err != nil {
+		return nil, fmt.Errorf("error compressing request: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("error compressing request: %v", err)
+	}
+	return b.Bytes(), nil
+}
+
+// compressRequest returns the gzipped body when compression is worthwhile
+// and the endpoint hasn't refused it, or nil to send the body as-is.
+func compressRequest(body []byte, opts apiOptions) []byte {
+	if compressionRejected(apiURL) || (len(body) < minCompressBytes && !opts.LowBandwidth) {
+		return nil
+	}
+	compressed, err := gzipBody(body)
+	if err != nil {
+		if opts.Verbose {
+			say("Warning: %v\n", err)
+		}
+		return nil
+	}
+	if opts.Verbose {
+		fmt.Printf("Request body: %s raw, %s gzipped\n", formatBytes(len(body)), formatBytes(len(compressed)))
+	}
+	return compressed
+}
+
+func formatBytes(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	if n < 1<<20 {
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	if n < 1<<30 {
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+}
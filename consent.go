@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// anthropicPrivacyURL is where the first-run notice points for how the API
+// handles what is sent.
+const anthropicPrivacyURL = "https://www.anthropic.com/legal/privacy"
+
+// consentPath is the marker recording that the first-run notice was
+// acknowledged. It lives next to the user config.
+func consentPath() (string, error) {
+	p, err := userConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(p), "consent"), nil
+}
+
+// confirmConsent asks, once per user, for explicit agreement before
+// anything is sent to the API, and records the answer so it isn't asked
+// again. It reports whether the run may go on. Only "yes" counts, so -yes
+// doesn't answer it; -no-consent-prompt skips it for scripts and CI.
+func confirmConsent() bool {
+	p, err := consentPath()
+	if err != nil {
+		say("Warning: %v\n", err)
+		return true
+	}
+	if _, err := os.Stat(p); err == nil {
+		return true
+	}
+	endpoint := "Anthropic's API"
+	if apiURL != defaultAPIURL {
+		endpoint = apiURL
+	}
+	say("\ngitcommit sends your staged diff, file names, recent commit messages and what you type to %s to write the message.\n", endpoint)
+	say("See %s for how Anthropic handles API data. Nothing has been sent yet.\n", anthropicPrivacyURL)
+	for {
+		switch strings.ToLower(getUserInput("Type yes to agree and continue, or no to stop (asked only once): ")) {
+		case "yes":
+			err := os.MkdirAll(filepath.Dir(p), 0700)
+			if err == nil {
+				err = os.WriteFile(p, []byte("acknowledged "+time.Now().UTC().Format(time.RFC3339)+"\n"), 0600)
+			}
+			if err != nil {
+				say("Warning: could not record your answer, so you'll be asked again: %v\n", err)
+			}
+			return true
+		case "no":
+			return false
+		}
+	}
+}
+
+// skipConsent is set by -no-consent-prompt.
+var skipConsent bool
+
+// requireConsent is confirmConsent for the subcommands that call the API.
+// Those run from scripts, editors and filter-branch often have no terminal
+// to ask on; a missing agreement is then an error rather than a question.
+func requireConsent() error {
+	if skipConsent {
+		return nil
+	}
+	p, err := consentPath()
+	if err != nil {
+		say("Warning: %v\n", err)
+		return nil
+	}
+	if _, err := os.Stat(p); err == nil {
+		return nil
+	}
+	if !stdinIsTerminal() {
+		return fmt.Errorf("nothing was sent: agree to sending data to the API by running gitcommit once in a terminal, or pass -no-consent-prompt (gitcommit -no-consent-prompt <command> ...)")
+	}
+	if !confirmConsent() {
+		return fmt.Errorf("nothing was sent")
+	}
+	return nil
+}
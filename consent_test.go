@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pipeStdin replaces stdin with a pipe holding input, so the run is not
+// attached to a terminal.
+func pipeStdin(t *testing.T, input string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString(input); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	old := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = old
+		r.Close()
+	})
+}
+
+func TestRequireConsent(t *testing.T) {
+	// The config path is overridden rather than XDG_CONFIG_HOME, which
+	// os.UserConfigDir ignores on macOS and Windows.
+	dir := filepath.Join(t.TempDir(), "gitcommit")
+	defer func(old string) { configOverride = old }(configOverride)
+	configOverride = filepath.Join(dir, "config.json")
+	defer func(old bool) { skipConsent = old }(skipConsent)
+	skipConsent = false
+	pipeStdin(t, "")
+
+	err := requireConsent()
+	if err == nil || !strings.Contains(err.Error(), "-no-consent-prompt") {
+		t.Fatalf("without a terminal or marker: err = %v, want a pointer to -no-consent-prompt", err)
+	}
+
+	skipConsent = true
+	if err := requireConsent(); err != nil {
+		t.Errorf("with -no-consent-prompt: err = %v", err)
+	}
+	skipConsent = false
+
+	marker := filepath.Join(dir, "consent")
+	if err := os.MkdirAll(filepath.Dir(marker), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(marker, []byte("acknowledged\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := requireConsent(); err != nil {
+		t.Errorf("with the marker: err = %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// contextMarker starts every record written by -export-context; the commit
+// sha follows it so records can be matched to commits later.
+const contextMarker = "<!-- gitcommit-context "
+
+// exchange is one clarifying question from Claude and the user's answer.
+type exchange struct {
+	Question string
+	Answer   string
+}
+
+// contextRecord is what -export-context writes for one commit.
+type contextRecord struct {
+	Sha       string
+	Message   string
+	Intent    string
+	Exchanges []exchange
+	Files     []fileStat
+	// PromptVersion is the built-in prompt version the message came from.
+	PromptVersion string
+}
+
+func (r contextRecord) markdown() string {
+	subject, _ := splitMessage(r.Message)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%s -->\n## %s %s\n\n", contextMarker, r.Sha, r.Sha[:12], subject)
+	if r.PromptVersion != "" {
+		fmt.Fprintf(&b, "Prompt version: %s\n\n", r.PromptVersion)
+	}
+	fmt.Fprintf(&b, "### Final message\n\n```\n%s\n```\n\n", r.Message)
+	if r.Intent != "" {
+		fmt.Fprintf(&b, "### Intent\n\n%s\n\n", r.Intent)
+	}
+	if len(r.Exchanges) > 0 {
+		b.WriteString("### Questions and answers\n\n")
+		for _, e := range r.Exchanges {
+			fmt.Fprintf(&b, "**Q:** %s\n\n**A:** %s\n\n", strings.TrimSpace(e.Question), e.Answer)
+		}
+	}
+	if len(r.Files) > 0 {
+		b.WriteString("### Files\n\n")
+		for _, f := range r.Files {
+			name := f.Path
+			if f.OldPath != "" {
+				name = f.OldPath + " → " + f.Path
+			}
+			if f.Binary {
+				fmt.Fprintf(&b, "- %s (binary)\n", name)
+			} else {
+				fmt.Fprintf(&b, "- %s (+%d -%d)\n", name, f.Added, f.Deleted)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// exportContext appends a record of the commit just made to path. The
+// record goes through the secret scanner like anything sent to the API.
+// Failures are reported but never affect the commit.
+func exportContext(path string, r contextRecord, scanner *scannerConfig) {
+	sha, err := gitRun("rev-parse", "HEAD")
+	if err != nil {
+		fmt.Printf("Warning: could not export context: %v\n", err)
+		return
+	}
+	r.Sha = sha
+	if output, err := gitRun("diff-tree", "--no-commit-id", "--numstat", "-z", "-r", "-M", "--root", "HEAD"); err == nil {
+		r.Files = parseNumstat(output)
+	}
+	record := r.markdown()
+	if scanner != nil {
+		if record, _, err = scanContent(scanner, record); err != nil {
+			fmt.Printf("Warning: context not exported: %v\n", err)
+			return
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("Warning: could not export context: %v\n", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(record); err != nil {
+		fmt.Printf("Warning: could not export context: %v\n", err)
+		return
+	}
+	fmt.Printf("Appended the context of this commit to %s.\n", path)
+}
+
+// readContextRecords returns the raw markdown records in the given files,
+// keyed by commit sha.
+func readContextRecords(paths []string) (map[string]string, error) {
+	records := map[string]string{}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("error reading context file: %v", err)
+		}
+		for _, chunk := range strings.Split(string(data), contextMarker)[1:] {
+			sha, _, _ := strings.Cut(chunk, " ")
+			// A later record for the same commit replaces an earlier one.
+			records[sha] = contextMarker + chunk
+		}
+	}
+	return records, nil
+}
+
+const prSystemPrompt = `You write pull request descriptions. Given the commits on a branch, and
+possibly the recorded context from when each commit was written (the
+author's intent and answers to clarifying questions), write a PR title and
+a description for reviewers: what changed, why, and anything that needs
+attention. Prefer the recorded context over guessing from the diffs.
+Respond with ONLY a JSON object wrapped in triple backticks, with the
+fields "title" and "body".`
+
+type prSuggestion struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// defaultPRRange compares the branch with its upstream, falling back to
+// origin's default branch.
+func defaultPRRange() string {
+	if _, err := gitRun("rev-parse", "--verify", "--quiet", "@{upstream}"); err == nil {
+		return "@{upstream}..HEAD"
+	}
+	return "origin/HEAD..HEAD"
+}
+
+// prDescription asks for a PR title and body for the commits in rng, using
+// any exported context records for those commits.
+func prDescription(rng string, contextFiles []string, apiKey string, opts apiOptions) (prSuggestion, error) {
+	commits, err := rangeCommits(rng)
+	if err != nil {
+		return prSuggestion{}, err
+	}
+	if len(commits) == 0 {
+		return prSuggestion{}, fmt.Errorf("no commits in %s", rng)
+	}
+	prompt := fmt.Sprintf("Here are the %d commits on the branch:\n\n%s", len(commits), rangePrompt(commits))
+	if len(contextFiles) > 0 {
+		records, err := readContextRecords(contextFiles)
+		if err != nil {
+			return prSuggestion{}, err
+		}
+		var found []string
+		for _, c := range commits {
+			if r, ok := records[c.Sha]; ok {
+				found = append(found, r)
+			}
+		}
+		if len(found) > 0 {
+			prompt += "Recorded context from when these commits were written:\n\n" + strings.Join(found, "\n")
+		}
+		fmt.Printf("Using recorded context for %d of %d commit(s).\n", len(found), len(commits))
+	}
+
+	opts.System = prSystemPrompt
+	response, err := askClaude(prompt, apiKey, opts)
+	if err != nil {
+		return prSuggestion{}, err
+	}
+	var s prSuggestion
+	if err := json.Unmarshal([]byte(extractCommitMessage(response)), &s); err != nil {
+		return prSuggestion{}, fmt.Errorf("error parsing suggestion: %v", err)
+	}
+	return s, nil
+}
+
+// runPRDescription implements
+// `gitcommit pr-description [--from-context file]... [range]`.
+func runPRDescription(args []string, profileName string) error {
+	fs := flag.NewFlagSet("pr-description", flag.ContinueOnError)
+	var contextFiles stringList
+	fs.Var(&contextFiles, "from-context", "read records written by -export-context (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: gitcommit pr-description [--from-context file]... [range]")
+	}
+	rng := defaultPRRange()
+	if fs.NArg() == 1 {
+		rng = fs.Arg(0)
+	}
+
+	apiKey, opts, err := subcommandAPI(profileName)
+	if err != nil {
+		return err
+	}
+	s, err := prDescription(rng, contextFiles, apiKey, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n\n%s\n", s.Title, strings.TrimSpace(s.Body))
+	return nil
+}
+
+// stringList is a flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
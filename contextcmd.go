@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// maxContextBytes caps how much of a -context-cmd's output, or of a
+	// -context-file, is sent. The end is kept, since that is where test
+	// failures and build errors are reported.
+	maxContextBytes = 16 * 1024
+	// contextCmdTimeout bounds a -context-cmd, such as a test run or a
+	// ticket fetcher waiting on the network.
+	contextCmdTimeout = 30 * time.Second
+)
+
+// extraContext is output supplied with -context-cmd or -context-file.
+type extraContext struct {
+	// Source describes where it came from, for the prompt.
+	Source string
+	Text   string
+	// Status is how a -context-cmd ended when it didn't succeed, such as
+	// "exit status 1"; that doesn't stop the output being sent.
+	Status string
+}
+
+// runContextCmd runs a -context-cmd through the shell and captures its
+// output, stderr included, since that is where build errors go. A command
+// that fails or times out still gives its output; only one that can't be
+// run at all is an error.
+func runContextCmd(command string) (*extraContext, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), contextCmdTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	output, err := cmd.CombinedOutput()
+	c := &extraContext{Source: fmt.Sprintf("Output of `%s`", command), Text: cleanContext(string(output))}
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		c.Status = fmt.Sprintf("stopped after %s", contextCmdTimeout)
+	case errors.As(err, &exitErr):
+		c.Status = err.Error()
+	case err != nil:
+		return nil, fmt.Errorf("error running -context-cmd %q: %v", command, err)
+	}
+	return c, nil
+}
+
+// readContextFile reads a -context-file, such as a saved test log.
+func readContextFile(path string) (*extraContext, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading -context-file: %v", err)
+	}
+	return &extraContext{Source: "Contents of " + path, Text: cleanContext(string(data))}, nil
+}
+
+// cleanContext makes output valid UTF-8 without escape sequences and, past
+// maxContextBytes, keeps only its last lines.
+func cleanContext(text string) string {
+	text = strings.ToValidUTF8(text, "�")
+	text = strings.ReplaceAll(ansiRe.ReplaceAllString(text, ""), "\x1b", "")
+	text = strings.TrimSpace(text)
+	if len(text) <= maxContextBytes {
+		return text
+	}
+	tail := text[len(text)-maxContextBytes:]
+	// Start at a line boundary, which is also a rune boundary.
+	if i := strings.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return fmt.Sprintf("[... %d earlier bytes cut]\n%s", len(text)-len(tail), tail)
+}
+
+// prompt labels the output for the prompt, framed as what the change is
+// likely to fix.
+func (c *extraContext) prompt() string {
+	source := c.Source
+	if c.Status != "" {
+		source += " (" + c.Status + ")"
+	}
+	fence := "```"
+	for strings.Contains(c.Text, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("%s, supplied by me. If it shows the problem this change addresses, say what actually broke rather than paraphrasing the diff; otherwise use only what is relevant:\n%s\n%s\n%s", source, fence, c.Text, fence)
+}
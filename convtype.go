@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// conventionalTypes are the types offered when the guess is uncertain.
+var conventionalTypes = []string{"feat", "fix", "refactor", "perf", "test", "docs", "build", "ci", "style", "chore"}
+
+// typeFacts are the cheap signals the type guess is made from.
+type typeFacts struct {
+	Paths []string
+	// NewExported and Modified count Go declarations added with an
+	// exported name, and existing declarations changed.
+	NewExported, Modified int
+	// FormattingOnly is set when every file changed only in whitespace.
+	FormattingOnly bool
+	// Seed is the message the user typed.
+	Seed string
+}
+
+// typeGuess is the suggested conventional type and how sure it is.
+type typeGuess struct {
+	Type       string
+	Confidence float64
+	Reasons    []string
+}
+
+// Confidence levels shown with the guess.
+const (
+	highTypeConfidence = 0.8
+	lowTypeConfidence  = 0.5
+)
+
+func (g typeGuess) level() string {
+	switch {
+	case g.Confidence >= highTypeConfidence:
+		return "high"
+	case g.Confidence >= lowTypeConfidence:
+		return "medium"
+	}
+	return "low"
+}
+
+func (g typeGuess) String() string {
+	return fmt.Sprintf("type: %s (%s confidence: %s)", g.Type, g.level(), strings.Join(g.Reasons, " + "))
+}
+
+var (
+	fixWordRe   = regexp.MustCompile(`(?i)\b(fix(es|ed)?|bugs?|crash(es)?|regression|broken|typo)\b`)
+	issueRefRe  = regexp.MustCompile(`(^|[\s(])(#\d+|[A-Z][A-Z0-9]+-\d+)\b|(?i:\b(closes|resolves)\b)`)
+	featWordRe  = regexp.MustCompile(`(?i)\b(add(s|ed)?|introduce[sd]?|support(s|ed)?|implement(s|ed)?|new)\b`)
+	refacWordRe = regexp.MustCompile(`(?i)\b(refactor(s|ed)?|rename[sd]?|move[sd]?|extract(s|ed)?|simplif(y|ies|ied)|clean ?up)\b`)
+	perfWordRe  = regexp.MustCompile(`(?i)\b(perf(ormance)?|faster|speed ?up|optimi[sz]e[sd]?)\b`)
+)
+
+// depFiles are manifests and lock files whose changes are dependency
+// updates.
+var depFiles = map[string]bool{
+	"go.mod": true, "go.sum": true, "package.json": true, "package-lock.json": true,
+	"yarn.lock": true, "pnpm-lock.yaml": true, "Cargo.toml": true, "Cargo.lock": true,
+	"Gemfile": true, "Gemfile.lock": true, "poetry.lock": true, "pyproject.toml": true,
+	"composer.json": true, "composer.lock": true,
+}
+
+func isTestPath(p string) bool {
+	base := path.Base(p)
+	return strings.HasSuffix(base, "_test.go") || strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.") || strings.HasPrefix(base, "test_") ||
+		strings.HasPrefix(p, "test/") || strings.HasPrefix(p, "tests/") ||
+		strings.Contains(p, "/test/") || strings.Contains(p, "/tests/") ||
+		strings.Contains(p, "testdata/")
+}
+
+func isDocsPath(p string) bool {
+	base := strings.ToLower(path.Base(p))
+	switch path.Ext(base) {
+	case ".md", ".rst", ".adoc", ".txt":
+		return !strings.HasPrefix(base, "requirements")
+	}
+	return strings.HasPrefix(p, "docs/") || strings.HasPrefix(p, "doc/") ||
+		strings.HasPrefix(base, "license") || strings.HasPrefix(base, "readme")
+}
+
+func isDepPath(p string) bool {
+	base := path.Base(p)
+	return depFiles[base] || (strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"))
+}
+
+func isCIPath(p string) bool {
+	return strings.HasPrefix(p, ".github/workflows/") || strings.HasPrefix(p, ".circleci/") ||
+		p == ".gitlab-ci.yml" || p == ".travis.yml" || p == "Jenkinsfile"
+}
+
+func allPaths(paths []string, match func(string) bool) bool {
+	for _, p := range paths {
+		if !match(p) {
+			return false
+		}
+	}
+	return len(paths) > 0
+}
+
+// guessType suggests a conventional type from the facts alone. It is a
+// pure function so it can run before any request is made.
+func guessType(f typeFacts) typeGuess {
+	switch {
+	case len(f.Paths) == 0:
+		return typeGuess{Type: "chore", Confidence: 0.2, Reasons: []string{"no changed files"}}
+	case f.FormattingOnly:
+		return typeGuess{Type: "style", Confidence: 0.9, Reasons: []string{"only formatting changed"}}
+	case allPaths(f.Paths, isTestPath):
+		return typeGuess{Type: "test", Confidence: 0.9, Reasons: []string{"only test files"}}
+	case allPaths(f.Paths, isDocsPath):
+		return typeGuess{Type: "docs", Confidence: 0.9, Reasons: []string{"only documentation"}}
+	case allPaths(f.Paths, isDepPath):
+		return typeGuess{Type: "build", Confidence: 0.85, Reasons: []string{"only dependency files"}}
+	case allPaths(f.Paths, isCIPath):
+		return typeGuess{Type: "ci", Confidence: 0.9, Reasons: []string{"only CI configuration"}}
+	}
+
+	withTests := false
+	for _, p := range f.Paths {
+		if isTestPath(p) {
+			withTests = true
+			break
+		}
+	}
+	issueRef := issueRefRe.MatchString(f.Seed)
+
+	switch {
+	case fixWordRe.MatchString(f.Seed):
+		g := typeGuess{Type: "fix", Confidence: 0.6, Reasons: []string{"message mentions a fix"}}
+		if f.Modified > 0 && f.NewExported == 0 {
+			g.Confidence += 0.15
+			g.Reasons = append(g.Reasons, "modifies existing behavior")
+		}
+		if issueRef {
+			g.Confidence += 0.1
+			g.Reasons = append(g.Reasons, "issue ref")
+		}
+		return g
+	case f.NewExported > 0:
+		g := typeGuess{Type: "feat", Confidence: 0.6, Reasons: []string{fmt.Sprintf("%d new exported symbol(s)", f.NewExported)}}
+		if featWordRe.MatchString(f.Seed) {
+			g.Confidence += 0.2
+			g.Reasons = append(g.Reasons, "message describes an addition")
+		}
+		if withTests {
+			g.Confidence += 0.05
+			g.Reasons = append(g.Reasons, "with tests")
+		}
+		return g
+	case perfWordRe.MatchString(f.Seed):
+		return typeGuess{Type: "perf", Confidence: 0.65, Reasons: []string{"message mentions performance"}}
+	case refacWordRe.MatchString(f.Seed):
+		g := typeGuess{Type: "refactor", Confidence: 0.6, Reasons: []string{"message describes restructuring"}}
+		if f.NewExported == 0 {
+			g.Confidence += 0.1
+			g.Reasons = append(g.Reasons, "no new exported symbols")
+		}
+		return g
+	case featWordRe.MatchString(f.Seed):
+		return typeGuess{Type: "feat", Confidence: 0.55, Reasons: []string{"message describes an addition"}}
+	case issueRef:
+		return typeGuess{Type: "fix", Confidence: 0.45, Reasons: []string{"issue ref"}}
+	}
+
+	if f.Modified > 0 {
+		return typeGuess{Type: "fix", Confidence: 0.35, Reasons: []string{"modifies existing code", "no other signal"}}
+	}
+	return typeGuess{Type: "chore", Confidence: 0.3, Reasons: []string{"no strong signal"}}
+}
+
+// typeFactsFor collects the signals for guessType from the change.
+func typeFactsFor(seed string, paths []string, symbols []fileSymbols, formattingOnly bool) typeFacts {
+	f := typeFacts{Paths: paths, Seed: seed, FormattingOnly: formattingOnly}
+	for _, s := range symbols {
+		for _, name := range s.Added {
+			if exportedDecl(name) {
+				f.NewExported++
+			}
+		}
+		f.Modified += len(s.Modified)
+	}
+	return f
+}
+
+// exportedDecl reports whether a declaration name from goDecls ("func
+// Foo", "type Bar", "method (*T).Baz") is exported.
+func exportedDecl(name string) bool {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return false
+	}
+	last := fields[len(fields)-1]
+	if i := strings.LastIndex(last, "."); i >= 0 {
+		last = last[i+1:]
+	}
+	r := []rune(last)
+	return len(r) > 0 && unicode.IsUpper(r[0])
+}
+
+// askType asks which type to use when the guess is uncertain. An empty
+// answer keeps the guess.
+func askType(g typeGuess) typeGuess {
+	for {
+		answer := strings.ToLower(ask("Which type is this change? (%s; Enter for %s): ", strings.Join(conventionalTypes, ", "), g.Type))
+		if answer == "" {
+			return g
+		}
+		for _, t := range conventionalTypes {
+			if answer == t {
+				return typeGuess{Type: t, Confidence: 1, Reasons: []string{"chosen by you"}}
+			}
+		}
+		say("Unknown type %q.\n", answer)
+	}
+}
+
+func typePrompt(g typeGuess) string {
+	if g.Confidence >= 1 {
+		return fmt.Sprintf("Write a conventional commit subject (type(scope): summary). The type is %s.", g.Type)
+	}
+	return fmt.Sprintf("Write a conventional commit subject (type(scope): summary). The type is probably %s (%s); use another only if the change clearly calls for it.", g.Type, strings.Join(g.Reasons, ", "))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGuessType(t *testing.T) {
+	tests := []struct {
+		name       string
+		facts      typeFacts
+		typ        string
+		confidence float64
+		level      string
+	}{
+		{"nothing changed", typeFacts{}, "chore", 0.2, "low"},
+		{"formatting only", typeFacts{Paths: []string{"main.go"}, FormattingOnly: true}, "style", 0.9, "high"},
+		{"test only", typeFacts{Paths: []string{"parser_test.go", "testdata/in.txt"}, Seed: "fix the flaky test"}, "test", 0.9, "high"},
+		{"docs only", typeFacts{Paths: []string{"README.md", "docs/usage.html"}, NewExported: 1}, "docs", 0.9, "high"},
+		{"deps only", typeFacts{Paths: []string{"go.mod", "go.sum", "web/package-lock.json"}}, "build", 0.85, "high"},
+		{"requirements file", typeFacts{Paths: []string{"requirements-dev.txt"}}, "build", 0.85, "high"},
+		{"ci only", typeFacts{Paths: []string{".github/workflows/test.yml"}}, "ci", 0.9, "high"},
+		{"fix in seed", typeFacts{Paths: []string{"parser.go"}, Seed: "fix parser"}, "fix", 0.6, "medium"},
+		{"bug in seed, modified body", typeFacts{Paths: []string{"parser.go"}, Modified: 1, Seed: "bug in the parser"}, "fix", 0.75, "medium"},
+		{"fix with issue ref", typeFacts{Paths: []string{"parser.go"}, Modified: 2, Seed: "Fixes #123"}, "fix", 0.85, "high"},
+		{"fix with ticket", typeFacts{Paths: []string{"parser.go"}, Seed: "fixed crash, PROJ-42"}, "fix", 0.7, "medium"},
+		{"new exported symbol", typeFacts{Paths: []string{"client.go"}, NewExported: 2}, "feat", 0.6, "medium"},
+		{"new exported symbol, added", typeFacts{Paths: []string{"client.go", "client_test.go"}, NewExported: 1, Seed: "add retries"}, "feat", 0.85, "high"},
+		{"fix beats new symbols", typeFacts{Paths: []string{"client.go"}, NewExported: 1, Modified: 1, Seed: "fix retries"}, "fix", 0.6, "medium"},
+		{"performance", typeFacts{Paths: []string{"cache.go"}, Modified: 1, Seed: "make lookups faster"}, "perf", 0.65, "medium"},
+		{"refactor", typeFacts{Paths: []string{"cache.go"}, Seed: "rename the cache"}, "refactor", 0.7, "medium"},
+		{"addition without symbols", typeFacts{Paths: []string{"cache.go"}, Seed: "support TTLs"}, "feat", 0.55, "medium"},
+		{"issue ref only", typeFacts{Paths: []string{"cache.go"}, Seed: "closes the loop on (#77)"}, "fix", 0.45, "low"},
+		{"modified, no words", typeFacts{Paths: []string{"cache.go"}, Modified: 1, Seed: "wip"}, "fix", 0.35, "low"},
+		{"no signal", typeFacts{Paths: []string{"cache.go", "Makefile"}}, "chore", 0.3, "low"},
+		{"prefix is not a word", typeFacts{Paths: []string{"cache.go"}, Seed: "prefix the keys"}, "chore", 0.3, "low"},
+	}
+	for _, tt := range tests {
+		g := guessType(tt.facts)
+		if g.Type != tt.typ || math.Abs(g.Confidence-tt.confidence) > 1e-9 || g.level() != tt.level {
+			t.Errorf("%s: got %s at %.2f (%s, %s), want %s at %.2f (%s)", tt.name, g.Type, g.Confidence, g.level(), strings.Join(g.Reasons, ", "), tt.typ, tt.confidence, tt.level)
+		}
+		if len(g.Reasons) == 0 {
+			t.Errorf("%s: no reasons given", tt.name)
+		}
+	}
+}
+
+func TestTypeGuessString(t *testing.T) {
+	g := guessType(typeFacts{Paths: []string{"parser.go"}, Modified: 1, Seed: "fix #12"})
+	if got, want := g.String(), "type: fix (high confidence: message mentions a fix + modifies existing behavior + issue ref)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestTypeFactsFor(t *testing.T) {
+	symbols := []fileSymbols{
+		{Path: "client.go", Added: []string{"func New", "func helper", "method (*Client).Do", "type Option"}, Modified: []string{"func (c *Client) get"}},
+		{Path: "util.go", Added: []string{"var cache"}, Removed: []string{"func Old"}, Modified: []string{"func trim", "func Split"}},
+	}
+	f := typeFactsFor("add a client", []string{"client.go", "util.go"}, symbols, false)
+	if f.NewExported != 3 || f.Modified != 3 || f.Seed != "add a client" || len(f.Paths) != 2 || f.FormattingOnly {
+		t.Errorf("typeFactsFor = %+v, want 3 new exported and 3 modified", f)
+	}
+	if f := typeFactsFor("", []string{"a.go"}, nil, true); !f.FormattingOnly || f.NewExported != 0 || f.Modified != 0 {
+		t.Errorf("formatting only: %+v", f)
+	}
+}
+
+func TestAskType(t *testing.T) {
+	low := guessType(typeFacts{Paths: []string{"cache.go"}})
+	withInput(t, "bogus\nPerf\n")
+	if g := askType(low); g.Type != "perf" || g.Confidence != 1 {
+		t.Errorf("askType = %+v, want perf chosen by the user", g)
+	}
+	withInput(t, "\n")
+	if g := askType(low); g.Type != low.Type {
+		t.Errorf("Enter gave %+v, want the guess kept", g)
+	}
+}
+
+func TestConventionalCheck(t *testing.T) {
+	tests := []struct {
+		prefix, message string
+		ok              bool
+	}{
+		{"", "fix(parser): handle empty input", true},
+		{"", "feat!: drop the v1 API\n\nBREAKING CHANGE: v1 is gone.", true},
+		{"", "Fix the parser", false},
+		{"", "fix:handle empty input", false},
+		{"[PROJ-1] ", "[PROJ-1] fix: handle empty input", true},
+		{"[PROJ-1] ", "fix: [PROJ-1] handle empty input", true},
+		{"[PROJ-1] ", "[PROJ-1] Fix the parser", false},
+	}
+	for _, tt := range tests {
+		if problems := conventionalCheck(tt.prefix)(tt.message); (len(problems) == 0) != tt.ok {
+			t.Errorf("conventionalCheck(%q)(%q) = %q", tt.prefix, tt.message, problems)
+		}
+	}
+}
+
+// TestEditReopensForConventional re-opens the editor when the edited
+// subject isn't conventional.
+func TestEditReopensForConventional(t *testing.T) {
+	dir := scriptedEditor(t, "Fix the parser\n", "fix(parser): handle empty input\n")
+	withInput(t, "y\n")
+	got, err := editUntilValid("fix: parser", nil, conventionalCheck(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "fix(parser): handle empty input" {
+		t.Errorf("editUntilValid = %q", got)
+	}
+	buffer, err := os.ReadFile(filepath.Join(dir, "buffer1"))
+	if err != nil {
+		t.Fatal("the editor was not opened again")
+	}
+	if !strings.Contains(string(buffer), validationCommentPrefix()+"the subject is not a conventional commit subject") || !strings.HasSuffix(string(buffer), "\nFix the parser") {
+		t.Errorf("the second buffer doesn't explain the problem:\n%s", buffer)
+	}
+}
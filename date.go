@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the absolute forms -date accepts besides "now".
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// offsetRe matches a numeric timezone offset such as +0200 or -05:30.
+var offsetRe = regexp.MustCompile(`^([+-])(\d{2}):?(\d{2})$`)
+
+// parseTimezone accepts an IANA zone name (Europe/Berlin), UTC, or a
+// numeric offset (+0200, -05:30).
+func parseTimezone(tz string) (*time.Location, error) {
+	if m := offsetRe.FindStringSubmatch(tz); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("invalid timezone offset %q", tz)
+		}
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(tz, offset), nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "" || tz == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q (use a name like Europe/Berlin or an offset like +0200)", tz)
+	}
+	return loc, nil
+}
+
+// commitDate resolves -date and -tz to the value passed to git, or "" to
+// let git use the current time and zone. Dates without an offset are read
+// in the -tz zone, or the local zone without one; with -tz, the result is
+// expressed in that zone.
+func commitDate(date, tz string, now time.Time) (string, error) {
+	if date == "" && tz == "" {
+		return "", nil
+	}
+	loc := time.Local
+	if tz != "" {
+		var err error
+		if loc, err = parseTimezone(tz); err != nil {
+			return "", err
+		}
+	}
+
+	t := now
+	if date != "" && date != "now" {
+		var err error
+		if t, err = parseDate(date, loc, now); err != nil {
+			return "", err
+		}
+	}
+	if tz != "" {
+		t = t.In(loc)
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+// dateIsNow reports whether -date asks for the current time.
+func dateIsNow(date string) bool {
+	return date == "" || nowWords[strings.ToLower(strings.TrimSpace(date))]
+}
+
+// commitDateAt resolves -date and -tz for a commit made at now. It is
+// called just before committing rather than at startup, since a run can
+// wait on the API and the user for minutes and "now" means the commit.
+// The date always applies to the author; it applies to the committer as
+// well only where git's own clock would be wrong: for an explicit date,
+// or for the current time in a -tz zone, and never with authorOnly.
+func commitDateAt(date, tz string, authorOnly bool, now time.Time) (string, bool, error) {
+	when, err := commitDate(date, tz, now)
+	if err != nil {
+		return "", false, err
+	}
+	return when, authorOnly || (dateIsNow(date) && tz == ""), nil
+}
+
+// parseDate reads the absolute layouts itself and leaves anything else to
+// git's own date parsing.
+func parseDate(date string, loc *time.Location, now time.Time) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, date, loc); err == nil {
+			return t, nil
+		}
+	}
+	if t, ok := gitDate(date, loc, now); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized -date %q (use now, 2006-01-02, 2006-01-02 15:04:05, RFC 3339 or a git date such as \"3 days ago\" or \"yesterday 17:00\")", date)
+}
+
+// nowWords are the expressions git reads as the current time on purpose.
+var nowWords = map[string]bool{"now": true, "today": true}
+
+// gitDate reads a date expression ("3 days ago", "last friday", "noon")
+// with git's rules, through git rev-parse --since, which prints it as a
+// timestamp. git reads anything it doesn't understand as the current
+// time, so that result is only accepted for an expression that means it.
+func gitDate(date string, loc *time.Location, now time.Time) (time.Time, bool) {
+	var env []string
+	if loc != time.Local {
+		// Named zones (not fixed offsets) can be handed to git.
+		if _, err := time.LoadLocation(loc.String()); err == nil {
+			env = append(env, "TZ="+loc.String())
+		}
+	}
+	output, err := gitCommandEnv(env, "rev-parse", "--since="+date).Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	secs, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(string(output)), "--max-age="), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t := time.Unix(secs, 0).In(loc)
+	if d := t.Sub(now); d > -5*time.Second && d < 5*time.Second && !nowWords[strings.ToLower(strings.TrimSpace(date))] {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// describeCommitDate spells out a -date for the summary shown before
+// committing, so an accidental backdate or future date is noticed.
+func describeCommitDate(date string, authorOnly bool, now time.Time) string {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return "Commit date: " + date
+	}
+	which := "author and committer"
+	if authorOnly {
+		which = "author only; the committer date is the current time"
+	}
+	return fmt.Sprintf("Commit date: %s (%s; %s)", t.Format("Mon, 02 Jan 2006 15:04:05 -0700"), relativeDate(t.Sub(now)), which)
+}
+
+// relativeDate describes an offset from now in the largest whole unit.
+func relativeDate(d time.Duration) string {
+	past := d < 0
+	if past {
+		d = -d
+	}
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		n, unit = int(d/time.Minute), "minute"
+	case d < 48*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	default:
+		n, unit = int(d/(24*time.Hour)), "day"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	if past {
+		return fmt.Sprintf("%d %s ago", n, unit)
+	}
+	return fmt.Sprintf("in %d %s", n, unit)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommitDateAt(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := start.Add(10 * time.Minute)
+	tests := []struct {
+		date, tz   string
+		authorOnly bool
+		want       string
+		wantAuthor bool
+	}{
+		{"", "", false, "", true},
+		{"now", "", false, later.In(time.Local).Format(time.RFC3339), true},
+		{"now", "+0200", false, "2024-03-01T14:10:00+02:00", false},
+		{"", "UTC", false, "2024-03-01T12:10:00Z", false},
+		{"2023-06-01", "UTC", false, "2023-06-01T00:00:00Z", false},
+		{"2023-06-01", "UTC", true, "2023-06-01T00:00:00Z", true},
+	}
+	for _, tt := range tests {
+		got, authorOnly, err := commitDateAt(tt.date, tt.tz, tt.authorOnly, later)
+		if err != nil {
+			t.Errorf("commitDateAt(%q, %q): %v", tt.date, tt.tz, err)
+			continue
+		}
+		if got != tt.want || authorOnly != tt.wantAuthor {
+			t.Errorf("commitDateAt(%q, %q, %v) = %q, %v; want %q, %v", tt.date, tt.tz, tt.authorOnly, got, authorOnly, tt.want, tt.wantAuthor)
+		}
+	}
+	if _, _, err := commitDateAt("now", "Mars/Olympus", false, start); err == nil {
+		t.Error("an unknown timezone was accepted")
+	}
+}
+
+func TestCommitArgsDate(t *testing.T) {
+	date := "2023-06-01T00:00:00Z"
+	args, env := commitOptions{Date: date, AuthorDateOnly: true}.gitArgs()
+	if !slices.Contains(args, "--date="+date) {
+		t.Errorf("args %q have no --date", args)
+	}
+	for _, e := range env {
+		if strings.HasPrefix(e, "GIT_COMMITTER_DATE=") {
+			t.Errorf("author-only commit sets %s", e)
+		}
+	}
+	_, env = commitOptions{Date: date}.gitArgs()
+	if !slices.Contains(env, "GIT_COMMITTER_DATE="+date) {
+		t.Errorf("env %q has no committer date", env)
+	}
+}
+
+func TestCommitDateNowIsCommitTime(t *testing.T) {
+	testRepo(t)
+	writeFile(t, "a.txt", "a\n")
+	mustGit(t, "add", "a.txt")
+
+	// A run that started an hour ago still commits at the current time.
+	start := time.Now().Add(-time.Hour)
+	_, authorOnly, err := commitDateAt("now", "", false, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	when, _ := commitDate("now", "", time.Now())
+	if _, err := gitCommit("Add a", commitOptions{Date: when, AuthorDateOnly: authorOnly}); err != nil {
+		t.Fatal(err)
+	}
+	var at, ct int64
+	if _, err := fmt.Sscan(mustGit(t, "log", "-1", "--format=%at %ct"), &at, &ct); err != nil {
+		t.Fatal(err)
+	}
+	if at < start.Add(30*time.Minute).Unix() {
+		t.Errorf("author date %v is the start of the run", time.Unix(at, 0))
+	}
+	if d := ct - at; d < -60 || d > 60 {
+		t.Errorf("author %v and committer %v dates differ", time.Unix(at, 0), time.Unix(ct, 0))
+	}
+}
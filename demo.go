@@ -0,0 +1,231 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The demo repository: a small fetcher, then a staged change adding retries
+// to it.
+const demoFetchBefore = `package fetch
+
+import (
+	"io"
+	"net/http"
+)
+
+// Get returns the body of url.
+func Get(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+`
+
+const demoFetchAfter = `package fetch
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxAttempts is how often Get tries before giving up.
+const maxAttempts = 3
+
+// Get returns the body of url, retrying network errors with backoff.
+func Get(url string) ([]byte, error) {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+		}
+		var resp *http.Response
+		if resp, err = http.Get(url); err != nil {
+			continue
+		}
+		defer resp.Body.Close()
+		return io.ReadAll(resp.Body)
+	}
+	return nil, err
+}
+`
+
+// demoReplies are the mock API's suggestions, in turn; rejecting one moves
+// on to the next.
+var demoReplies = []string{
+	"Retry failed fetches\n\nNetwork errors are often transient.",
+	"Retry network errors in fetch.Get with backoff\n\nA single dropped connection used to fail the whole fetch. Get now makes\nup to three attempts, waiting a little longer before each retry.",
+}
+
+const demoIntro = `This demo runs gitcommit for real, on a throwaway repository with a
+staged change and a mock API, so nothing leaves your machine and no API
+key is needed. It is removed when the demo ends.
+
+What happens:
+ 1. You describe the change in a few words. gitcommit sends that, the
+    staged diff and recent history to the API (here, the mock).
+ 2. A suggested message comes back. y commits it, e opens it in your
+    editor, n asks for another. Try n once to see a second suggestion.
+    -stream shows suggestions as they arrive; -explain-rejection asks
+    why you said no and tells the model.
+ 3. gitcommit checks the message (subject length, -kind, -changelog
+    trailers) and creates the commit. -suggest-only prints it instead,
+    and -a would commit all tracked changes rather than the staged ones.
+`
+
+// demoAPI is a stand-in for the messages endpoint that narrates each
+// request and answers from demoReplies.
+type demoAPI struct {
+	mu   sync.Mutex
+	sent int
+}
+
+func (d *demoAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	var req MessagesRequest
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	n := d.sent
+	d.sent++
+	d.mu.Unlock()
+	if n == 0 {
+		fmt.Println("\n[demo] The mock API received the diff and your description; here is its suggestion.")
+	} else {
+		fmt.Println("\n[demo] You answered n, so gitcommit asked the mock API for another suggestion.")
+	}
+	text := "```\n" + demoReplies[min(n, len(demoReplies)-1)] + "\n```"
+	if !req.Stream {
+		json.NewEncoder(w).Encode(MessagesResponse{
+			Content: []ContentBlock{{Type: "text", Text: text}},
+			Usage:   Usage{InputTokens: 800, OutputTokens: len(text) / 4},
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	event := func(v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	event(map[string]any{"type": "message_start", "message": map[string]any{"usage": Usage{InputTokens: 800}}})
+	for _, word := range strings.SplitAfter(text, " ") {
+		event(map[string]any{"type": "content_block_delta", "delta": map[string]string{"type": "text_delta", "text": word}})
+	}
+	event(map[string]any{"type": "message_delta", "usage": Usage{OutputTokens: len(text) / 4}})
+	event(map[string]string{"type": "message_stop"})
+}
+
+// setUpDemoRepo creates the demo repository in dir with the change staged.
+// Its local config keeps the user's global hooks and signing out of it.
+func setUpDemoRepo(dir string) error {
+	git := func(args ...string) error {
+		_, err := gitRun(append([]string{"-C", dir}, args...)...)
+		return err
+	}
+	hooks := filepath.Join(dir, ".git", "demo-hooks")
+	file := filepath.Join(dir, "fetch.go")
+	steps := []func() error{
+		func() error { _, err := gitRun("init", "-q", dir); return err },
+		func() error { return git("config", "user.name", "Demo User") },
+		func() error { return git("config", "user.email", "demo@example.com") },
+		func() error { return git("config", "commit.gpgsign", "false") },
+		func() error { return os.MkdirAll(hooks, 0755) },
+		func() error { return git("config", "core.hooksPath", hooks) },
+		func() error { return os.WriteFile(file, []byte(demoFetchBefore), 0644) },
+		func() error { return git("add", "fetch.go") },
+		func() error { return git("commit", "-q", "-m", "Add fetch.Get") },
+		func() error { return os.WriteFile(file, []byte(demoFetchAfter), 0644) },
+		func() error { return git("add", "fetch.go") },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return fmt.Errorf("error creating the demo repository: %v", err)
+		}
+	}
+	return nil
+}
+
+// runDemo implements `gitcommit demo`: it runs the full interactive flow
+// against a temporary repository and the mock API, then cleans up. With
+// scripted input it doubles as an end-to-end check of the prompt loop.
+func runDemo(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: gitcommit demo")
+	}
+	dir, err := os.MkdirTemp("", "gitcommit-demo-")
+	if err != nil {
+		return fmt.Errorf("error creating the demo directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	repo := filepath.Join(dir, "repo")
+	if err := setUpDemoRepo(repo); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("error starting the mock API: %v", err)
+	}
+	server := &http.Server{Handler: &demoAPI{}}
+	go server.Serve(listener)
+	defer server.Close()
+
+	config := filepath.Join(dir, "config.json")
+	cfg := userConfig{Profiles: map[string]*profile{"demo": {APIURL: "http://" + listener.Addr().String() + "/v1/messages"}}}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("error writing the demo config: %v", err)
+	}
+	if err := os.WriteFile(config, data, 0600); err != nil {
+		return fmt.Errorf("error writing the demo config: %v", err)
+	}
+
+	fmt.Print(demoIntro)
+	fmt.Printf("[demo] Repository: %s\n", repo)
+	fmt.Println("[demo] Staged: fetch.go, adding retries with backoff to fetch.Get.")
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error locating gitcommit: %v", err)
+	}
+	cmd := exec.Command(exe, "-C", repo, "-config", config, "-profile", "demo", "-no-consent-prompt")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), "CLAUDE_API_KEY=demo", "XDG_CONFIG_HOME="+filepath.Join(dir, "config"))
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("error running the demo: %v", err)
+		}
+	}
+
+	if log, err := gitRun("-C", repo, "log", "-1", "--format=%h %s"); err == nil && !strings.HasSuffix(log, " Add fetch.Get") {
+		fmt.Printf("\n[demo] Done: the demo repository now has %s\n", log)
+	} else {
+		fmt.Println("\n[demo] No commit was made this time.")
+	}
+	fmt.Println("[demo] The demo repository has been removed. Run gitcommit in one of your own repositories next; see gitcommit -help for every option.")
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestDemo runs `gitcommit demo` as a new user would: no config, no API
+// key, answering n to the first suggestion and y to the second.
+func TestDemo(t *testing.T) {
+	home, tmp := t.TempDir(), t.TempDir()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(exe, "demo")
+	cmd.Stdin = strings.NewReader("retries\nn\ny\n")
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); name != "CLAUDE_API_KEY" && name != profileEnv {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env, "GITCOMMIT_TEST_MAIN=1", "HOME="+home, "XDG_CONFIG_HOME="+filepath.Join(home, ".config"), "TMPDIR="+tmp, "NO_COLOR=1")
+	output, err := cmd.CombinedOutput()
+	out := string(output)
+	if err != nil {
+		t.Fatalf("gitcommit demo: %v\n%s", err, out)
+	}
+
+	m := regexp.MustCompile(`\[demo\] Repository: (.+)`).FindStringSubmatch(out)
+	if m == nil {
+		t.Fatalf("no repository in the output:\n%s", out)
+	}
+	if repo := m[1]; !strings.HasPrefix(repo, tmp) {
+		t.Errorf("the repository %s is outside TMPDIR", repo)
+	} else if _, err := os.Stat(repo); !os.IsNotExist(err) {
+		t.Errorf("the repository %s was not removed (%v)", repo, err)
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("left behind in TMPDIR: %v", entries)
+	}
+	if !regexp.MustCompile(`\[demo\] Done: the demo repository now has [0-9a-f]+ Retry network errors in fetch.Get with backoff\n`).MatchString(out) {
+		t.Errorf("the second suggestion was not committed:\n%s", out)
+	}
+	if !strings.Contains(out, "asked the mock API for another suggestion") {
+		t.Errorf("the n answer didn't reach the mock API:\n%s", out)
+	}
+	if entries, _ := os.ReadDir(home); len(entries) != 0 {
+		t.Errorf("the demo wrote to HOME: %v", entries)
+	}
+}
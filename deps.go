@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// depChange is one dependency whose version a manifest change adds,
+// removes or moves. From is empty for an added dependency and To for a
+// removed one.
+type depChange struct {
+	Name string
+	From string
+	To   string
+	File string
+}
+
+func (c depChange) String() string {
+	switch {
+	case c.From == "":
+		return fmt.Sprintf("add %s %s (%s)", c.Name, c.To, c.File)
+	case c.To == "":
+		return fmt.Sprintf("remove %s %s (%s)", c.Name, c.From, c.File)
+	}
+	return fmt.Sprintf("bump %s from %s to %s (%s)", c.Name, c.From, c.To, c.File)
+}
+
+var (
+	goModRequireRe  = regexp.MustCompile(`^\s*(?:require\s+)?([^\s()]+)\s+(v\S+)`)
+	packageJSONDep  = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*"([\^~>=< ]*v?\d[^"]*)"`)
+	cargoDepRe      = regexp.MustCompile(`^\s*([A-Za-z0-9_-]+)\s*=\s*"([^"]+)"`)
+	cargoTableDepRe = regexp.MustCompile(`^\s*([A-Za-z0-9_-]+)\s*=\s*\{.*\bversion\s*=\s*"([^"]+)"`)
+	requirementRe   = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9_.\[\],-]*)\s*(==|===|~=|>=|<=|!=|>|<)\s*([^\s;#]+)`)
+)
+
+// manifestKeys are keys in package.json and Cargo.toml that look like
+// dependencies but describe the package itself.
+var manifestKeys = map[string]bool{
+	"version": true, "name": true, "edition": true, "rust-version": true,
+	"node": true, "npm": true, "description": true, "license": true,
+}
+
+// parseDepLine reads the dependency and version on one manifest line, for
+// the manifests gitcommit knows: go.mod, package.json, Cargo.toml and pip
+// requirements files.
+func parseDepLine(file, line string) (name, version string, ok bool) {
+	base := path.Base(file)
+	var m []string
+	switch {
+	case base == "go.mod":
+		// Replacements point elsewhere rather than naming a version.
+		if strings.Contains(line, "=>") {
+			return "", "", false
+		}
+		m = goModRequireRe.FindStringSubmatch(line)
+		if m != nil && (m[1] == "module" || m[1] == "go" || m[1] == "toolchain") {
+			m = nil
+		}
+	case base == "package.json":
+		m = packageJSONDep.FindStringSubmatch(line)
+	case base == "Cargo.toml":
+		if m = cargoTableDepRe.FindStringSubmatch(line); m == nil {
+			m = cargoDepRe.FindStringSubmatch(line)
+		}
+	case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+		if r := requirementRe.FindStringSubmatch(line); r != nil {
+			v := r[3]
+			if r[2] != "==" {
+				v = r[2] + v
+			}
+			m = []string{r[0], r[1], v}
+		}
+	}
+	if m == nil || manifestKeys[m[1]] {
+		return "", "", false
+	}
+	return m[1], strings.TrimSpace(m[2]), true
+}
+
+// dependencyChanges lists the version changes in the manifests a diff
+// touches, matching removed and added lines by dependency name. Lock files
+// are left out; they repeat the manifests in more detail.
+func dependencyChanges(diff string) []depChange {
+	var changes []depChange
+	for _, f := range splitDiff(diff) {
+		if !isDepPath(f.Path) {
+			continue
+		}
+		var order []string
+		from, to := map[string]string{}, map[string]string{}
+		for _, line := range strings.Split(f.Text, "\n") {
+			if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") || line == "" {
+				continue
+			}
+			versions := from
+			switch line[0] {
+			case '-':
+			case '+':
+				versions = to
+			default:
+				continue
+			}
+			name, version, ok := parseDepLine(f.Path, line[1:])
+			if !ok {
+				continue
+			}
+			if _, seen := from[name]; !seen {
+				if _, seen := to[name]; !seen {
+					order = append(order, name)
+				}
+			}
+			versions[name] = version
+		}
+		for _, name := range order {
+			if from[name] != to[name] {
+				changes = append(changes, depChange{Name: name, From: from[name], To: to[name], File: f.Path})
+			}
+		}
+	}
+	return changes
+}
+
+// dependencyUpdateOnly reports whether every changed path is a dependency
+// manifest, lock file or vendored copy.
+func dependencyUpdateOnly(paths []string) bool {
+	return allPaths(paths, func(p string) bool {
+		return isDepPath(p) || strings.HasPrefix(p, "vendor/") || strings.Contains(p, "/vendor/")
+	})
+}
+
+// dependencyPrompt asks for the usual dependency-update message, built
+// from the version changes found in the manifests.
+func dependencyPrompt(changes []depChange, conventional bool) string {
+	prefix := "deps: "
+	if conventional {
+		prefix = "build(deps): "
+	}
+	var b strings.Builder
+	b.WriteString("These changes only update dependencies. Version changes in the manifests:\n")
+	for _, c := range changes {
+		fmt.Fprintf(&b, "- %s\n", c)
+	}
+	fmt.Fprintf(&b, "Start the subject with %q. For a single change, write the subject like %q. For several, summarize them in the subject (naming the main one if there is one) and list every change in the body, one per line.",
+		prefix, prefix+strings.TrimSuffix(changes[0].String(), " ("+changes[0].File+")"))
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Diffs are read with bounded memory: files too big to be worth sending
+// are found from their blob sizes and left out before any content is read,
+// and what is read from git is capped as it streams in.
+const (
+	// maxFileDiffBytes is the largest file, before or after the change,
+	// whose content goes into the diff.
+	maxFileDiffBytes = 4 << 20
+	// maxDiffReadBytes caps how much diff output is read from git at all.
+	maxDiffReadBytes = 8 << 20
+)
+
+// oversizedFile is a changed file left out of the diff for its size.
+type oversizedFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// findOversized returns the changed files larger than maxFileDiffBytes on
+// either side of the change. Sizes come from `git cat-file --batch-check`,
+// or from the work tree for content only there (with -a), so no content is
+// read.
+func findOversized(all bool) ([]oversizedFile, error) {
+	raw, err := getRawChanges(all)
+	if err != nil {
+		return nil, err
+	}
+	top, err := gitRun("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("error locating work tree: %v", err)
+	}
+	var shas []string
+	for _, c := range raw {
+		for _, sha := range []string{c.OldSha, c.NewSha} {
+			if !isNullSha(sha) {
+				shas = append(shas, sha)
+			}
+		}
+	}
+	sizes := map[string]int64{}
+	if len(shas) > 0 {
+		cmd := gitCommand("cat-file", "--batch-check=%(objectname) %(objectsize)")
+		cmd.Stdin = strings.NewReader(strings.Join(shas, "\n") + "\n")
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("error reading file sizes: %v", err)
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			if sha, size, ok := strings.Cut(line, " "); ok {
+				sizes[sha], _ = strconv.ParseInt(size, 10, 64)
+			}
+		}
+	}
+	var big []oversizedFile
+	for _, c := range raw {
+		size := max(sizes[c.OldSha], sizes[c.NewSha])
+		if isNullSha(c.NewSha) && c.Status != 'D' {
+			if info, err := os.Lstat(filepath.Join(top, c.Path)); err == nil {
+				size = max(size, info.Size())
+			}
+		}
+		if size > maxFileDiffBytes {
+			big = append(big, oversizedFile{Path: c.Path, Size: size})
+		}
+	}
+	return big, nil
+}
+
+// excludePathspecs returns pathspecs that keep the files out of a diff.
+// The paths are relative to the top of the work tree, like git's output.
+func excludePathspecs(files []oversizedFile) []string {
+	if len(files) == 0 {
+		return nil
+	}
+	specs := []string{"--", ":/"}
+	for _, f := range files {
+		specs = append(specs, ":(top,literal,exclude)"+f.Path)
+	}
+	return specs
+}
+
+// readCapped runs cmd and returns at most limit bytes of its output. Past
+// that, git is killed rather than read to the end, and the output is cut
+// back to the last complete file; truncated says so.
+func readCapped(cmd *exec.Cmd, limit int) (output string, truncated bool, err error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", false, err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", false, err
+	}
+	data, err := io.ReadAll(io.LimitReader(bufio.NewReader(stdout), int64(limit)+1))
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return "", false, err
+	}
+	if len(data) > limit {
+		cmd.Process.Kill()
+		cmd.Wait()
+		data = data[:limit]
+		if i := bytes.LastIndex(data, []byte("\ndiff --git ")); i >= 0 {
+			data = data[:i+1]
+		}
+		return string(data), true, nil
+	}
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", false, fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", false, err
+	}
+	return string(data), false, nil
+}
+
+// oversizedPrompt tells the model which files changed without their
+// content being shown.
+func oversizedPrompt(files []oversizedFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("These files also changed, but are too large for their content to be included:")
+	for _, f := range files {
+		fmt.Fprintf(&b, "\n- %s (%s)", f.Path, formatBytes(int(f.Size)))
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindOversized(t *testing.T) {
+	testRepo(t)
+	commitFile(t, "big.bin", "small for now\n", "Add big.bin")
+	commitFile(t, "small.txt", "one\n", "Add small.txt")
+	big := strings.Repeat("0123456789abcdef\n", maxFileDiffBytes/17+1)
+	writeFile(t, "big.bin", big)
+	writeFile(t, "small.txt", "one\ntwo\n")
+	mustGit(t, "add", ".")
+
+	files, err := findOversized(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "big.bin" || files[0].Size != int64(len(big)) {
+		t.Fatalf("findOversized = %+v, want big.bin at %d bytes", files, len(big))
+	}
+	diff, truncated, err := getDiff(false, excludePathspecs(files))
+	if err != nil || truncated {
+		t.Fatalf("getDiff: truncated %v, %v", truncated, err)
+	}
+	if strings.Contains(diff, "big.bin") || !strings.Contains(diff, "+two") {
+		t.Errorf("the diff still has big.bin or lost small.txt:\n%.500s", diff)
+	}
+	if p := oversizedPrompt(files); !strings.Contains(p, "- big.bin (") {
+		t.Errorf("oversizedPrompt = %q", p)
+	}
+
+	// A file that only grew in the work tree counts with -a.
+	mustGit(t, "reset", "-q")
+	mustGit(t, "checkout", "--", "big.bin")
+	writeFile(t, "big.bin", big)
+	if files, err := findOversized(false); err != nil || len(files) != 0 {
+		t.Errorf("with nothing staged findOversized = %+v, %v", files, err)
+	}
+	if files, err := findOversized(true); err != nil || len(files) != 1 || files[0].Path != "big.bin" {
+		t.Errorf("with -a findOversized = %+v, %v", files, err)
+	}
+}
+
+func TestReadCapped(t *testing.T) {
+	testRepo(t)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		writeFile(t, name, strings.Repeat(name+"\n", 200))
+	}
+	mustGit(t, "add", ".")
+	full := mustGit(t, "diff", "--cached") + "\n"
+
+	got, truncated, err := readCapped(gitCommand("diff", "--cached"), len(full))
+	if err != nil || truncated || got != full {
+		t.Errorf("within the limit: truncated %v, err %v, same output %v", truncated, err, got == full)
+	}
+
+	for _, limit := range []int{len(full) - 1, len(full) / 2, 100} {
+		got, truncated, err := readCapped(gitCommand("diff", "--cached"), limit)
+		if err != nil || !truncated {
+			t.Errorf("limit %d: truncated %v, err %v", limit, truncated, err)
+		}
+		if len(got) > limit {
+			t.Errorf("limit %d: read %d bytes", limit, len(got))
+		}
+		if !strings.HasPrefix(full, got) {
+			t.Errorf("limit %d: the output is not a prefix of the diff", limit)
+		}
+		// The cut falls between files when there is an earlier one.
+		if strings.Count(full[:limit], "diff --git ") > 1 && !strings.HasPrefix(full[len(got):], "diff --git ") {
+			t.Errorf("limit %d: cut in the middle of a file", limit)
+		}
+	}
+
+	if _, _, err := readCapped(gitCommand("diff", "--no-such-option"), 100); err == nil || !strings.Contains(err.Error(), "no-such-option") {
+		t.Errorf("a failing command gave %v, want its stderr", err)
+	}
+}
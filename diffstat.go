@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fileStat is one entry of `git diff --numstat`.
+type fileStat struct {
+	Path    string
+	OldPath string // set for renames and copies
+	Added   int
+	Deleted int
+	Binary  bool
+}
+
+func (f fileStat) churn() int {
+	return f.Added + f.Deleted
+}
+
+func diffArgs(all bool, extra ...string) []string {
+	args := []string{"diff"}
+	if !all {
+		args = append(args, "--cached")
+		// Before the first commit there is no HEAD to compare the index
+		// with, so compare it with the empty tree to show every new file.
+		if tree, ok := initialCommitBase(); ok {
+			args = append(args, tree)
+		}
+	}
+	return append(args, extra...)
+}
+
+var (
+	headOnce  sync.Once
+	headExist bool
+	emptyTree string
+)
+
+// hasHead reports whether the repository has at least one commit.
+func hasHead() bool {
+	headOnce.Do(func() {
+		err := gitCommand("rev-parse", "--verify", "--quiet", "HEAD").Run()
+		headExist = err == nil
+		if !headExist {
+			// Works for both SHA-1 and SHA-256 repositories.
+			output, err := gitCommand("hash-object", "-t", "tree", os.DevNull).Output()
+			if err == nil {
+				emptyTree = strings.TrimSpace(string(output))
+			}
+		}
+	})
+	return headExist
+}
+
+// resetHeadCache forgets what hasHead learned, for a process that moves
+// between repositories.
+func resetHeadCache() {
+	headOnce = sync.Once{}
+	headExist = false
+	emptyTree = ""
+}
+
+// initialCommitBase returns the empty tree to diff against when the
+// repository has no commits yet.
+func initialCommitBase() (string, bool) {
+	if hasHead() || emptyTree == "" {
+		return "", false
+	}
+	return emptyTree, true
+}
+
+func getNumstat(all bool) ([]fileStat, error) {
+	cmd := gitCommand(diffArgs(all, "--numstat", "-z", "-M")...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting diff stats: %v", err)
+	}
+	return parseNumstat(string(output)), nil
+}
+
+// parseNumstat parses `git diff --numstat -z` output. Renamed entries have
+// an empty path field followed by the old and new paths as separate fields.
+func parseNumstat(output string) []fileStat {
+	var stats []fileStat
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		var st fileStat
+		if parts[0] == "-" && parts[1] == "-" {
+			st.Binary = true
+		} else {
+			st.Added, _ = strconv.Atoi(parts[0])
+			st.Deleted, _ = strconv.Atoi(parts[1])
+		}
+		st.Path = parts[2]
+		if st.Path == "" && i+2 < len(fields) {
+			st.OldPath = fields[i+1]
+			st.Path = fields[i+2]
+			i += 2
+		}
+		stats = append(stats, st)
+	}
+	return stats
+}
+
+func isTestFile(p string) bool {
+	base := path.Base(p)
+	return strings.HasSuffix(base, "_test.go") ||
+		strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.") ||
+		strings.HasPrefix(base, "test_") ||
+		strings.HasPrefix(p, "test/") || strings.HasPrefix(p, "tests/") ||
+		strings.Contains(p, "/test/") || strings.Contains(p, "/tests/") ||
+		strings.Contains(p, "testdata/")
+}
+
+func isDocFile(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".md", ".rst", ".txt", ".adoc":
+		return true
+	}
+	return strings.HasPrefix(p, "docs/") || strings.Contains(p, "/docs/")
+}
+
+// suggestReviewerNote proposes a single reviewer-facing scope hint based on
+// the shape of the diff. It returns "" when nothing useful can be said. Only
+// claims that follow directly from the stats are made; in particular "no
+// content changes" is only stated for pure renames.
+func suggestReviewerNote(stats []fileStat) string {
+	if len(stats) == 0 {
+		return ""
+	}
+
+	var total, tests, docs, renames int
+	packages := make(map[string]bool)
+	largest := stats[0]
+	for _, st := range stats {
+		total += st.churn()
+		if st.churn() > largest.churn() {
+			largest = st
+		}
+		if isTestFile(st.Path) {
+			tests++
+		}
+		if isDocFile(st.Path) {
+			docs++
+		}
+		if st.OldPath != "" && st.churn() == 0 && !st.Binary {
+			renames++
+		}
+		packages[path.Dir(st.Path)] = true
+	}
+
+	switch {
+	case renames == len(stats):
+		return fmt.Sprintf("Reviewer note: mechanical rename/move of %d file(s); no content changes.", renames)
+	case tests == len(stats):
+		return "Reviewer note: test-only change; no production code touched."
+	case docs == len(stats):
+		return "Reviewer note: documentation-only change."
+	case len(stats) >= 3 && total > 0 && largest.churn()*100/total >= 80:
+		return fmt.Sprintf("Reviewer note: most of the change is in %s; focus review there.", largest.Path)
+	case len(packages) >= 5:
+		return fmt.Sprintf("Reviewer note: touches %d directories; mostly cross-cutting edits.", len(packages))
+	case tests == 0 && docs < len(stats) && total >= 50:
+		return "Reviewer note: changes code without touching tests; please check coverage."
+	}
+	return ""
+}
+
+// appendToBody adds a paragraph to the end of a commit message, ensuring a
+// blank line separates it from the subject or existing body.
+func appendToBody(message, paragraph string) string {
+	message = strings.TrimRight(message, "\n")
+	return message + "\n\n" + paragraph
+}
+
+// insertIntoBody adds a paragraph at the end of the body, before the
+// trailer block if there is one, so trailers stay last where git and the
+// changelog look for them.
+func insertIntoBody(message, paragraph string) string {
+	paragraphs := strings.Split(strings.TrimRight(message, "\n"), "\n\n")
+	if n := len(paragraphs); n > 1 && isTrailerBlock(paragraphs[n-1]) {
+		return strings.Join(append(paragraphs[:n-1], paragraph, paragraphs[n-1]), "\n\n")
+	}
+	return appendToBody(message, paragraph)
+}
+
+// fileChange is one entry of `git diff --name-status`.
+type fileChange struct {
+	Status     byte // A, M, D, R, C, T, ...
+	Similarity int  // for renames and copies
+	Path       string
+	OldPath    string
+}
+
+func getNameStatus(all bool) ([]fileChange, error) {
+	cmd := gitCommand(diffArgs(all, "--name-status", "-z", "-M")...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting changed files: %v", err)
+	}
+	return parseNameStatus(string(output)), nil
+}
+
+// parseNameStatus parses `git diff --name-status -z` output, where renames
+// and copies are followed by both the old and the new path.
+func parseNameStatus(output string) []fileChange {
+	var changes []fileChange
+	fields := strings.Split(output, "\x00")
+	for i := 0; i+1 < len(fields); i++ {
+		status := fields[i]
+		if status == "" {
+			continue
+		}
+		ch := fileChange{Status: status[0]}
+		if ch.Status == 'R' || ch.Status == 'C' {
+			if i+2 >= len(fields) {
+				break
+			}
+			ch.Similarity, _ = strconv.Atoi(status[1:])
+			ch.OldPath = fields[i+1]
+			ch.Path = fields[i+2]
+			i += 2
+		} else {
+			ch.Path = fields[i+1]
+			i++
+		}
+		changes = append(changes, ch)
+	}
+	return changes
+}
+
+// changedFilesPrompt summarizes the changed files, spelling out renames so a
+// move is not mistaken for an unrelated delete and add.
+func changedFilesPrompt(changes []fileChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Changed files:\n")
+	for _, ch := range changes {
+		switch ch.Status {
+		case 'R':
+			if ch.Similarity == 100 {
+				fmt.Fprintf(&b, "- renamed %s to %s (content unchanged)\n", ch.OldPath, ch.Path)
+			} else {
+				fmt.Fprintf(&b, "- renamed %s to %s (%d%% similar)\n", ch.OldPath, ch.Path, ch.Similarity)
+			}
+		case 'C':
+			fmt.Fprintf(&b, "- copied %s to %s (%d%% similar)\n", ch.OldPath, ch.Path, ch.Similarity)
+		case 'A':
+			fmt.Fprintf(&b, "- added %s\n", ch.Path)
+		case 'D':
+			fmt.Fprintf(&b, "- deleted %s\n", ch.Path)
+		case 'T':
+			fmt.Fprintf(&b, "- changed file type of %s\n", ch.Path)
+		default:
+			fmt.Fprintf(&b, "- modified %s\n", ch.Path)
+		}
+	}
+	return b.String()
+}
+
+func changePaths(changes []fileChange) []string {
+	paths := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		paths = append(paths, ch.Path)
+	}
+	return paths
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFirstCommit(t *testing.T) {
+	testRepo(t)
+	writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, "docs/README.md", "# Demo\n")
+	mustGit(t, "add", ".")
+
+	if hasHead() {
+		t.Fatal("hasHead in an empty repository")
+	}
+	snap, err := gatherSnapshot(false, true)
+	if err != nil {
+		t.Fatalf("gatherSnapshot: %v", err)
+	}
+	for _, want := range []string{"new file mode", "+func main() {}", "+# Demo"} {
+		if !strings.Contains(snap.Diff, want) {
+			t.Errorf("diff is missing %q:\n%s", want, snap.Diff)
+		}
+	}
+	if len(snap.Changes) != 2 {
+		t.Errorf("changes = %v, want both new files", snap.Changes)
+	}
+	if len(snap.History) != 0 {
+		t.Errorf("history = %v, want none before the first commit", snap.History)
+	}
+	stats, err := getNumstat(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := changedLines(stats); n != 4 {
+		t.Errorf("changed lines = %d, want 4", n)
+	}
+
+	if _, err := gitCommit("Initial commit\n", commitOptions{}); err != nil {
+		t.Fatalf("gitCommit: %v", err)
+	}
+	if got := mustGit(t, "log", "--format=%s"); got != "Initial commit" {
+		t.Errorf("log = %q, want the one commit", got)
+	}
+}
+
+func TestInsertIntoBody(t *testing.T) {
+	tests := []struct{ message, want string }{
+		{"Fix it", "Fix it\n\nNote."},
+		{"Fix it\n\nBody.\n", "Fix it\n\nBody.\n\nNote."},
+		{"Fix it\n\nChangelog: fixed", "Fix it\n\nNote.\n\nChangelog: fixed"},
+		{"Fix it\n\nBody.\n\nChangelog: fixed\nComponent: api\n", "Fix it\n\nBody.\n\nNote.\n\nChangelog: fixed\nComponent: api"},
+		{"Fix it\n\nBody: not a trailer block\nbecause of this line", "Fix it\n\nBody: not a trailer block\nbecause of this line\n\nNote."},
+	}
+	for _, tt := range tests {
+		if got := insertIntoBody(tt.message, "Note."); got != tt.want {
+			t.Errorf("insertIntoBody(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+// TestReviewerNoteBeforeTrailers follows finish: the reviewer note goes
+// into the body and the changelog trailers are added after it.
+func TestReviewerNoteBeforeTrailers(t *testing.T) {
+	testRepo(t)
+	writeFile(t, "main.go", strings.Repeat("var x = 1\n", 60))
+	mustGit(t, "add", ".")
+	defer func(old bool) { assumeYes = old }(assumeYes)
+	assumeYes = true
+
+	message := offerReviewerNote("Add x\n\nSigned-off-by: Test <test@example.com>", false)
+	message = setTrailer(message, "Changelog", "added")
+	paragraphs := strings.Split(message, "\n\n")
+	if len(paragraphs) != 3 || !strings.HasPrefix(paragraphs[1], "Reviewer note:") {
+		t.Fatalf("the note is not in the body:\n%s", message)
+	}
+	if want := "Signed-off-by: Test <test@example.com>\nChangelog: added"; paragraphs[2] != want {
+		t.Errorf("trailer block = %q, want %q", paragraphs[2], want)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// earlyHook is a pre-commit hook run started while the message is being
+// written (-early-hooks), so a slow hook overlaps with generation instead
+// of following it.
+type earlyHook struct {
+	hook   hookInfo
+	dir    string
+	tree   string
+	cmd    *exec.Cmd
+	output bytes.Buffer
+	start  time.Time
+	done   chan struct{}
+	err    error
+	took   time.Duration
+}
+
+// startEarlyHook starts the pre-commit hook against the staged tree as it
+// is now. It returns nil when no pre-commit hook is installed.
+func startEarlyHook() (*earlyHook, error) {
+	dir, err := resolveHooksDir()
+	if err != nil {
+		return nil, err
+	}
+	h, ok := findHook(dir, "pre-commit")
+	if !ok {
+		return nil, nil
+	}
+	tree, err := gitRun("write-tree")
+	if err != nil {
+		return nil, fmt.Errorf("error recording the staged tree: %v", err)
+	}
+	top, err := gitRun("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("error locating work tree: %v", err)
+	}
+
+	if h.isPreCommitFramework() {
+		if err := gitCommand("diff", "--quiet").Run(); err != nil {
+			fmt.Println("pre-commit sets your unstaged changes aside while it runs; they are back once it finishes.")
+		}
+	}
+
+	e := &earlyHook{hook: h, dir: dir, tree: tree, start: time.Now(), done: make(chan struct{})}
+	// git runs hooks from the top of the work tree with no editor.
+	e.cmd = exec.Command(h.Path)
+	e.cmd.Dir = top
+	e.cmd.Env = append(os.Environ(), "GIT_EDITOR=:")
+	e.cmd.Stdout, e.cmd.Stderr = &e.output, &e.output
+	if err := e.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting pre-commit hook: %v", err)
+	}
+	go func() {
+		e.err = e.cmd.Wait()
+		e.took = time.Since(e.start)
+		close(e.done)
+	}()
+	return e, nil
+}
+
+// frameworkStopTimeout is how long the pre-commit framework gets to put
+// unstaged changes back after being interrupted.
+const frameworkStopTimeout = 10 * time.Second
+
+// stop ends a run that is no longer needed. The pre-commit framework is
+// interrupted rather than killed, and waited for, so it can restore the
+// unstaged changes it set aside; on Windows, which has no interrupt, it
+// is simply waited for.
+func (e *earlyHook) stop() {
+	if e == nil {
+		return
+	}
+	select {
+	case <-e.done:
+		return
+	default:
+	}
+	if !e.hook.isPreCommitFramework() {
+		e.cmd.Process.Kill()
+		return
+	}
+	fmt.Println("Waiting for pre-commit to put your unstaged changes back...")
+	if runtime.GOOS != "windows" {
+		e.cmd.Process.Signal(os.Interrupt)
+		select {
+		case <-e.done:
+			return
+		case <-time.After(frameworkStopTimeout):
+			e.cmd.Process.Kill()
+		}
+	}
+	<-e.done
+}
+
+// passed waits for the run to finish and reports whether its result still
+// holds for the commit: the hook succeeded and the staged tree is the one
+// it checked. Otherwise it says why hooks will run as usual.
+func (e *earlyHook) passed() bool {
+	select {
+	case <-e.done:
+	default:
+		fmt.Printf("Waiting for the pre-commit hook started %s ago...\n", time.Since(e.start).Round(time.Second))
+		<-e.done
+	}
+	if e.err != nil {
+		fmt.Printf("The early pre-commit run failed (%v); hooks will run again with the commit.\n", e.err)
+		if out := strings.TrimSpace(e.output.String()); out != "" {
+			fmt.Println(out)
+		}
+		return false
+	}
+	// Only the staged tree object counts: the work tree, and the index's
+	// stat data, may have been rewritten by a hook (the pre-commit
+	// framework's stash and restore) without the staged content changing.
+	tree, err := gitRun("write-tree")
+	if err != nil {
+		fmt.Printf("Could not check the staged changes after the early pre-commit run (%v); hooks will run again with the commit.\n", err)
+		return false
+	}
+	if tree != e.tree {
+		fmt.Println("The staged changes differ from what the early pre-commit run checked; hooks will run again with the commit.")
+		return false
+	}
+	fmt.Printf("pre-commit passed earlier (took %s); it won't run again for the commit.\n", e.took.Round(time.Second))
+	return true
+}
+
+// hooksWithoutPreCommit returns a directory that mirrors the hooks
+// directory except for pre-commit, to use as core.hooksPath for the commit.
+// Unlike --no-verify, this keeps commit-msg and the other hooks. Every
+// entry is linked, not only hooks, so hooks that source helpers next to
+// themselves (husky) still find them. The caller removes the directory.
+func hooksWithoutPreCommit(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("error reading hooks: %v", err)
+	}
+	tmp, err := os.MkdirTemp("", "gitcommit-hooks-*")
+	if err != nil {
+		return "", fmt.Errorf("error creating hooks directory: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == "pre-commit" {
+			continue
+		}
+		if err := os.Symlink(filepath.Join(dir, entry.Name()), filepath.Join(tmp, entry.Name())); err != nil {
+			os.RemoveAll(tmp)
+			return "", fmt.Errorf("error linking hook %s: %v", entry.Name(), err)
+		}
+	}
+	return tmp, nil
+}
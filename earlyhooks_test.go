@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frameworkHook stands in for a hook installed by the pre-commit
+// framework: it sets the unstaged changes aside with git stash, records
+// what it sees of f.txt in $HOOK_SAW, runs body and puts the changes back.
+func frameworkHook(body string) string {
+	return `#!/bin/sh
+# File generated by pre-commit: https://pre-commit.com
+restore() { git reset -q --hard && git stash pop -q --index; }
+git stash push -q --keep-index || exit 1
+cat f.txt > "$HOOK_SAW"
+` + body + `
+restore
+`
+}
+
+// stashRepo stages one change to f.txt and leaves another unstaged, with
+// hook installed as pre-commit. It returns the file the hook records to.
+func stashRepo(t *testing.T, hook string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook is a shell script")
+	}
+	dir := testRepo(t)
+	commitFile(t, "f.txt", "one\n", "Add f")
+	writeFile(t, "f.txt", "one\nstaged\n")
+	mustGit(t, "add", "f.txt")
+	writeFile(t, "f.txt", "one\nstaged\nunstaged\n")
+	if err := os.WriteFile(filepath.Join(dir, ".git", "hooks", "pre-commit"), []byte(hook), 0755); err != nil {
+		t.Fatal(err)
+	}
+	saw := filepath.Join(t.TempDir(), "saw")
+	t.Setenv("HOOK_SAW", saw)
+	return saw
+}
+
+// checkRestored checks that the staged and unstaged changes are as
+// stashRepo left them.
+func checkRestored(t *testing.T, tree string) {
+	t.Helper()
+	if got := mustGit(t, "write-tree"); got != tree {
+		t.Errorf("staged tree %s, want %s", got, tree)
+	}
+	if got := mustGit(t, "diff", "--cached"); !strings.Contains(got, "+staged") || strings.Contains(got, "+unstaged") {
+		t.Errorf("staged diff:\n%s", got)
+	}
+	if data, _ := os.ReadFile("f.txt"); string(data) != "one\nstaged\nunstaged\n" {
+		t.Errorf("work tree f.txt = %q", data)
+	}
+	if stashes := mustGit(t, "stash", "list"); stashes != "" {
+		t.Errorf("stash left behind: %s", stashes)
+	}
+}
+
+func TestEarlyHookFrameworkStash(t *testing.T) {
+	saw := stashRepo(t, frameworkHook(""))
+	tree := mustGit(t, "write-tree")
+	key, err := snapshotKey(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := startEarlyHook()
+	if err != nil || e == nil {
+		t.Fatalf("startEarlyHook = %v, %v", e, err)
+	}
+	if !e.hook.isPreCommitFramework() {
+		t.Error("the framework's hook was not recognized")
+	}
+	if !e.passed() {
+		t.Errorf("the run did not pass:\n%s", e.output.String())
+	}
+	if data, _ := os.ReadFile(saw); string(data) != "one\nstaged\n" {
+		t.Errorf("the hook saw %q, want only the staged content", data)
+	}
+	checkRestored(t, tree)
+
+	// The stash and restore rewrote the index, not what is staged.
+	if after, err := snapshotKey(false); err != nil || after != key {
+		t.Errorf("snapshot key changed from %q to %q (%v)", key, after, err)
+	}
+}
+
+func TestEarlyHookStagedChangeDetected(t *testing.T) {
+	stashRepo(t, frameworkHook(""))
+	e, err := startEarlyHook()
+	if err != nil || e == nil {
+		t.Fatalf("startEarlyHook = %v, %v", e, err)
+	}
+	<-e.done
+	writeFile(t, "g.txt", "new\n")
+	mustGit(t, "add", "g.txt")
+	if e.passed() {
+		t.Error("the run passed although the staged tree changed")
+	}
+}
+
+func TestEarlyHookFrameworkStop(t *testing.T) {
+	// The hook is still running when it is no longer needed; interrupted,
+	// it puts the unstaged changes back before exiting.
+	saw := stashRepo(t, frameworkHook(`trap 'kill $! 2>/dev/null; restore; exit 130' INT
+sleep 30 &
+wait`))
+	tree := mustGit(t, "write-tree")
+	e, err := startEarlyHook()
+	if err != nil || e == nil {
+		t.Fatalf("startEarlyHook = %v, %v", e, err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(saw); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the hook never started")
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	e.stop()
+	checkRestored(t, tree)
+}
+
+func TestIsPreCommitFramework(t *testing.T) {
+	dir := t.TempDir()
+	for name, script := range map[string]string{
+		"framework": frameworkHook(""),
+		"plain":     "#!/bin/sh\nexec make lint\n",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if got := (hookInfo{Name: "pre-commit", Path: p}).isPreCommitFramework(); got != (name == "framework") {
+			t.Errorf("%s: isPreCommitFramework = %v", name, got)
+		}
+	}
+	if (hookInfo{Path: filepath.Join(dir, "missing")}).isPreCommitFramework() {
+		t.Error("a missing hook was recognized")
+	}
+}
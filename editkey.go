@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+)
+
+// editKey, pressed while a suggestion streams in, stops the response and
+// opens what has arrived in the editor.
+const editKey = 'e'
+
+// errStreamStopped is returned by askClaude when the user pressed editKey.
+// Text is what had arrived by then.
+type errStreamStopped struct {
+	Text string
+}
+
+func (e *errStreamStopped) Error() string {
+	return "generation stopped to edit"
+}
+
+// keyWatcher reads single keys from the terminal while a response streams.
+type keyWatcher struct {
+	saved   string
+	stop    chan struct{}
+	done    chan struct{}
+	pressed atomic.Bool
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// watchEditKey puts the terminal in character mode and calls cancel when
+// editKey is pressed. Reads time out every tenth of a second, so the
+// watcher can stop without swallowing input meant for the next prompt.
+// It returns nil where the terminal can't do that (see rawKeysSupported).
+func watchEditKey(cancel func()) *keyWatcher {
+	if !rawKeysSupported() {
+		return nil
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil
+	}
+	if _, err := stty("-icanon", "-echo", "min", "0", "time", "1"); err != nil {
+		return nil
+	}
+	w := &keyWatcher{saved: saved, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		buf := make([]byte, 1)
+		for {
+			select {
+			case <-w.stop:
+				return
+			default:
+			}
+			if n, _ := os.Stdin.Read(buf); n == 1 && buf[0] == editKey {
+				w.pressed.Store(true)
+				cancel()
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// close stops watching and restores the terminal. It reports whether
+// editKey was pressed.
+func (w *keyWatcher) close() bool {
+	if w == nil {
+		return false
+	}
+	close(w.stop)
+	<-w.done
+	stty(w.saved)
+	return w.pressed.Load()
+}
+
+// stoppedBuffer is the editor buffer for a stopped generation: the partial
+// suggestion, then comments marking it as partial and quoting the user's
+// own message. The comments are removed again after editing.
+func stoppedBuffer(partial, original string) string {
+	text := partial
+	if _, rest, ok := strings.Cut(partial, "```"); ok {
+		// Drop the rest of the fence line and anything after a closing fence.
+		if _, after, ok := strings.Cut(rest, "\n"); ok {
+			rest = after
+		}
+		text, _, _ = strings.Cut(rest, "```")
+	}
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(text) + "\n\n")
+	b.WriteString(validationCommentPrefix() + "generation was stopped early; the text above is what had arrived\n")
+	if original != "" {
+		b.WriteString(validationCommentPrefix() + "your message was:\n")
+		for _, line := range strings.Split(original, "\n") {
+			b.WriteString(validationCommentPrefix() + "  " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// editKeyHint is shown before a stream that can be stopped with editKey.
+var editKeyHint = fmt.Sprintf("(press %c to stop and edit)", editKey)
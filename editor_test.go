@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// runGitcommit runs the test binary as gitcommit in the current directory
+// against an API that always suggests suggestion, feeding it input as
+// piped stdin, with env added to the environment. It returns the combined
+// output.
+func runGitcommit(t *testing.T, suggestion, input string, env []string, args ...string) string {
+	t.Helper()
+	reply := []ContentBlock{{Type: "text", Text: "```\n" + suggestion + "\n```"}}
+	return runGitcommitReply(t, reply, input, env, args...)
+}
+
+// runGitcommitReply is runGitcommit with an API that always replies with
+// the given content blocks.
+func runGitcommitReply(t *testing.T, reply []ContentBlock, input string, env []string, args ...string) string {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(MessagesResponse{Content: reply})
+	}))
+	defer api.Close()
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.json")
+	data, _ := json.Marshal(userConfig{Profiles: map[string]*profile{"test": {APIURL: api.URL}}})
+	if err := os.WriteFile(config, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(exe, append([]string{"-config", config, "-profile", "test", "-no-consent-prompt"}, args...)...)
+	cmd.Stdin = strings.NewReader(input)
+	// NO_COLOR is only set when the caller passes it.
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "NO_COLOR=") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env, append([]string{"GITCOMMIT_TEST_MAIN=1", "CLAUDE_API_KEY=test"}, env...)...)
+	output, _ := cmd.CombinedOutput()
+	return string(output)
+}
+
+// TestEditWithoutEditor edits the suggestion where no full-screen editor
+// can run: on a TERM=dumb terminal, or when the editor can't be started.
+// The text is typed at the prompt instead. The dumb terminal runs without
+// NO_COLOR, so the output is plain because of TERM alone.
+func TestEditWithoutEditor(t *testing.T) {
+	tests := []struct {
+		name, input, want, notice string
+		env                       []string
+	}{
+		{"dumb terminal", "readme\ne\nAdd a README\n\nIt says what the demo is.\n.\n", "Add a README\n\nIt says what the demo is.",
+			"can't run a full-screen editor", []string{"TERM=dumb", "GIT_EDITOR=false"}},
+		{"dumb terminal keeps text", "readme\ne\n.\ny\n", "Add readme",
+			"can't run a full-screen editor", []string{"TERM=dumb", "GIT_EDITOR=false"}},
+		{"missing editor", "readme\ne\nAdd a README\n.\n", "Add a README",
+			"Could not start the editor", []string{"TERM=xterm", "NO_COLOR=1", "GIT_EDITOR=gitcommit-no-such-editor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			writeFile(t, "README.md", "# Demo\n")
+			mustGit(t, "add", ".")
+
+			out := runGitcommit(t, "Add readme", tt.input, tt.env)
+			if !strings.Contains(out, tt.notice) {
+				t.Errorf("no %q notice:\n%s", tt.notice, out)
+			}
+			if strings.Contains(out, "\x1b") {
+				t.Errorf("escape sequences in the output:\n%q", out)
+			}
+			if got := mustGit(t, "log", "-1", "--format=%B"); got != tt.want {
+				t.Errorf("committed message = %q, want %q\noutput:\n%s", got, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestPlainWriter(t *testing.T) {
+	var b bytes.Buffer
+	plainWriter{&b}.Write([]byte("\x1b[1mAdd\x1b[0m a README\x1b"))
+	if got := b.String(); got != "Add a README" {
+		t.Errorf("plainWriter wrote %q, want %q", got, "Add a README")
+	}
+}
+
+// TestRunEditorStripsBOM saves the edit the way some Windows editors do,
+// with a UTF-8 byte order mark in front.
+func TestRunEditorStripsBOM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the editor is a shell script")
+	}
+	testRepo(t)
+	t.Setenv("TERM", "xterm")
+	tests := []struct{ name, saved, want string }{
+		{"bom", "\ufeffFix the parser\n", "Fix the parser\n"},
+		{"no bom", "Fix the parser\n", "Fix the parser\n"},
+		{"bom in the body", "\ufeffFix the parser\n\nKeep \ufeff here.\n", "Fix the parser\n\nKeep \ufeff here.\n"},
+		{"bom only", "\ufeff", ""},
+	}
+	for _, tt := range tests {
+		saved := filepath.Join(t.TempDir(), "saved")
+		if err := os.WriteFile(saved, []byte(tt.saved), 0644); err != nil {
+			t.Fatal(err)
+		}
+		editor := filepath.Join(t.TempDir(), "editor")
+		if err := os.WriteFile(editor, []byte("#!/bin/sh\ncp '"+saved+"' \"$1\"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("GIT_EDITOR", editor)
+		got, err := runEditor("Fix parser\n")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: runEditor = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// charsets are the non-UTF-8 commit encodings gitcommit can write, keyed by
+// normalized name. Each maps a rune to its byte, or reports false.
+var charsets = map[string]func(rune) (byte, bool){
+	"usascii":     asciiByte,
+	"ascii":       asciiByte,
+	"iso88591":    latin1Byte,
+	"latin1":      latin1Byte,
+	"iso885915":   latin9Byte,
+	"latin9":      latin9Byte,
+	"windows1252": cp1252Byte,
+	"cp1252":      cp1252Byte,
+}
+
+// charsetKey normalizes an encoding name as git and iconv accept it
+// (ISO-8859-1, iso8859-1, latin1).
+func charsetKey(name string) string {
+	return strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(name))
+}
+
+func isUTF8Charset(name string) bool {
+	return charsetKey(name) == "utf8"
+}
+
+func supportedCharset(name string) bool {
+	return isUTF8Charset(name) || charsets[charsetKey(name)] != nil
+}
+
+func asciiByte(r rune) (byte, bool) {
+	return byte(r), r < 0x80
+}
+
+func latin1Byte(r rune) (byte, bool) {
+	return byte(r), r < 0x100
+}
+
+// latin9Swaps are the ISO-8859-15 positions that differ from ISO-8859-1.
+var latin9Swaps = map[rune]byte{
+	'€': 0xA4, 'Š': 0xA6, 'š': 0xA8, 'Ž': 0xB4, 'ž': 0xB8, 'Œ': 0xBC, 'œ': 0xBD, 'Ÿ': 0xBE,
+}
+
+func latin9Byte(r rune) (byte, bool) {
+	if b, ok := latin9Swaps[r]; ok {
+		return b, true
+	}
+	switch r {
+	case 0xA4, 0xA6, 0xA8, 0xB4, 0xB8, 0xBC, 0xBD, 0xBE:
+		return 0, false
+	}
+	return latin1Byte(r)
+}
+
+// cp1252High are the Windows-1252 characters in 0x80-0x9F, where
+// ISO-8859-1 has control codes.
+var cp1252High = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+func cp1252Byte(r rune) (byte, bool) {
+	if b, ok := cp1252High[r]; ok {
+		return b, true
+	}
+	if r >= 0x80 && r < 0xA0 {
+		return 0, false
+	}
+	return latin1Byte(r)
+}
+
+// encodeMessage converts a UTF-8 message to charset. Characters the charset
+// lacks become "?"; they are returned as lost, along with the message as it
+// will read once committed.
+func encodeMessage(message, charset string) (encoded, committed string, lost []rune, err error) {
+	if isUTF8Charset(charset) {
+		return message, message, nil, nil
+	}
+	toByte := charsets[charsetKey(charset)]
+	if toByte == nil {
+		return "", "", nil, fmt.Errorf("can't encode messages as %s", charset)
+	}
+	var enc []byte
+	var out strings.Builder
+	seen := map[rune]bool{}
+	for _, r := range message {
+		if b, ok := toByte(r); ok && r != utf8.RuneError {
+			enc = append(enc, b)
+			out.WriteRune(r)
+			continue
+		}
+		enc = append(enc, '?')
+		out.WriteByte('?')
+		if !seen[r] {
+			seen[r] = true
+			lost = append(lost, r)
+		}
+	}
+	return string(enc), out.String(), lost, nil
+}
+
+// describeRunes lists characters for a warning, with their code points.
+func describeRunes(runes []rune) string {
+	parts := make([]string, len(runes))
+	for i, r := range runes {
+		parts[i] = fmt.Sprintf("%q (U+%04X)", r, r)
+	}
+	return strings.Join(parts, ", ")
+}
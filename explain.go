@@ -0,0 +1,61 @@
+package main
+
+import "fmt"
+
+const explainSystemPrompt = `You are reviewing a Git change before it is committed. Explain concisely what
+the change does, point out potential risks, and flag anything that looks
+unintentional, such as leftover debug code, commented-out code, or files that
+seem to have been added by accident. Use short plain-text paragraphs or bullet
+points. Do not write a commit message.`
+
+// explainChanges asks for an explanation of the change and prints it. It
+// returns the exchange so message generation can continue the conversation.
+func explainChanges(changes, apiKey string, opts apiOptions) ([]Message, error) {
+	prompt := "Explain this change before I commit it.\n\n" + changes
+	opts.System = explainSystemPrompt
+	if opts.Stream {
+		fmt.Println("\nExplanation:")
+	}
+	explanation, err := askClaude(prompt, apiKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Stream {
+		fmt.Printf("\nExplanation:\n%s\n", explanation)
+	}
+	return append(opts.History,
+		Message{Role: "user", Content: prompt},
+		Message{Role: "assistant", Content: explanation},
+	), nil
+}
+
+const critiqueSystemPrompt = `You are reviewing a commit message a developer wrote, to help them write
+better ones. Compare the message with the change and give specific feedback:
+is it too vague, does it miss the reason for the change, does its scope match
+what the change actually does (too narrow, too broad, or describing something
+else), is anything wrong or misleading, is the subject well formed. Quote the
+words you are commenting on. Use short plain-text bullet points, and end with
+a one-line verdict. If the message is good, say so and why. Do not write a
+replacement message.`
+
+// critiqueMessage asks for a review of the user's own message against the
+// change and prints it. Like explainChanges, it returns the exchange so an
+// improved message can be written with the critique in mind.
+func critiqueMessage(message, changes, apiKey string, opts apiOptions) ([]Message, error) {
+	prompt := fmt.Sprintf("Review the commit message I wrote for this change.\n\nMy message:\n```\n%s\n```\n\n%s", message, changes)
+	opts.System = critiqueSystemPrompt
+	if opts.Stream {
+		fmt.Println("\nCritique:")
+	}
+	critique, err := askClaude(prompt, apiKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Stream {
+		fmt.Printf("\nCritique:\n%s\n", critique)
+	}
+	return append(opts.History,
+		Message{Role: "user", Content: prompt},
+		Message{Role: "assistant", Content: critique},
+	), nil
+}
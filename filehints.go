@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// fileHintMatches reports whether a file_hints pattern applies to a path.
+// Patterns with glob characters (migrations/*.sql) are matched against the
+// whole path or, failing that, the base name; plain patterns (.sql,
+// _test.go, Dockerfile) match the end of the base name.
+func fileHintMatches(pattern, p string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		ok, _ := path.Match(pattern, path.Base(p))
+		return ok
+	}
+	return strings.HasSuffix(path.Base(p), pattern)
+}
+
+// fileHintsPrompt returns the configured hints for the kinds of files the
+// change touches, with the files each applies to.
+func fileHintsPrompt(hints map[string]string, paths []string) string {
+	patterns := make([]string, 0, len(hints))
+	for pattern := range hints {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	var lines []string
+	for _, pattern := range patterns {
+		var matched []string
+		for _, p := range paths {
+			if fileHintMatches(pattern, p) {
+				matched = append(matched, p)
+			}
+		}
+		if len(matched) == 0 || strings.TrimSpace(hints[pattern]) == "" {
+			continue
+		}
+		files := strings.Join(matched, ", ")
+		if len(matched) > 3 {
+			files = fmt.Sprintf("%s and %d more", strings.Join(matched[:3], ", "), len(matched)-3)
+		}
+		lines = append(lines, fmt.Sprintf("- %s (%s)", strings.TrimSpace(hints[pattern]), files))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Notes for the kinds of files changed:\n" + strings.Join(lines, "\n")
+}
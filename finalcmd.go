@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Shells -print-final-command can write for.
+const (
+	shellPOSIX      = "sh"
+	shellPowerShell = "powershell"
+)
+
+// shellSafeRe matches arguments that need no quoting in either shell.
+var shellSafeRe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// finalCommand returns the command that makes the commit with message, for
+// -print-final-command: git commit -F - with the message piped in, so no
+// character in it is ever interpreted as part of the command line.
+func finalCommand(message, shell, dir string, opts commitOptions) (string, error) {
+	args, env := opts.gitArgs()
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	switch shell {
+	case shellPOSIX:
+		return posixCommand(message, args, env), nil
+	case shellPowerShell:
+		return powerShellCommand(message, args, env), nil
+	}
+	return "", fmt.Errorf("unknown -shell %q (use %s or %s)", shell, shellPOSIX, shellPowerShell)
+}
+
+// posixCommand pipes the message from printf. Within single quotes a POSIX
+// shell takes everything literally (quotes, backticks, $, backslashes and
+// newlines) except a single quote, which shellQuote closes and escapes.
+func posixCommand(message string, args, env []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "printf '%%s\\n' %s |", shellQuote(message))
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		b.WriteString(" " + name + "=" + posixArg(value))
+	}
+	b.WriteString(" git")
+	for _, arg := range args {
+		b.WriteString(" " + posixArg(arg))
+	}
+	return b.String()
+}
+
+func posixArg(s string) string {
+	if shellSafeRe.MatchString(s) {
+		return s
+	}
+	return shellQuote(s)
+}
+
+// powerShellCommand pipes the message as a single-quoted string, in a script
+// block so the UTF-8 $OutputEncoding (Windows PowerShell defaults to ASCII
+// for native commands) doesn't outlast it. PowerShell adds a line break
+// after the piped string, which git's cleanup removes like printf's.
+func powerShellCommand(message string, args, env []string) string {
+	var b strings.Builder
+	b.WriteString("& {\n  $OutputEncoding = [System.Text.UTF8Encoding]::new($false)\n")
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&b, "  $env:%s = %s\n", name, powerShellQuote(value))
+	}
+	b.WriteString("  " + powerShellQuote(message) + " | git")
+	for _, arg := range args {
+		if shellSafeRe.MatchString(arg) && !strings.HasPrefix(arg, "@") {
+			b.WriteString(" " + arg)
+		} else {
+			b.WriteString(" " + powerShellQuote(arg))
+		}
+	}
+	b.WriteString("\n")
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&b, "  Remove-Item Env:%s\n", name)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// powerShellQuote quotes s for PowerShell. In a single-quoted string only
+// a single quote is special, and PowerShell counts the typographic ones
+// (‘ ’ ‚ ‛) as single quotes too; each is escaped by doubling it.
+func powerShellQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestPosixCommandQuoting runs the printed command through sh with a git
+// on PATH that records what it was given, and compares that with what
+// gitcommit would have run itself.
+func TestPosixCommandQuoting(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	bin, out := t.TempDir(), t.TempDir()
+	fakeGit := `#!/bin/sh
+for a; do printf '%s\0' "$a"; done > "$OUT/argv"
+printf '%s' "$GIT_COMMITTER_DATE" > "$OUT/date"
+cat > "$OUT/stdin"
+`
+	if err := os.WriteFile(filepath.Join(bin, "git"), []byte(fakeGit), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := map[string]string{
+		"plain":        "Fix the parser",
+		"quotes":       "Don't \"break\" it\n\nIt's 'fine' now.",
+		"dollar":       "Expand $HOME and ${PATH} and $(id) and $((1+1))",
+		"backticks":    "Run `rm -rf /` never\n\n```sh\nmake test\n```",
+		"backslashes":  `Escape \n and \\ and \' and \"`,
+		"newlines":     "Subject\n\n\nBody after two blank lines\n\n",
+		"non-ascii":    "Übersetze die Meldungen ✓\n\n修复解析器 — “quoted” ‘too’",
+		"printf":       "Format %s and %d and %% and \\n",
+		"shell syntax": "a | b; c && d > e < f & g * ? [x] ~ !! # not a comment",
+	}
+	date := "2024-03-01T12:00:00+02:00"
+	dirs := []string{"", "my repo", "it's $HOME/`x`"}
+	for name, message := range messages {
+		for _, dir := range dirs {
+			opts := commitOptions{All: true, NoVerify: true, Date: date}
+			command, err := finalCommand(message, shellPOSIX, dir, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd := exec.Command("sh", "-c", command)
+			cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"), "OUT="+out)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: sh -c %s: %v\n%s", name, command, err, output)
+			}
+
+			args, _ := opts.gitArgs()
+			if dir != "" {
+				args = append([]string{"-C", dir}, args...)
+			}
+			argv, _ := os.ReadFile(filepath.Join(out, "argv"))
+			if got := strings.Split(strings.TrimSuffix(string(argv), "\x00"), "\x00"); !reflect.DeepEqual(got, args) {
+				t.Errorf("%s in %q: git got %q, want %q", name, dir, got, args)
+			}
+			if got, _ := os.ReadFile(filepath.Join(out, "stdin")); string(got) != message+"\n" {
+				t.Errorf("%s in %q: message %q, want %q", name, dir, got, message+"\n")
+			}
+			if got, _ := os.ReadFile(filepath.Join(out, "date")); string(got) != date {
+				t.Errorf("%s in %q: GIT_COMMITTER_DATE %q, want %q", name, dir, got, date)
+			}
+		}
+	}
+}
+
+func TestFinalCommandUnknownShell(t *testing.T) {
+	if _, err := finalCommand("Fix it", "fish", "", commitOptions{}); err == nil {
+		t.Error("an unknown shell was accepted")
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// whitespaceArgs hide changes that only touch whitespace or blank lines.
+var whitespaceArgs = []string{"-w", "--ignore-blank-lines"}
+
+// getWhitespaceDiff returns the diff with whitespace-only changes hidden,
+// for the index (or all changes) or for a stash entry.
+func getWhitespaceDiff(all bool, stash string, exclude []string) (string, error) {
+	args := diffArgs(all, append(append([]string{}, whitespaceArgs...), exclude...)...)
+	if stash != "" {
+		args = append([]string{"stash", "show", "-p"}, append(whitespaceArgs, stash)...)
+	}
+	output, _, err := readCapped(gitCommand(args...), maxDiffReadBytes)
+	if err != nil {
+		return "", fmt.Errorf("error getting diff: %v", err)
+	}
+	return output, nil
+}
+
+func hasHunks(text string) bool {
+	return strings.HasPrefix(text, "@@") || strings.Contains(text, "\n@@")
+}
+
+// formattingOnlyFiles returns the files whose content changes in full all
+// disappear when whitespace is ignored. Files without content changes,
+// such as pure mode changes, are not counted.
+func formattingOnlyFiles(full, whitespace string) []string {
+	substantive := map[string]bool{}
+	for _, f := range splitDiff(whitespace) {
+		if hasHunks(f.Text) {
+			substantive[f.Path] = true
+		}
+	}
+	var files []string
+	for _, f := range splitDiff(full) {
+		if hasHunks(f.Text) && !substantive[f.Path] {
+			files = append(files, f.Path)
+		}
+	}
+	return files
+}
+
+// diffFileCount counts the files in a diff that have content changes.
+func diffFileCount(diff string) int {
+	n := 0
+	for _, f := range splitDiff(diff) {
+		if hasHunks(f.Text) {
+			n++
+		}
+	}
+	return n
+}
+
+// formatterFor names the usual formatter when every file is of one kind.
+func formatterFor(files []string) string {
+	formatters := map[string]string{
+		".go": "gofmt", ".rs": "rustfmt", ".py": "black",
+		".js": "prettier", ".jsx": "prettier", ".ts": "prettier", ".tsx": "prettier",
+		".css": "prettier", ".scss": "prettier", ".json": "prettier",
+		".c": "clang-format", ".h": "clang-format", ".cc": "clang-format", ".cpp": "clang-format",
+	}
+	var formatter string
+	for i, f := range files {
+		name := formatters[strings.ToLower(path.Ext(f))]
+		if name == "" || (i > 0 && name != formatter) {
+			return ""
+		}
+		formatter = name
+	}
+	return formatter
+}
+
+// formattingMessage describes a commit that only changes formatting.
+func formattingMessage(files []string) string {
+	target := fmt.Sprintf("%d files", len(files))
+	if len(files) == 1 {
+		target = files[0]
+	} else if dir := commonDir(files); dir != "" {
+		target = dir
+	}
+	subject := "Reformat " + target
+	if formatter := formatterFor(files); formatter != "" {
+		subject += " with " + formatter
+	}
+	return subject + "\n\nWhitespace and formatting changes only; no functional changes."
+}
+
+// commonDir returns the deepest directory containing every file, or "".
+func commonDir(files []string) string {
+	dir := path.Dir(files[0])
+	for _, f := range files[1:] {
+		for dir != "." && !strings.HasPrefix(f, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+func formattingPrompt(files []string) string {
+	return fmt.Sprintf("The diff above ignores whitespace. There are also formatting-only changes in %d file(s): %s", len(files), strings.Join(files, ", "))
+}
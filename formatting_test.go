@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreWhitespaceMixedDiff(t *testing.T) {
+	testRepo(t)
+	writeFile(t, "util.go", "package main\n\nfunc add(a, b int) int {\n  return a + b\n}\n")
+	writeFile(t, "retry.go", "package main\n\nfunc attempts() int {\n  return 3\n}\n")
+	mustGit(t, "add", ".")
+	mustGit(t, "commit", "-q", "-m", "Fixture")
+
+	// gofmt churn in both files, and one real change in retry.go.
+	reformatted := "package main\n\nfunc add(a, b int) int {\n\treturn a + b\n}\n"
+	writeFile(t, "util.go", reformatted)
+	writeFile(t, "retry.go", "package main\n\nfunc attempts() int {\n\n\treturn 5\n}\n")
+	mustGit(t, "add", ".")
+
+	full, _, err := getDiff(false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	whitespace, err := getWhitespaceDiff(false, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(whitespace, "+\treturn 5") || !strings.Contains(whitespace, "-  return 3") {
+		t.Errorf("the real change is missing from the prompt diff:\n%s", whitespace)
+	}
+	if strings.Contains(whitespace, "util.go") {
+		t.Errorf("the formatting-only file is in the prompt diff:\n%s", whitespace)
+	}
+	files := formattingOnlyFiles(full, whitespace)
+	if !reflect.DeepEqual(files, []string{"util.go"}) {
+		t.Errorf("formattingOnlyFiles = %q, want util.go", files)
+	}
+	if len(files) == diffFileCount(full) {
+		t.Error("a mixed diff was taken for formatting only")
+	}
+
+	// The commit itself keeps the formatting changes.
+	if _, err := gitCommit("Retry five times\n", commitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGit(t, "show", "HEAD:util.go") + "\n"; got != reformatted {
+		t.Errorf("committed util.go = %q, want %q", got, reformatted)
+	}
+}
+
+func TestFormattingOnly(t *testing.T) {
+	testRepo(t)
+	writeFile(t, "pkg/a.go", "package pkg\n\nvar A = 1\n")
+	writeFile(t, "pkg/b.go", "package pkg\n\nvar B = 2\n")
+	mustGit(t, "add", ".")
+	mustGit(t, "commit", "-q", "-m", "Fixture")
+	writeFile(t, "pkg/a.go", "package pkg\n\nvar A   = 1\n")
+	writeFile(t, "pkg/b.go", "package pkg\n\n\nvar B = 2\n")
+	mustGit(t, "add", ".")
+
+	full, _, err := getDiff(false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	whitespace, err := getWhitespaceDiff(false, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := formattingOnlyFiles(full, whitespace)
+	if len(files) != diffFileCount(full) {
+		t.Fatalf("formattingOnlyFiles = %q, want every changed file", files)
+	}
+	want := "Reformat pkg with gofmt\n\nWhitespace and formatting changes only; no functional changes."
+	if got := formattingMessage(files); got != want {
+		t.Errorf("formattingMessage = %q, want %q", got, want)
+	}
+}
+
+func TestFormattingMessage(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"main.go"}, "Reformat main.go with gofmt"},
+		{[]string{"web/a.ts", "web/lib/b.tsx"}, "Reformat web with prettier"},
+		{[]string{"a.go", "b.py"}, "Reformat 2 files"},
+		{[]string{"src/x.c", "include/x.h"}, "Reformat 2 files with clang-format"},
+	}
+	for _, tt := range tests {
+		if got, _, _ := strings.Cut(formattingMessage(tt.files), "\n"); got != tt.want {
+			t.Errorf("formattingMessage(%q) subject = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// readOnly is set by -read-only (or read_only in the user config). Every
+// git command that could change the repository is then refused by
+// gitCommand instead of run.
+var readOnly bool
+
+// errReadOnly is the error a refused command fails with; gitCommand has
+// already said what would have run.
+type errReadOnly struct {
+	args []string
+}
+
+func (e *errReadOnly) Error() string {
+	return "blocked by -read-only"
+}
+
+// gitCommand builds every git command gitcommit runs; nothing should call
+// exec.Command("git", ...) directly. In read-only mode a command that
+// isn't known to be read-only is reported and returned with Err set, so
+// it fails when started without running.
+func gitCommand(args ...string) *exec.Cmd {
+	return gitCommandEnv(nil, args...)
+}
+
+// gitCommandEnv is gitCommand with extra environment variables, such as
+// GIT_INDEX_FILE, set for the command. The environment is always explicit
+// (see gitEnv), so a command behaves the same whatever else the calling
+// shell exports.
+func gitCommandEnv(extra []string, args ...string) *exec.Cmd {
+	sub := gitSubcommand(args)
+	cmd := exec.Command("git", args...)
+	if d, ok := gitTimeouts[sub]; ok {
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		// Release the context once it has expired either way.
+		time.AfterFunc(d, cancel)
+		cmd = exec.CommandContext(ctx, "git", args...)
+		cmd.Cancel = func() error {
+			fmt.Fprintf(os.Stderr, "git %s took longer than %s; stopping it\n", sub, d)
+			return cmd.Process.Kill()
+		}
+	}
+	cmd.Env = gitEnv(sub, extra)
+	if readOnly && !readOnlyGitCommand(args) {
+		cmd.Err = &errReadOnly{args: args}
+		fmt.Printf("Read-only: would run: git %s\n", strings.Join(args, " "))
+	}
+	if debugGit {
+		logGitCommand(args, cmd.Env)
+	}
+	return cmd
+}
+
+// debugGit is set by -debug-git: every git command is logged to stderr
+// with how its environment differs from gitcommit's own.
+var debugGit bool
+
+// gitTimeouts bound commands that can hang on the network or a credential
+// prompt nobody answers.
+var gitTimeouts = map[string]time.Duration{
+	"push":      5 * time.Minute,
+	"fetch":     5 * time.Minute,
+	"ls-remote": time.Minute,
+}
+
+// hookCommands run hooks, credential helpers or ssh, which are the user's
+// own programs and expect the user's whole environment.
+var hookCommands = map[string]bool{
+	"commit": true, "hook": true, "push": true, "fetch": true,
+	"ls-remote": true, "am": true, "merge": true, "rebase": true,
+}
+
+// gitEnvNames and gitEnvPrefixes are what git itself needs from the
+// environment: where config and credentials live, the locale and time
+// zone, and the user's GIT_* settings.
+var (
+	gitEnvNames = map[string]bool{
+		"PATH": true, "HOME": true, "USER": true, "LOGNAME": true, "SHELL": true,
+		"TMPDIR": true, "TEMP": true, "TMP": true, "TERM": true, "LANG": true,
+		"LANGUAGE": true, "TZ": true, "EDITOR": true, "VISUAL": true,
+		"PAGER": true, "LESS": true, "DISPLAY": true, "WAYLAND_DISPLAY": true,
+		"PWD": true, "GNUPGHOME": true, "HTTP_PROXY": true,
+		"HTTPS_PROXY": true, "NO_PROXY": true, "ALL_PROXY": true,
+		"SSL_CERT_FILE": true, "SSL_CERT_DIR": true, "CURL_CA_BUNDLE": true,
+		// Windows.
+		"SYSTEMROOT": true, "WINDIR": true, "COMSPEC": true, "PATHEXT": true,
+		"USERPROFILE": true, "HOMEDRIVE": true, "HOMEPATH": true,
+		"APPDATA": true, "LOCALAPPDATA": true, "PROGRAMDATA": true,
+	}
+	gitEnvPrefixes = []string{"GIT_", "LC_", "XDG_", "SSH_", "GPG_"}
+)
+
+// gitEnv is the environment for a git command: the allowed variables from
+// gitcommit's own environment, or all of it for hookCommands, plus extra.
+// Anything else, such as the API key, stays out of git's reach.
+func gitEnv(sub string, extra []string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if hookCommands[sub] || allowedGitEnv(name) {
+			env = append(env, kv)
+		}
+	}
+	return append(env, extra...)
+}
+
+func allowedGitEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	if gitEnvNames[upper] {
+		return true
+	}
+	for _, p := range gitEnvPrefixes {
+		if strings.HasPrefix(upper, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitSubcommand returns the git command in args, after global options
+// such as -c key=value.
+func gitSubcommand(args []string) string {
+	for len(args) > 1 && (args[0] == "-c" || args[0] == "-C") {
+		args = args[2:]
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// logGitCommand prints a command and the variables it sets or drops
+// relative to gitcommit's environment.
+func logGitCommand(args, env []string) {
+	own := map[string]string{}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		own[name] = value
+	}
+	var set []string
+	kept := map[string]bool{}
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		kept[name] = true
+		if v, ok := own[name]; !ok || v != value {
+			set = append(set, kv)
+		}
+	}
+	var dropped []string
+	for name := range own {
+		if !kept[name] {
+			dropped = append(dropped, name)
+		}
+	}
+	sort.Strings(dropped)
+	fmt.Fprintf(os.Stderr, "git: git %s\n", strings.Join(args, " "))
+	if len(set) > 0 {
+		fmt.Fprintf(os.Stderr, "     env set: %s\n", strings.Join(set, " "))
+	}
+	if len(dropped) > 0 {
+		fmt.Fprintf(os.Stderr, "     env dropped: %s\n", strings.Join(dropped, " "))
+	}
+}
+
+// readGitCommands are the git commands that never change refs, the index,
+// the work tree or config. write-tree, commit-tree and stash create only
+// add unreferenced objects, which is what gives a dry run its commit ids.
+var readGitCommands = map[string]bool{
+	"rev-parse": true, "rev-list": true, "log": true, "show": true,
+	"diff": true, "diff-tree": true, "status": true, "cat-file": true,
+	"for-each-ref": true, "ls-files": true, "merge-base": true,
+	"stripspace": true, "write-tree": true, "commit-tree": true,
+}
+
+// readOnlyGitCommand reports whether a git command is safe to run in
+// read-only mode. Commands that read or write depending on their options
+// are allowed only in their reading forms.
+func readOnlyGitCommand(args []string) bool {
+	// Skip global options such as -c key=value.
+	for len(args) > 1 && (args[0] == "-c" || args[0] == "-C") {
+		args = args[2:]
+	}
+	if len(args) == 0 {
+		return false
+	}
+	sub, rest := args[0], args[1:]
+	if readGitCommands[sub] {
+		return true
+	}
+	positional := 0
+	for _, a := range rest {
+		if !strings.HasPrefix(a, "-") {
+			positional++
+		}
+	}
+	switch sub {
+	case "config":
+		for _, a := range rest {
+			switch a {
+			case "--add", "--unset", "--unset-all", "--replace-all", "--rename-section", "--remove-section", "-e", "--edit":
+				return false
+			}
+		}
+		return positional <= 1 || hasArg(rest, "--get-regexp") || hasArg(rest, "--get")
+	case "notes":
+		// The subcommand follows any --ref, and a note's text could be
+		// "show". Without one, notes lists.
+		if len(rest) > 1 && rest[0] == "--ref" {
+			rest = rest[2:]
+		} else if len(rest) > 0 && strings.HasPrefix(rest[0], "--ref=") {
+			rest = rest[1:]
+		}
+		return len(rest) == 0 || rest[0] == "show" || rest[0] == "list"
+	case "stash":
+		return len(rest) > 0 && (rest[0] == "show" || rest[0] == "list" || rest[0] == "create")
+	case "symbolic-ref":
+		return positional <= 1
+	case "hash-object":
+		return !hasArg(rest, "-w")
+	case "format-patch":
+		return hasArg(rest, "--stdout")
+	case "commit":
+		return hasArg(rest, "--dry-run")
+	}
+	return false
+}
+
+// readOnlyConflict names the option, if any, that would run something
+// other than git that can change the repository or publish it: the
+// pre-commit hook run directly by -early-hooks, the -run-before and
+// -context-cmd commands, or gh pushing and opening a pull request for -pr.
+// gitCommand can't refuse those, so -read-only refuses the option instead.
+func readOnlyConflict(earlyHooks, openPR bool, runBefore, contextCmd string) string {
+	switch {
+	case earlyHooks:
+		return "-early-hooks"
+	case runBefore != "":
+		return "-run-before"
+	case contextCmd != "":
+		return "-context-cmd"
+	case openPR:
+		return "-pr"
+	}
+	return ""
+}
+
+func hasArg(args []string, want string) bool {
+	for _, a := range args {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyGitCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"rev-parse", "HEAD"}, true},
+		{[]string{"-c", "core.quotepath=off", "diff", "--cached"}, true},
+		{[]string{"-C", "sub", "log", "-1"}, true},
+		{[]string{"write-tree"}, true},
+		{[]string{"config", "user.name"}, true},
+		{[]string{"config", "--get-regexp", "^branch\\."}, true},
+		{[]string{"config", "user.name", "someone"}, false},
+		{[]string{"config", "--unset", "user.name"}, false},
+		{[]string{"stash", "list"}, true},
+		{[]string{"stash", "create"}, true},
+		{[]string{"stash", "push"}, false},
+		{[]string{"stash"}, false},
+		{[]string{"notes", "show", "HEAD"}, true},
+		{[]string{"notes", "add", "-m", "x"}, false},
+		{[]string{"notes", "add", "-m", "show"}, false},
+		{[]string{"notes", "append", "-m", "list", "HEAD"}, false},
+		{[]string{"notes", "--ref", "gitcommit", "show", "HEAD"}, true},
+		{[]string{"notes", "--ref=gitcommit", "list"}, true},
+		{[]string{"notes", "--ref", "show", "add", "-m", "x"}, false},
+		{[]string{"notes"}, true},
+		{[]string{"hash-object", "f"}, true},
+		{[]string{"hash-object", "-w", "f"}, false},
+		{[]string{"format-patch", "--stdout", "HEAD~1"}, true},
+		{[]string{"format-patch", "HEAD~1"}, false},
+		{[]string{"commit", "--dry-run", "--short"}, true},
+		{[]string{"commit", "-F", "-"}, false},
+		{[]string{"-c", "core.hooksPath=/tmp/x", "commit", "-F", "-"}, false},
+		{[]string{"symbolic-ref", "HEAD"}, true},
+		{[]string{"symbolic-ref", "HEAD", "refs/heads/x"}, false},
+		{[]string{"add", "-A"}, false},
+		{[]string{"reset", "-q"}, false},
+		{[]string{"update-ref", "refs/x", "HEAD"}, false},
+		{[]string{"push"}, false},
+		{[]string{"hook", "run", "pre-commit"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := readOnlyGitCommand(tt.args); got != tt.want {
+			t.Errorf("readOnlyGitCommand(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestGitCommandReadOnly(t *testing.T) {
+	defer func(old bool) { readOnly = old }(readOnly)
+	readOnly = true
+
+	cmd := gitCommand("commit", "-F", "-")
+	var refused *errReadOnly
+	if !errors.As(cmd.Err, &refused) {
+		t.Fatalf("commit in read-only mode: Err = %v, want errReadOnly", cmd.Err)
+	}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("running a refused command: err = %v, want it blocked", err)
+	}
+	if cmd := gitCommand("rev-parse", "--git-dir"); cmd.Err != nil {
+		t.Errorf("rev-parse in read-only mode: Err = %v, want nil", cmd.Err)
+	}
+
+	readOnly = false
+	if cmd := gitCommand("commit", "-F", "-"); cmd.Err != nil {
+		t.Errorf("commit outside read-only mode: Err = %v, want nil", cmd.Err)
+	}
+}
+
+func TestReadOnlyConflict(t *testing.T) {
+	tests := []struct {
+		earlyHooks, openPR    bool
+		runBefore, contextCmd string
+		want                  string
+	}{
+		{false, false, "", "", ""},
+		{true, false, "", "", "-early-hooks"},
+		{false, true, "", "", "-pr"},
+		{false, false, "go test ./...", "", "-run-before"},
+		{false, false, "", "git log -5", "-context-cmd"},
+		{false, true, "", "./ticket.sh", "-context-cmd"},
+		{true, true, "make", "", "-early-hooks"},
+	}
+	for _, tt := range tests {
+		if got := readOnlyConflict(tt.earlyHooks, tt.openPR, tt.runBefore, tt.contextCmd); got != tt.want {
+			t.Errorf("readOnlyConflict(%v, %v, %q, %q) = %q, want %q", tt.earlyHooks, tt.openPR, tt.runBefore, tt.contextCmd, got, tt.want)
+		}
+	}
+}
+
+// testRepo creates a repository with an identity in a temporary directory
+// and changes into it for the rest of the test. The user's own git config
+// is kept out of it. Tests that change git config after this call
+// resetGitConfigCache before reading it.
+func testRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_DATE", "")
+	t.Setenv("GIT_COMMITTER_DATE", "")
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	resetHeadCache()
+	resetGitConfigCache()
+	t.Cleanup(func() {
+		os.Chdir(old)
+		resetHeadCache()
+		resetGitConfigCache()
+	})
+	mustGit(t, "init", "-q")
+	mustGit(t, "config", "user.name", "Test")
+	mustGit(t, "config", "user.email", "test@example.com")
+	mustGit(t, "config", "commit.gpgsign", "false")
+	return dir
+}
+
+// mustGit runs git in the current directory and returns its trimmed output.
+func mustGit(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// writeFile writes a file relative to the current directory, creating its
+// parent directories.
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commitFile writes and commits a file, returning the commit's short hash.
+func commitFile(t *testing.T, name, content, subject string) string {
+	t.Helper()
+	writeFile(t, name, content)
+	mustGit(t, "add", name)
+	mustGit(t, "commit", "-q", "-m", subject)
+	return mustGit(t, "rev-parse", "--short", "HEAD")
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gitConfigValue is one setting from `git config --list` and the file (or
+// other origin) that set it.
+type gitConfigValue struct {
+	Value  string
+	Origin string
+}
+
+var (
+	gitConfigOnce  sync.Once
+	gitConfigCache map[string]gitConfigValue
+)
+
+// loadGitConfig reads every git setting in one call and caches the result.
+// The last value of a key wins, as in git. Errors leave the cache empty, so
+// everything falls back to its default.
+func loadGitConfig() map[string]gitConfigValue {
+	gitConfigOnce.Do(func() {
+		gitConfigCache = map[string]gitConfigValue{}
+		output, err := gitCommand("config", "--list", "--null", "--show-origin").Output()
+		if err != nil {
+			return
+		}
+		gitConfigCache = parseGitConfig(string(output))
+	})
+	return gitConfigCache
+}
+
+// parseGitConfig parses `git config --list --null --show-origin` output:
+// origin NUL key LF value NUL, repeated. A key without a value (implicit
+// true) has no LF.
+func parseGitConfig(output string) map[string]gitConfigValue {
+	values := map[string]gitConfigValue{}
+	fields := strings.Split(output, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, value, ok := strings.Cut(fields[i+1], "\n")
+		if !ok {
+			value = "true"
+		}
+		values[strings.ToLower(key)] = gitConfigValue{Value: value, Origin: fields[i]}
+	}
+	return values
+}
+
+// resetGitConfigCache forgets the cached settings, for a process that moves
+// between repositories.
+func resetGitConfigCache() {
+	gitConfigOnce = sync.Once{}
+	gitConfigCache = nil
+}
+
+// parseGitBool interprets a git boolean. commit.verbose also accepts a
+// verbosity level, which counts as true when above zero.
+func parseGitBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on":
+		return true, true
+	case "false", "no", "off", "":
+		return false, true
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return n > 0, true
+	}
+	return false, false
+}
+
+// gitSetting is the effective value of a setting gitcommit follows and
+// where that value came from.
+type gitSetting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// cleanupModes are the values git accepts for commit.cleanup and --cleanup.
+var cleanupModes = []string{"default", "strip", "whitespace", "verbatim", "scissors"}
+
+// gitSettings are the git preferences that shape the editor buffer and the
+// commit. Flags override them for one run.
+type gitSettings struct {
+	ShowDiff     bool   // commit.verbose: the diff below a scissors line
+	ShowStatus   bool   // commit.status: git status as comments
+	Cleanup      string // commit.cleanup
+	CommentChar  string // core.commentChar
+	Encoding     string // i18n.commitEncoding
+	Sign         *bool  // commit.gpgsign; nil leaves it to git
+	CleanupFlag  bool   // Cleanup came from -cleanup and must be passed on
+	EncodingFlag bool   // Encoding came from -encoding and must be passed on
+
+	// Diff is what commit.verbose shows; it is the diff being committed.
+	Diff string
+
+	list []gitSetting
+}
+
+// settingFlags are the flags given on the command line, and their values,
+// that override git settings.
+type settingFlags map[string]string
+
+// resolveGitSettings combines git config with any overriding flags.
+// Unset keys keep gitcommit's own defaults, which for commit.status is off
+// so the buffer stays as it was unless the user asked for more.
+func resolveGitSettings(flags settingFlags) (*gitSettings, error) {
+	config := loadGitConfig()
+	s := &gitSettings{}
+	lookup := func(key, flagName, def string) string {
+		if v, ok := flags[flagName]; ok {
+			s.list = append(s.list, gitSetting{key, v, "-" + flagName + " flag"})
+			return v
+		}
+		if v, ok := config[strings.ToLower(key)]; ok {
+			s.list = append(s.list, gitSetting{key, v.Value, v.Origin})
+			return v.Value
+		}
+		s.list = append(s.list, gitSetting{key, def, "default"})
+		return def
+	}
+	boolean := func(key, flagName string) (bool, error) {
+		v := lookup(key, flagName, "false")
+		b, ok := parseGitBool(v)
+		if !ok {
+			return false, fmt.Errorf("bad boolean value %q for %s", v, key)
+		}
+		return b, nil
+	}
+
+	var err error
+	if s.ShowDiff, err = boolean("commit.verbose", "show-diff"); err != nil {
+		return nil, err
+	}
+	if s.ShowStatus, err = boolean("commit.status", "show-status"); err != nil {
+		return nil, err
+	}
+	_, s.CleanupFlag = flags["cleanup"]
+	s.Cleanup = lookup("commit.cleanup", "cleanup", "default")
+	if !validCleanup(s.Cleanup) {
+		return nil, fmt.Errorf("unknown cleanup mode %q (use %s)", s.Cleanup, strings.Join(cleanupModes, ", "))
+	}
+	s.CommentChar = lookup("core.commentChar", "", "#")
+	if s.CommentChar == "auto" || s.CommentChar == "" {
+		// git picks a character unused in the message; the buffers gitcommit
+		// writes are known in advance not to need one, so # is safe.
+		s.CommentChar = "#"
+	}
+	_, s.EncodingFlag = flags["encoding"]
+	s.Encoding = lookup("i18n.commitEncoding", "encoding", "UTF-8")
+	if !supportedCharset(s.Encoding) {
+		return nil, fmt.Errorf("gitcommit can't write commit messages in %s (use UTF-8, US-ASCII, ISO-8859-1, ISO-8859-15 or Windows-1252)", s.Encoding)
+	}
+	_, signFlag := flags["gpg-sign"]
+	sign, err := boolean("commit.gpgsign", "gpg-sign")
+	if err != nil {
+		return nil, err
+	}
+	if signFlag {
+		s.Sign = &sign
+	}
+	return s, nil
+}
+
+func validCleanup(mode string) bool {
+	for _, m := range cleanupModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// print shows each effective setting and its source.
+func (s *gitSettings) print() {
+	fmt.Println("Git settings:")
+	for _, setting := range s.list {
+		fmt.Printf("  %s = %s (%s)\n", setting.Key, setting.Value, setting.Source)
+	}
+}
+
+// utf8Encoding reports whether the commit encoding is UTF-8, the encoding
+// messages are written in until they are committed.
+func (s *gitSettings) utf8Encoding() bool {
+	return isUTF8Charset(s.Encoding)
+}
+
+// scissorsLine is the line below which git ignores the rest of the buffer.
+func (s *gitSettings) scissorsLine() string {
+	return s.CommentChar + " ------------------------ >8 ------------------------"
+}
+
+// wrap adds what commit.status and commit.verbose ask for to a message
+// opened in the editor. A nil s leaves the message alone.
+func (s *gitSettings) wrap(message string) string {
+	if s == nil || (!s.ShowStatus && !s.ShowDiff) {
+		return message
+	}
+	var b strings.Builder
+	b.WriteString(message)
+	if !strings.HasSuffix(message, "\n") {
+		b.WriteString("\n")
+	}
+	if s.ShowStatus {
+		if status, err := gitCommand("status").Output(); err == nil {
+			b.WriteString(s.CommentChar + "\n")
+			for _, line := range strings.Split(strings.TrimRight(string(status), "\n"), "\n") {
+				b.WriteString(strings.TrimRight(s.CommentChar+" "+line, " ") + "\n")
+			}
+		}
+	}
+	if s.ShowDiff {
+		b.WriteString(s.scissorsLine() + "\n")
+		b.WriteString(s.CommentChar + " Do not modify or remove the line above.\n")
+		b.WriteString(s.CommentChar + " Everything below it will be ignored.\n")
+		b.WriteString(s.Diff)
+	}
+	return b.String()
+}
+
+// unwrap removes what wrap added: everything from the scissors line down
+// and, with the status shown, comment lines, as git's strip cleanup would.
+func (s *gitSettings) unwrap(edited string) string {
+	if s == nil || (!s.ShowStatus && !s.ShowDiff) {
+		return edited
+	}
+	lines := strings.Split(edited, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if s.ShowDiff && line == s.scissorsLine() {
+			break
+		}
+		if s.ShowStatus && (line == s.CommentChar || strings.HasPrefix(line, s.CommentChar+" ")) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimRight(strings.Join(kept, "\n"), "\n") + "\n"
+}
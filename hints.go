@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// minHintCount is how many times a type/scope must have been used under a
+// prefix before it is offered to the model as a hint.
+const minHintCount = 2
+
+// maxHints caps how many path hints go into the prompt.
+const maxHints = 3
+
+var conventionalRe = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]+)\))?!?: `)
+
+// parseConventional extracts the type and scope from a conventional commit
+// subject. ok is false if the subject is not in conventional form.
+func parseConventional(subject string) (typ, scope string, ok bool) {
+	m := conventionalRe.FindStringSubmatch(subject)
+	if m == nil {
+		return "", "", false
+	}
+	return strings.ToLower(m[1]), m[2], true
+}
+
+// conventionalCheck requires a conventional subject, after the
+// -subject-prefix when that goes before the type.
+func conventionalCheck(prefix string) messageCheck {
+	return func(message string) []string {
+		subject, _ := splitMessage(message)
+		if _, _, ok := parseConventional(strings.TrimPrefix(subject, prefix)); !ok {
+			return []string{`the subject is not a conventional commit subject, such as "fix(parser): handle empty input"`}
+		}
+		return nil
+	}
+}
+
+func hintKey(typ, scope string) string {
+	if scope == "" {
+		return typ
+	}
+	return typ + "(" + scope + ")"
+}
+
+func dirPrefix(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir + "/"
+}
+
+// recordHints learns the type/scope of a committed message for every
+// directory touched by the commit.
+func recordHints(state *repoState, message string, paths []string) bool {
+	subject, _, _ := strings.Cut(message, "\n")
+	typ, scope, ok := parseConventional(subject)
+	if !ok {
+		return false
+	}
+	if state.Hints == nil {
+		state.Hints = make(map[string]map[string]int)
+	}
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		prefix := dirPrefix(p)
+		if prefix == "" || seen[prefix] {
+			continue
+		}
+		seen[prefix] = true
+		if state.Hints[prefix] == nil {
+			state.Hints[prefix] = make(map[string]int)
+		}
+		state.Hints[prefix][hintKey(typ, scope)]++
+	}
+	return len(seen) > 0
+}
+
+// topHint returns the most used type/scope for a prefix.
+func topHint(counts map[string]int) (string, int) {
+	var best string
+	var bestCount int
+	for key, n := range counts {
+		if n > bestCount || (n == bestCount && key < best) {
+			best, bestCount = key, n
+		}
+	}
+	return best, bestCount
+}
+
+// matchHints finds, for each changed file, the nearest recorded prefix and
+// returns the strongest prefixes first.
+func matchHints(state *repoState, paths []string) []string {
+	counts := make(map[string]int)
+	for _, p := range paths {
+		for dir := dirPrefix(p); dir != ""; dir = dirPrefix(strings.TrimSuffix(dir, "/")) {
+			if _, ok := state.Hints[dir]; ok {
+				_, n := topHint(state.Hints[dir])
+				if n >= minHintCount {
+					counts[dir] = n
+				}
+				break
+			}
+		}
+	}
+	prefixes := make([]string, 0, len(counts))
+	for dir := range counts {
+		prefixes = append(prefixes, dir)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if counts[prefixes[i]] != counts[prefixes[j]] {
+			return counts[prefixes[i]] > counts[prefixes[j]]
+		}
+		return prefixes[i] < prefixes[j]
+	})
+	if len(prefixes) > maxHints {
+		prefixes = prefixes[:maxHints]
+	}
+	return prefixes
+}
+
+// hintsPrompt formats learned path hints for the prompt.
+func hintsPrompt(state *repoState, paths []string) string {
+	prefixes := matchHints(state, paths)
+	if len(prefixes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("In this repository, conventional commit types and scopes have been used consistently per area. Follow these unless the change clearly calls for something else:\n")
+	for _, dir := range prefixes {
+		key, n := topHint(state.Hints[dir])
+		fmt.Fprintf(&b, "- changes under %s are usually %s (%d commits)\n", dir, key, n)
+	}
+	return b.String()
+}
+
+// learnFromCommit records the final message's type/scope, ignoring errors
+// since learning is best-effort and must never affect the commit.
+func learnFromCommit(message string, paths []string) {
+	state, err := loadRepoState()
+	if err != nil {
+		return
+	}
+	if recordHints(state, message, paths) {
+		saveRepoState(state)
+	}
+}
+
+// runHints implements `gitcommit hints list|clear`.
+func runHints(args []string) error {
+	if len(args) != 1 || (args[0] != "list" && args[0] != "clear") {
+		return fmt.Errorf("usage: gitcommit hints list|clear")
+	}
+	state, err := loadRepoState()
+	if err != nil {
+		return err
+	}
+	if args[0] == "clear" {
+		state.Hints = nil
+		if err := saveRepoState(state); err != nil {
+			return err
+		}
+		fmt.Println("Cleared learned hints.")
+		return nil
+	}
+
+	if len(state.Hints) == 0 {
+		fmt.Println("No learned hints yet.")
+		return nil
+	}
+	prefixes := make([]string, 0, len(state.Hints))
+	for dir := range state.Hints {
+		prefixes = append(prefixes, dir)
+	}
+	sort.Strings(prefixes)
+	for _, dir := range prefixes {
+		keys := make([]string, 0, len(state.Hints[dir]))
+		for key := range state.Hints[dir] {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return state.Hints[dir][keys[i]] > state.Hints[dir][keys[j]]
+		})
+		parts := make([]string, 0, len(keys))
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%s x%d", key, state.Hints[dir][key]))
+		}
+		fmt.Printf("%s: %s\n", dir, strings.Join(parts, ", "))
+	}
+	return nil
+}
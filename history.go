@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxHistoryFiles caps how many changed files we query history for.
+	maxHistoryFiles = 30
+	// maxHistoryCommits caps how many distinct commits go into the prompt.
+	maxHistoryCommits = 15
+)
+
+// historyCommit is a recent commit together with the changed files it touched.
+type historyCommit struct {
+	Line  string // "<short hash> <subject>"
+	Files []string
+}
+
+// getFileHistory returns the last couple of commits touching each path,
+// deduplicated so a commit shared by several files is listed once.
+func getFileHistory(paths []string) []historyCommit {
+	if len(paths) > maxHistoryFiles {
+		paths = paths[:maxHistoryFiles]
+	}
+
+	var commits []historyCommit
+	index := make(map[string]int)
+	for _, p := range paths {
+		// Paths are file names, not patterns: "[ab].go" is one file.
+		cmd := gitCommandEnv([]string{"GIT_LITERAL_PATHSPECS=1"}, "log", "-2", "--oneline", "--", p)
+		output, err := cmd.Output()
+		if err != nil {
+			// No history yet (e.g. initial commit) is not an error worth reporting.
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if line == "" {
+				continue
+			}
+			if i, ok := index[line]; ok {
+				commits[i].Files = append(commits[i].Files, p)
+				continue
+			}
+			if len(commits) >= maxHistoryCommits {
+				continue
+			}
+			index[line] = len(commits)
+			commits = append(commits, historyCommit{Line: line, Files: []string{p}})
+		}
+	}
+	return commits
+}
+
+// historyPrompt formats recent per-file history for inclusion in the prompt.
+func historyPrompt(commits []historyCommit) string {
+	if len(commits) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Recent commits touching the changed files. If this change is genuinely a\n")
+	b.WriteString("follow-up to one of them, you may reference it by short hash; otherwise ignore them.\n")
+	for _, c := range commits {
+		fmt.Fprintf(&b, "%s (%s)\n", c.Line, strings.Join(c.Files, ", "))
+	}
+	return b.String()
+}
+
+// maxRelatedCommits caps -related-history.
+const maxRelatedCommits = 20
+
+// getRelatedHistory returns up to n of the latest commits that touched any
+// of the paths, newest first, as "<short hash> <subject>" lines. Unlike the
+// per-file history, this follows the ongoing work across the whole set.
+func getRelatedHistory(paths []string, n int) []string {
+	if n > maxRelatedCommits {
+		n = maxRelatedCommits
+	}
+	if n <= 0 || len(paths) == 0 {
+		return nil
+	}
+	args := append([]string{"log", fmt.Sprintf("-n%d", n), "--no-merges", "--format=%h %s", "--"}, paths...)
+	output, err := gitCommandEnv([]string{"GIT_LITERAL_PATHSPECS=1"}, args...).Output()
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// relatedHistoryPrompt formats -related-history, leaving out commits the
+// per-file history already lists.
+func relatedHistoryPrompt(lines []string, listed []historyCommit) string {
+	seen := map[string]bool{}
+	for _, c := range listed {
+		seen[c.Line] = true
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		if !seen[line] {
+			b.WriteString(line + "\n")
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "Recent work on the changed files, newest first, to show where this change fits:\n" + b.String()
+}
+
+// maxBranchCommits caps how many earlier commits on the branch are listed.
+const maxBranchCommits = 20
+
+// defaultSimilarSubject is how alike (0-1) a subject may be to an earlier
+// one on the branch before -similar-subject warns.
+const defaultSimilarSubject = 0.8
+
+// branchCommits returns the commits on the current branch since it forked
+// from its upstream (or origin's default branch), newest first, as
+// "<short hash> <subject>" lines. Without either there are none.
+func branchCommits() []string {
+	base := "@{upstream}"
+	if _, err := gitRun("rev-parse", "--verify", "--quiet", base); err != nil {
+		base = "origin/HEAD"
+		if _, err := gitRun("rev-parse", "--verify", "--quiet", base); err != nil {
+			return nil
+		}
+	}
+	output, err := gitCommand("log", fmt.Sprintf("-n%d", maxBranchCommits), "--no-merges", "--format=%h %s", base+"..HEAD").Output()
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// branchPrompt asks for a subject that sets this commit apart from the
+// earlier ones in a stack.
+func branchPrompt(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Earlier commits on this branch, newest first. This commit is stacked on them: make its subject clearly different from theirs by saying what this commit adds, not what the branch as a whole does.\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// subjectBigrams returns the letter pairs of a subject's words, after
+// lowercasing and dropping any conventional type(scope): prefix, so
+// "feat(api): Add retries" and "Add retry" are compared on what they say.
+func subjectBigrams(subject string) map[string]int {
+	if _, _, ok := parseConventional(subject); ok {
+		_, subject, _ = strings.Cut(subject, ":")
+	}
+	words := strings.FieldsFunc(strings.ToLower(subject), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	bigrams := map[string]int{}
+	for _, w := range words {
+		rs := []rune(w)
+		if len(rs) == 1 {
+			bigrams[w]++
+		}
+		for i := 0; i+1 < len(rs); i++ {
+			bigrams[string(rs[i:i+2])]++
+		}
+	}
+	return bigrams
+}
+
+// subjectSimilarity compares two subjects with the Sørensen–Dice
+// coefficient over letter pairs: 1 for the same words, near 0 for
+// unrelated ones. It tolerates small edits such as "error"/"errors".
+func subjectSimilarity(a, b string) float64 {
+	x, y := subjectBigrams(a), subjectBigrams(b)
+	total, shared := 0, 0
+	for k, n := range x {
+		total += n
+		shared += min(n, y[k])
+	}
+	for _, n := range y {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// similarBranchSubject returns the earlier commit on the branch whose
+// subject is most like subject, if it is at least threshold alike.
+func similarBranchSubject(subject string, lines []string, threshold float64) (string, float64, bool) {
+	best, bestScore := "", 0.0
+	for _, line := range lines {
+		_, other, _ := strings.Cut(line, " ")
+		if score := subjectSimilarity(subject, other); score > bestScore {
+			best, bestScore = line, score
+		}
+	}
+	return best, bestScore, threshold > 0 && bestScore >= threshold
+}
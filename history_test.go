@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHistoryPrompt(t *testing.T) {
+	testRepo(t)
+	first := commitFile(t, "retry.go", "package retry\n", "Add retry package")
+	second := commitFile(t, "retry.go", "package retry\n\nconst attempts = 3\n", "Retry three times")
+	commitFile(t, "README", "readme\n", "Add README")
+	shared := commitFile(t, "retry.go", "package retry\n\nconst attempts = 5\n", "Retry five times")
+	readme := commitFile(t, "README", "readme\nmore\n", "Extend README")
+
+	writeFile(t, "retry.go", "package retry\n\nconst attempts = 7\n")
+	writeFile(t, "README", "readme\nmore\nstill more\n")
+	mustGit(t, "add", "retry.go", "README")
+
+	snap, err := gatherSnapshot(false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt := historyPrompt(snap.History)
+	for _, want := range []string{
+		shared + " Retry five times (retry.go)",
+		second + " Retry three times (retry.go)",
+		readme + " Extend README (README)",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt is missing %q:\n%s", want, prompt)
+		}
+	}
+	// Only the last two commits per file are listed.
+	if strings.Contains(prompt, first) {
+		t.Errorf("prompt lists %s, older than the last two commits to retry.go:\n%s", first, prompt)
+	}
+
+	if snap, err = gatherSnapshot(true, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.History) != 0 {
+		t.Errorf("history disabled: got %v", snap.History)
+	}
+}
+
+func TestGetFileHistoryDedupes(t *testing.T) {
+	testRepo(t)
+	writeFile(t, "a.go", "package a\n")
+	writeFile(t, "b.go", "package a\n")
+	mustGit(t, "add", ".")
+	mustGit(t, "commit", "-q", "-m", "Add a and b")
+	both := mustGit(t, "rev-parse", "--short", "HEAD")
+
+	commits := getFileHistory([]string{"a.go", "b.go"})
+	if len(commits) != 1 {
+		t.Fatalf("got %d commits, want the shared one once: %v", len(commits), commits)
+	}
+	if want := both + " Add a and b"; commits[0].Line != want {
+		t.Errorf("Line = %q, want %q", commits[0].Line, want)
+	}
+	if got := strings.Join(commits[0].Files, ","); got != "a.go,b.go" {
+		t.Errorf("Files = %s, want a.go,b.go", got)
+	}
+}
+
+func TestGetFileHistoryLiteralPaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("* is not allowed in Windows file names")
+	}
+	testRepo(t)
+	commitFile(t, "a.go", "package a\n", "Add a")
+	commitFile(t, "b.go", "package a\n", "Add b")
+	glob := commitFile(t, "[ab].go", "package a\n", "Add [ab].go")
+	star := commitFile(t, "*.go", "package a\n", "Add *.go")
+
+	for path, want := range map[string]string{"[ab].go": glob + " Add [ab].go", "*.go": star + " Add *.go"} {
+		commits := getFileHistory([]string{path})
+		if len(commits) != 1 || commits[0].Line != want {
+			t.Errorf("history of %s = %v, want only %q", path, commits, want)
+		}
+	}
+}
+
+// stack makes a feature branch tracking main with one commit per subject,
+// oldest first, and returns the branchCommits lines.
+func stack(t *testing.T, subjects ...string) []string {
+	t.Helper()
+	testRepo(t)
+	commitFile(t, "README.md", "# Demo\n", "Initial commit")
+	mustGit(t, "branch", "-M", "main")
+	mustGit(t, "checkout", "-q", "-b", "feature")
+	mustGit(t, "branch", "-q", "--set-upstream-to", "main")
+	for i, s := range subjects {
+		commitFile(t, fmt.Sprintf("file%d.go", i), "package demo\n", s)
+	}
+	return branchCommits()
+}
+
+func TestBranchCommits(t *testing.T) {
+	lines := stack(t, "Add retry options to the HTTP client", "Retry idempotent requests in the fetcher")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " Retry idempotent requests in the fetcher") || !strings.HasSuffix(lines[1], " Add retry options to the HTTP client") {
+		t.Fatalf("branchCommits = %q, want the two branch commits newest first", lines)
+	}
+
+	// Merges are left out, and so is the history of main.
+	mustGit(t, "checkout", "-q", "-b", "side", "main")
+	commitFile(t, "side.go", "package demo\n", "Side change")
+	mustGit(t, "checkout", "-q", "feature")
+	mustGit(t, "merge", "-q", "--no-edit", "side")
+	if lines := branchCommits(); len(lines) != 3 || strings.Contains(strings.Join(lines, "\n"), "Initial commit") || strings.Contains(strings.Join(lines, "\n"), "Merge") {
+		t.Errorf("after a merge branchCommits = %q", lines)
+	}
+
+	// Without an upstream or origin there is nothing to compare with.
+	mustGit(t, "branch", "-q", "--unset-upstream")
+	if lines := branchCommits(); lines != nil {
+		t.Errorf("without an upstream branchCommits = %q", lines)
+	}
+}
+
+func TestSimilarBranchSubjectInStack(t *testing.T) {
+	lines := stack(t,
+		"feat(http): Add retry options to the client",
+		"feat(fetch): Retry idempotent requests",
+		"test(fetch): Cover retries on 503 responses",
+		"docs: Document the retry options",
+	)
+	tests := []struct {
+		subject, match string
+		similar        bool
+	}{
+		// Regenerated near-duplicates of an earlier commit in the stack.
+		{"feat(fetch): Retry idempotent requests", "Retry idempotent requests", true},
+		{"fix(fetch): Retry idempotent request", "Retry idempotent requests", true},
+		{"Add retry option to the client", "Add retry options to the client", true},
+		{"test: Cover retries on 503 response", "Cover retries on 503 responses", true},
+		// The next commit in the stack, about the same feature.
+		{"feat(fetch): Log each retry at debug level", "", false},
+		{"feat(upload): Stream large files in chunks", "", false},
+		{"Bump golang.org/x/net to v0.30.0", "", false},
+	}
+	for _, tt := range tests {
+		line, score, ok := similarBranchSubject(tt.subject, lines, defaultSimilarSubject)
+		if ok != tt.similar {
+			t.Errorf("%q: similar = %v (%.2f to %q), want %v", tt.subject, ok, score, line, tt.similar)
+		}
+		if ok && !strings.HasSuffix(line, tt.match) {
+			t.Errorf("%q: matched %q, want %q", tt.subject, line, tt.match)
+		}
+	}
+	if _, _, ok := similarBranchSubject("docs: Document the retry options", lines, 0); ok {
+		t.Error("a threshold of 0 warned")
+	}
+}
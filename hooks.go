@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// commitHooks are the hooks git may run during `git commit`, in run order.
+var commitHooks = []string{"pre-commit", "prepare-commit-msg", "commit-msg", "post-commit"}
+
+// hookInfo describes an installed, executable hook.
+type hookInfo struct {
+	Name string
+	Path string
+}
+
+// resolveHooksDir returns the directory git runs hooks from. It honors
+// core.hooksPath (as set by husky and the pre-commit framework) and falls
+// back to the repository's hooks directory, which also handles worktrees.
+func resolveHooksDir() (string, error) {
+	output, err := gitCommand("config", "--get", "core.hooksPath").Output()
+	if err == nil {
+		dir := strings.TrimSpace(string(output))
+		if dir != "" {
+			if strings.HasPrefix(dir, "~/") {
+				if home, err := os.UserHomeDir(); err == nil {
+					dir = filepath.Join(home, dir[2:])
+				}
+			}
+			if !filepath.IsAbs(dir) {
+				// Relative hook paths are resolved by git from the top of the work tree.
+				top, err := gitCommand("rev-parse", "--show-toplevel").Output()
+				if err != nil {
+					return "", fmt.Errorf("error resolving hooks path: %v", err)
+				}
+				dir = filepath.Join(strings.TrimSpace(string(top)), dir)
+			}
+			return dir, nil
+		}
+	}
+
+	output, err = gitCommand("rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("error resolving hooks path: %v", err)
+	}
+	dir, err := filepath.Abs(strings.TrimSpace(string(output)))
+	if err != nil {
+		return "", fmt.Errorf("error resolving hooks path: %v", err)
+	}
+	return dir, nil
+}
+
+// findHook returns the hook with the given name if it is installed and
+// executable. Sample hooks (*.sample) are never run by git and are ignored.
+func findHook(dir, name string) (hookInfo, bool) {
+	p := filepath.Join(dir, name)
+	info, err := os.Stat(p)
+	if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+		return hookInfo{}, false
+	}
+	return hookInfo{Name: name, Path: p}, true
+}
+
+// preCommitFrameworkMarker is in every hook script the pre-commit
+// framework (https://pre-commit.com) installs.
+const preCommitFrameworkMarker = "File generated by pre-commit: https://pre-commit.com"
+
+// isPreCommitFramework reports whether the hook was installed by the
+// pre-commit framework. While it runs, the framework sets unstaged changes
+// aside (`git checkout` of the work tree, restored from a patch after),
+// so it must not be killed halfway and the work tree can't be trusted to
+// match the index until it ends.
+func (h hookInfo) isPreCommitFramework() bool {
+	f, err := os.Open(h.Path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 4096)
+	n, _ := io.ReadFull(f, head)
+	return strings.Contains(string(head[:n]), preCommitFrameworkMarker)
+}
+
+// findCommitHooks returns the commit-related hooks that git will run.
+func findCommitHooks() (string, []hookInfo, error) {
+	dir, err := resolveHooksDir()
+	if err != nil {
+		return "", nil, err
+	}
+	var hooks []hookInfo
+	for _, name := range commitHooks {
+		if h, ok := findHook(dir, name); ok {
+			hooks = append(hooks, h)
+		}
+	}
+	return dir, hooks, nil
+}
+
+// printHooks lists the hooks that will run on commit, for verbose output.
+func printHooks() {
+	dir, hooks, err := findCommitHooks()
+	if err != nil {
+		say("Warning: %v\n", err)
+		return
+	}
+	if len(hooks) == 0 {
+		fmt.Printf("No commit hooks found in %s\n", dir)
+		return
+	}
+	fmt.Printf("Commit hooks found in %s:\n", dir)
+	for _, h := range hooks {
+		fmt.Printf("  %s (%s)\n", h.Name, h.Path)
+	}
+}
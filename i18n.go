@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Interactive text goes through say, ask and getUserInput, which look up
+// the English text in the catalog for uiLang and fall back to English when
+// there is no translation. Answer letters (y/n/e) are never translated, so
+// muscle memory and scripts keep working in every language.
+
+// uiLang is the language of the interactive text: detected from the
+// environment, or set by "language" in the user config.
+var uiLang = envLanguage()
+
+// envLanguage reads the language from LC_ALL, LC_MESSAGES or LANG, in the
+// order the C library uses them.
+func envLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			if lang := languageCode(v); catalogs[lang] != nil {
+				return lang
+			}
+			return "en"
+		}
+	}
+	return "en"
+}
+
+// languageCode reduces a locale such as de_DE.UTF-8 or zh-CN to its
+// language, de or zh.
+func languageCode(locale string) string {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	lang, _, _ := strings.Cut(strings.ReplaceAll(locale, "-", "_"), "_")
+	return strings.ToLower(lang)
+}
+
+// setLanguage switches the interactive text to lang; English needs no
+// catalog.
+func setLanguage(lang string) error {
+	code := languageCode(lang)
+	if code != "en" && catalogs[code] == nil {
+		return fmt.Errorf("no translation for language %q (available: %s)", lang, strings.Join(languages(), ", "))
+	}
+	uiLang = code
+	return nil
+}
+
+func languages() []string {
+	langs := []string{"en"}
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs[1:])
+	return langs
+}
+
+// tr returns the translation of text, or text itself.
+func tr(text string) string {
+	if t, ok := catalogs[uiLang][text]; ok {
+		return t
+	}
+	return text
+}
+
+// say prints translated output.
+func say(format string, args ...any) {
+	fmt.Printf(tr(format), args...)
+}
+
+// ask prompts with translated text and returns the answer.
+func ask(format string, args ...any) string {
+	return getUserInput(fmt.Sprintf(tr(format), args...))
+}
+
+// catalogs are the translations, keyed by the English text exactly as it
+// appears in the code, including verbs and surrounding newlines.
+var catalogs = map[string]map[string]string{
+	"de": {
+		"Enter commit message: ":            "Commit-Nachricht eingeben: ",
+		"\nSuggested commit message:\n%s\n": "\nVorgeschlagene Commit-Nachricht:\n%s\n",
+		"\nSuggested commit message (possibly truncated; n to regenerate):\n%s\n":          "\nVorgeschlagene Commit-Nachricht (möglicherweise abgeschnitten; n für einen neuen Vorschlag):\n%s\n",
+		"\nUse this message? (y/n/e to edit): ":                                            "\nDiese Nachricht verwenden? (y/n/e zum Bearbeiten): ",
+		"Invalid option. Please enter y, n, or e.\n":                                       "Ungültige Eingabe. Bitte y, n oder e eingeben.\n",
+		"\nClaude asks: %s\nYour response (%s for the editor): ":                           "\nClaude fragt: %s\nDeine Antwort (%s für den Editor): ",
+		"No answer written. Your response: ":                                               "Keine Antwort geschrieben. Deine Antwort: ",
+		"\nResuming an earlier run. Claude asks: %s\nYour response: ":                      "\nEin früherer Lauf wird fortgesetzt. Claude fragt: %s\nDeine Antwort: ",
+		"Commit successful!\n":                                                             "Commit erfolgreich!\n",
+		"Error: %v\n":                                                                      "Fehler: %v\n",
+		"Warning: %v\n":                                                                    "Warnung: %v\n",
+		"Error editing message: %v\n":                                                      "Fehler beim Bearbeiten der Nachricht: %v\n",
+		"Error editing answer: %v\n":                                                       "Fehler beim Bearbeiten der Antwort: %v\n",
+		"No changes made. Use original message? (y/n): ":                                   "Keine Änderungen. Ursprüngliche Nachricht verwenden? (y/n): ",
+		"Append it to the commit body? (y/n): ":                                            "An den Nachrichtentext anhängen? (y/n): ",
+		"\nContinue to write a commit message? (y/n): ":                                    "\nWeiter zur Commit-Nachricht? (y/n): ",
+		"\nApply %s and commit it? (y/n): ":                                                "\n%s anwenden und committen? (y/n): ",
+		"Missing required section(s): %s. Commit anyway? (y/n): ":                          "Fehlende Pflichtabschnitte: %s. Trotzdem committen? (y/n): ",
+		"Warning: missing required section(s): %s\n":                                       "Warnung: fehlende Pflichtabschnitte: %s\n",
+		"Generating a new suggestion.\n":                                                   "Ein neuer Vorschlag wird erstellt.\n",
+		"Aborted; nothing was committed.\n":                                                "Abgebrochen; es wurde nichts committet.\n",
+		"\nFinal commit message (not committed; -suggest-only):\n%s\n":                     "\nEndgültige Commit-Nachricht (nicht committet; -suggest-only):\n%s\n",
+		"Re-open the editor? (y to edit again, n to abort): ":                              "Editor erneut öffnen? (y zum erneuten Bearbeiten, n zum Abbrechen): ",
+		"Use the sanitized message? (y/n): ":                                               "Die bereinigte Nachricht verwenden? (y/n): ",
+		"Why didn't it fit? (optional, kept in a local log; Enter to skip): ":              "Warum hat er nicht gepasst? (optional, wird lokal protokolliert; Enter zum Überspringen): ",
+		"Also use this as feedback for the next suggestion? (y/n): ":                       "Auch als Rückmeldung für den nächsten Vorschlag verwenden? (y/n): ",
+		"Treat it as a commit message (m) or a question to answer (q)? ":                   "Als Commit-Nachricht (m) oder als zu beantwortende Frage (q) behandeln? ",
+		"\nYour message looks good — commit as-is? (y to commit / g to generate anyway): ": "\nDeine Nachricht sieht gut aus – so committen? (y zum Committen / g für einen Vorschlag): ",
+		"\nPush %s to %s? (y/n): ":                                                         "\n%s nach %s pushen? (y/n): ",
+		"\nCreate the pull request? (y/n/e to edit): ":                                     "\nPull Request erstellen? (y/n/e zum Bearbeiten): ",
+		"Enter API key to store in the keyring: ":                                          "API-Schlüssel für den Schlüsselbund eingeben: ",
+		"\nOnly %d line(s) changed (below -min-diff-lines %d). Commit your message as-is? (y to commit / g to generate anyway): ": "\nNur %d Zeile(n) geändert (unter -min-diff-lines %d). Deine Nachricht so committen? (y zum Committen / g für einen Vorschlag): ",
+		"Which type is this change? (%s; Enter for %s): ":                                                                         "Welcher Typ ist diese Änderung? (%s; Enter für %s): ",
+		"Unknown type %q.\n":                          "Unbekannter Typ %q.\n",
+		"Commit with them replaced by \"?\"? (y/n): ": "Mit \"?\" an ihrer Stelle committen? (y/n): ",
+		"Warning: %s has no %s\n":                     "Warnung: %s enthält kein %s\n",
+		"Check again? (y to check again, e to edit the message first, n to stop): ":        "Erneut prüfen? (y zum erneuten Prüfen, e um zuerst die Nachricht zu bearbeiten, n zum Beenden): ",
+		"Warning: the commit date is in the future\n":                                      "Warnung: Das Commit-Datum liegt in der Zukunft\n",
+		"Warning: the message is %d bytes, over the limit of %d\n":                         "Warnung: Die Nachricht ist %d Bytes groß und überschreitet das Limit von %d\n",
+		"Use the shortened message? (y/n): ":                                               "Die gekürzte Nachricht verwenden? (y/n): ",
+		"The edited message is empty; generating a new suggestion.\n":                      "Die bearbeitete Nachricht ist leer; ein neuer Vorschlag wird erstellt.\n",
+		"Committing your original message; no message was generated.\n":                    "Ihre ursprüngliche Nachricht wird committet; es wurde keine Nachricht erstellt.\n",
+		"\nThe API request failed. Your original message was:\n%s\n":                       "\nDie API-Anfrage ist fehlgeschlagen. Ihre ursprüngliche Nachricht war:\n%s\n",
+		"Commit with your original message? (y/n/e to edit): ":                             "Mit Ihrer ursprünglichen Nachricht committen? (y/n/e zum Bearbeiten): ",
+		"%s: %s (Enter to accept, or type another): ":                                      "%s: %s (Enter zum Übernehmen oder einen anderen Wert eingeben): ",
+		"%s (Enter for none): ":                                                            "%s (Enter für keinen): ",
+		"\nGenerate an improved version? (y/n): ":                                          "\nEine verbesserte Version erstellen? (y/n): ",
+		"Commit this part? (y/n/e to edit): ":                                              "Diesen Teil committen? (y/n/e zum Bearbeiten): ",
+		"\nStage and commit these parts one at a time? (y/n): ":                            "\nDiese Teile nacheinander stagen und committen? (y/n): ",
+		"Warning: the subject is %.0f%% similar to an earlier commit on this branch: %s\n": "Warnung: Der Betreff ist zu %.0f%% ähnlich zu einem früheren Commit auf diesem Branch: %s\n",
+		"Reuse it? (y to commit as-is / i to improve it / n to write a new one): ":         "Wiederverwenden? (y unverändert committen / i verbessern / n neue schreiben): ",
+		"\nError: -yes keeps being asked the same question; stopping.\n":                   "\nFehler: -yes bekommt immer wieder dieselbe Frage; Abbruch.\n",
+		"\nError: end of input.\n":                                                         "\nFehler: Ende der Eingabe.\n",
+		"\nError: stdin ended before this question was answered. Run gitcommit in a terminal, or pass -yes to answer yes/no questions with y.\n": "\nFehler: stdin endete, bevor diese Frage beantwortet wurde. Führen Sie gitcommit in einem Terminal aus oder übergeben Sie -yes, um Ja/Nein-Fragen mit y zu beantworten.\n",
+		"Warning: webhook not delivered within %s\n":                                                                             "Warnung: Webhook wurde nicht innerhalb von %s zugestellt\n",
+		"Enter the webhook secret to store in the keyring: ":                                                                     "Webhook-Geheimnis zum Speichern im Schlüsselbund eingeben: ",
+		"Note: -context-cmd %s; its output is sent anyway\n":                                                                     "Hinweis: -context-cmd %s; die Ausgabe wird trotzdem gesendet\n",
+		"\ngitcommit sends your staged diff, file names, recent commit messages and what you type to %s to write the message.\n": "\ngitcommit sendet deinen gestagten Diff, Dateinamen, letzte Commit-Nachrichten und deine Eingaben an %s, um die Nachricht zu schreiben.\n",
+		"See %s for how Anthropic handles API data. Nothing has been sent yet.\n":                                                "Unter %s steht, wie Anthropic API-Daten behandelt. Es wurde noch nichts gesendet.\n",
+		"Type yes to agree and continue, or no to stop (asked only once): ":                                                      "Gib yes ein, um zuzustimmen und fortzufahren, oder no zum Abbrechen (wird nur einmal gefragt): ",
+		"Warning: could not record your answer, so you'll be asked again: %v\n":                                                  "Warnung: deine Antwort konnte nicht gespeichert werden, du wirst erneut gefragt: %v\n",
+		"Nothing was sent; -dry-run-diff shows what would be.\n":                                                                 "Es wurde nichts gesendet; -dry-run-diff zeigt, was gesendet würde.\n",
+		"Commit it? (y/n): ": "Committen? (y/n): ",
+		"\nCommand to make the commit (not run; -print-final-command):\n%s\n": "\nBefehl für den Commit (nicht ausgeführt; -print-final-command):\n%s\n",
+	},
+	"es": {
+		"Enter commit message: ":            "Escribe el mensaje del commit: ",
+		"\nSuggested commit message:\n%s\n": "\nMensaje de commit sugerido:\n%s\n",
+		"\nSuggested commit message (possibly truncated; n to regenerate):\n%s\n":          "\nMensaje de commit sugerido (quizá truncado; n para generar otro):\n%s\n",
+		"\nUse this message? (y/n/e to edit): ":                                            "\n¿Usar este mensaje? (y/n/e para editar): ",
+		"Invalid option. Please enter y, n, or e.\n":                                       "Opción no válida. Escribe y, n o e.\n",
+		"\nClaude asks: %s\nYour response (%s for the editor): ":                           "\nClaude pregunta: %s\nTu respuesta (%s para el editor): ",
+		"No answer written. Your response: ":                                               "No se escribió ninguna respuesta. Tu respuesta: ",
+		"\nResuming an earlier run. Claude asks: %s\nYour response: ":                      "\nReanudando una ejecución anterior. Claude pregunta: %s\nTu respuesta: ",
+		"Commit successful!\n":                                                             "¡Commit realizado!\n",
+		"Error: %v\n":                                                                      "Error: %v\n",
+		"Warning: %v\n":                                                                    "Aviso: %v\n",
+		"Error editing message: %v\n":                                                      "Error al editar el mensaje: %v\n",
+		"Error editing answer: %v\n":                                                       "Error al editar la respuesta: %v\n",
+		"No changes made. Use original message? (y/n): ":                                   "No hubo cambios. ¿Usar el mensaje original? (y/n): ",
+		"Append it to the commit body? (y/n): ":                                            "¿Añadirlo al cuerpo del commit? (y/n): ",
+		"\nContinue to write a commit message? (y/n): ":                                    "\n¿Continuar con el mensaje del commit? (y/n): ",
+		"\nApply %s and commit it? (y/n): ":                                                "\n¿Aplicar %s y hacer commit? (y/n): ",
+		"Missing required section(s): %s. Commit anyway? (y/n): ":                          "Faltan secciones obligatorias: %s. ¿Hacer commit de todos modos? (y/n): ",
+		"Warning: missing required section(s): %s\n":                                       "Aviso: faltan secciones obligatorias: %s\n",
+		"Generating a new suggestion.\n":                                                   "Generando una nueva sugerencia.\n",
+		"Aborted; nothing was committed.\n":                                                "Cancelado; no se hizo ningún commit.\n",
+		"\nFinal commit message (not committed; -suggest-only):\n%s\n":                     "\nMensaje de commit final (sin commit; -suggest-only):\n%s\n",
+		"Re-open the editor? (y to edit again, n to abort): ":                              "¿Abrir el editor de nuevo? (y para editar otra vez, n para cancelar): ",
+		"Use the sanitized message? (y/n): ":                                               "¿Usar el mensaje limpio? (y/n): ",
+		"Why didn't it fit? (optional, kept in a local log; Enter to skip): ":              "¿Por qué no encajaba? (opcional, se guarda en un registro local; Enter para omitir): ",
+		"Also use this as feedback for the next suggestion? (y/n): ":                       "¿Usarlo también como indicación para la próxima sugerencia? (y/n): ",
+		"Treat it as a commit message (m) or a question to answer (q)? ":                   "¿Tratarlo como mensaje de commit (m) o como pregunta a responder (q)? ",
+		"\nYour message looks good — commit as-is? (y to commit / g to generate anyway): ": "\nTu mensaje se ve bien: ¿hacer commit tal cual? (y para hacer commit / g para generar otro): ",
+		"\nPush %s to %s? (y/n): ":                                                         "\n¿Hacer push de %s a %s? (y/n): ",
+		"\nCreate the pull request? (y/n/e to edit): ":                                     "\n¿Crear el pull request? (y/n/e para editar): ",
+		"Enter API key to store in the keyring: ":                                          "Escribe la clave de API para guardarla en el llavero: ",
+		"\nOnly %d line(s) changed (below -min-diff-lines %d). Commit your message as-is? (y to commit / g to generate anyway): ": "\nSolo cambiaron %d línea(s) (menos que -min-diff-lines %d). ¿Hacer commit de tu mensaje tal cual? (y para hacer commit / g para generar otro): ",
+		"Which type is this change? (%s; Enter for %s): ":                                                                         "¿De qué tipo es este cambio? (%s; Enter para %s): ",
+		"Unknown type %q.\n":                          "Tipo desconocido %q.\n",
+		"Commit with them replaced by \"?\"? (y/n): ": "¿Hacer commit reemplazándolos por \"?\"? (y/n): ",
+		"Warning: %s has no %s\n":                     "Advertencia: %s no tiene %s\n",
+		"Check again? (y to check again, e to edit the message first, n to stop): ":        "¿Comprobar de nuevo? (y para comprobar de nuevo, e para editar antes el mensaje, n para terminar): ",
+		"Warning: the commit date is in the future\n":                                      "Advertencia: la fecha del commit está en el futuro\n",
+		"Warning: the message is %d bytes, over the limit of %d\n":                         "Advertencia: el mensaje ocupa %d bytes, por encima del límite de %d\n",
+		"Use the shortened message? (y/n): ":                                               "¿Usar el mensaje acortado? (y/n): ",
+		"The edited message is empty; generating a new suggestion.\n":                      "El mensaje editado está vacío; generando una nueva sugerencia.\n",
+		"Committing your original message; no message was generated.\n":                    "Confirmando su mensaje original; no se generó ningún mensaje.\n",
+		"\nThe API request failed. Your original message was:\n%s\n":                       "\nLa solicitud a la API falló. Su mensaje original era:\n%s\n",
+		"Commit with your original message? (y/n/e to edit): ":                             "¿Confirmar con su mensaje original? (y/n/e para editar): ",
+		"%s: %s (Enter to accept, or type another): ":                                      "%s: %s (Enter para aceptar o escriba otro): ",
+		"%s (Enter for none): ":                                                            "%s (Enter para ninguno): ",
+		"\nGenerate an improved version? (y/n): ":                                          "\n¿Generar una versión mejorada? (y/n): ",
+		"Commit this part? (y/n/e to edit): ":                                              "¿Confirmar esta parte? (y/n/e para editar): ",
+		"\nStage and commit these parts one at a time? (y/n): ":                            "\n¿Preparar y confirmar estas partes una a una? (y/n): ",
+		"Warning: the subject is %.0f%% similar to an earlier commit on this branch: %s\n": "Advertencia: el asunto es un %.0f%% similar a un commit anterior de esta rama: %s\n",
+		"Reuse it? (y to commit as-is / i to improve it / n to write a new one): ":         "¿Reutilizarlo? (y para confirmarlo tal cual / i para mejorarlo / n para escribir uno nuevo): ",
+		"\nError: -yes keeps being asked the same question; stopping.\n":                   "\nError: -yes recibe la misma pregunta una y otra vez; deteniendo.\n",
+		"\nError: end of input.\n":                                                         "\nError: fin de la entrada.\n",
+		"\nError: stdin ended before this question was answered. Run gitcommit in a terminal, or pass -yes to answer yes/no questions with y.\n": "\nError: stdin terminó antes de responder esta pregunta. Ejecute gitcommit en una terminal o use -yes para responder y a las preguntas de sí/no.\n",
+		"Warning: webhook not delivered within %s\n":                                                                             "Aviso: el webhook no se entregó en %s\n",
+		"Enter the webhook secret to store in the keyring: ":                                                                     "Introduce el secreto del webhook para guardarlo en el llavero: ",
+		"Note: -context-cmd %s; its output is sent anyway\n":                                                                     "Nota: -context-cmd %s; su salida se envía de todos modos\n",
+		"\ngitcommit sends your staged diff, file names, recent commit messages and what you type to %s to write the message.\n": "\ngitcommit envía tu diff preparado, los nombres de archivo, mensajes de commit recientes y lo que escribes a %s para redactar el mensaje.\n",
+		"See %s for how Anthropic handles API data. Nothing has been sent yet.\n":                                                "Consulta %s para saber cómo trata Anthropic los datos de la API. Aún no se ha enviado nada.\n",
+		"Type yes to agree and continue, or no to stop (asked only once): ":                                                      "Escribe yes para aceptar y continuar, o no para detenerte (solo se pregunta una vez): ",
+		"Warning: could not record your answer, so you'll be asked again: %v\n":                                                  "Aviso: no se pudo guardar tu respuesta, así que se te volverá a preguntar: %v\n",
+		"Nothing was sent; -dry-run-diff shows what would be.\n":                                                                 "No se envió nada; -dry-run-diff muestra lo que se enviaría.\n",
+		"Commit it? (y/n): ": "¿Hacer el commit? (y/n): ",
+		"\nCommand to make the commit (not run; -print-final-command):\n%s\n": "\nComando para hacer el commit (no ejecutado; -print-final-command):\n%s\n",
+	},
+	"ja": {
+		"Enter commit message: ":            "コミットメッセージを入力してください: ",
+		"\nSuggested commit message:\n%s\n": "\n提案されたコミットメッセージ:\n%s\n",
+		"\nSuggested commit message (possibly truncated; n to regenerate):\n%s\n":          "\n提案されたコミットメッセージ（途中で切れている可能性があります。n で再生成）:\n%s\n",
+		"\nUse this message? (y/n/e to edit): ":                                            "\nこのメッセージを使いますか？ (y/n/e で編集): ",
+		"Invalid option. Please enter y, n, or e.\n":                                       "無効な入力です。y、n、e のいずれかを入力してください。\n",
+		"\nClaude asks: %s\nYour response (%s for the editor): ":                           "\nClaude からの質問: %s\n回答（%s でエディタを開く）: ",
+		"No answer written. Your response: ":                                               "回答が書かれていません。回答: ",
+		"\nResuming an earlier run. Claude asks: %s\nYour response: ":                      "\n前回の実行を再開します。Claude からの質問: %s\n回答: ",
+		"Commit successful!\n":                                                             "コミットしました！\n",
+		"Error: %v\n":                                                                      "エラー: %v\n",
+		"Warning: %v\n":                                                                    "警告: %v\n",
+		"Error editing message: %v\n":                                                      "メッセージの編集中にエラーが発生しました: %v\n",
+		"Error editing answer: %v\n":                                                       "回答の編集中にエラーが発生しました: %v\n",
+		"No changes made. Use original message? (y/n): ":                                   "変更はありません。元のメッセージを使いますか？ (y/n): ",
+		"Append it to the commit body? (y/n): ":                                            "コミット本文に追加しますか？ (y/n): ",
+		"\nContinue to write a commit message? (y/n): ":                                    "\nコミットメッセージの作成に進みますか？ (y/n): ",
+		"\nApply %s and commit it? (y/n): ":                                                "\n%s を適用してコミットしますか？ (y/n): ",
+		"Missing required section(s): %s. Commit anyway? (y/n): ":                          "必須セクションがありません: %s。このままコミットしますか？ (y/n): ",
+		"Warning: missing required section(s): %s\n":                                       "警告: 必須セクションがありません: %s\n",
+		"Generating a new suggestion.\n":                                                   "新しい提案を生成します。\n",
+		"Aborted; nothing was committed.\n":                                                "中止しました。何もコミットされていません。\n",
+		"\nFinal commit message (not committed; -suggest-only):\n%s\n":                     "\n最終的なコミットメッセージ（-suggest-only のためコミットしていません）:\n%s\n",
+		"Re-open the editor? (y to edit again, n to abort): ":                              "エディタをもう一度開きますか？ (y で再編集、n で中止): ",
+		"Use the sanitized message? (y/n): ":                                               "整形したメッセージを使いますか？ (y/n): ",
+		"Why didn't it fit? (optional, kept in a local log; Enter to skip): ":              "合わなかった理由は？（任意。ローカルのログに保存されます。Enter でスキップ）: ",
+		"Also use this as feedback for the next suggestion? (y/n): ":                       "次の提案へのフィードバックとしても使いますか？ (y/n): ",
+		"Treat it as a commit message (m) or a question to answer (q)? ":                   "コミットメッセージ (m) と質問 (q) のどちらとして扱いますか？ ",
+		"\nYour message looks good — commit as-is? (y to commit / g to generate anyway): ": "\nメッセージは良さそうです。このままコミットしますか？ (y でコミット / g で生成): ",
+		"\nPush %s to %s? (y/n): ":                                                         "\n%s を %s にプッシュしますか？ (y/n): ",
+		"\nCreate the pull request? (y/n/e to edit): ":                                     "\nプルリクエストを作成しますか？ (y/n/e で編集): ",
+		"Enter API key to store in the keyring: ":                                          "キーリングに保存する API キーを入力してください: ",
+		"\nOnly %d line(s) changed (below -min-diff-lines %d). Commit your message as-is? (y to commit / g to generate anyway): ": "\n変更は %d 行のみです（-min-diff-lines %d 未満）。入力したメッセージのままコミットしますか？ (y でコミット / g で生成): ",
+		"Which type is this change? (%s; Enter for %s): ":                                                                         "この変更の種類は？ (%s; Enter で %s): ",
+		"Unknown type %q.\n":                          "不明な種類です: %q\n",
+		"Commit with them replaced by \"?\"? (y/n): ": "\"?\" に置き換えてコミットしますか? (y/n): ",
+		"Warning: %s has no %s\n":                     "警告: %s には %s がありません\n",
+		"Check again? (y to check again, e to edit the message first, n to stop): ":        "もう一度チェックしますか? (y で再チェック、e で先にメッセージを編集、n で終了): ",
+		"Warning: the commit date is in the future\n":                                      "警告: コミット日時が未来の日付です\n",
+		"Warning: the message is %d bytes, over the limit of %d\n":                         "警告: メッセージは %d バイトで、上限の %d を超えています\n",
+		"Use the shortened message? (y/n): ":                                               "短くしたメッセージを使いますか? (y/n): ",
+		"The edited message is empty; generating a new suggestion.\n":                      "編集したメッセージが空です。新しい提案を生成します。\n",
+		"Committing your original message; no message was generated.\n":                    "元のメッセージでコミットします。メッセージは生成されませんでした。\n",
+		"\nThe API request failed. Your original message was:\n%s\n":                       "\nAPI リクエストが失敗しました。元のメッセージ:\n%s\n",
+		"Commit with your original message? (y/n/e to edit): ":                             "元のメッセージでコミットしますか? (y/n/e で編集): ",
+		"%s: %s (Enter to accept, or type another): ":                                      "%s: %s (Enter で確定、または別の値を入力): ",
+		"%s (Enter for none): ":                                                            "%s (Enter でなし): ",
+		"\nGenerate an improved version? (y/n): ":                                          "\n改善版を生成しますか? (y/n): ",
+		"Commit this part? (y/n/e to edit): ":                                              "この部分をコミットしますか? (y/n/e で編集): ",
+		"\nStage and commit these parts one at a time? (y/n): ":                            "\nこれらの部分を 1 つずつステージしてコミットしますか? (y/n): ",
+		"Warning: the subject is %.0f%% similar to an earlier commit on this branch: %s\n": "警告: 件名がこのブランチの以前のコミットと %.0f%% 類似しています: %s\n",
+		"Reuse it? (y to commit as-is / i to improve it / n to write a new one): ":         "再利用しますか? (y でそのままコミット / i で改善 / n で新しく書く): ",
+		"\nError: -yes keeps being asked the same question; stopping.\n":                   "\nエラー: -yes に同じ質問が繰り返されています。停止します。\n",
+		"\nError: end of input.\n":                                                         "\nエラー: 入力が終了しました。\n",
+		"\nError: stdin ended before this question was answered. Run gitcommit in a terminal, or pass -yes to answer yes/no questions with y.\n": "\nエラー: この質問に答える前に stdin が終了しました。ターミナルで gitcommit を実行するか、-yes を指定して yes/no の質問に y で答えてください。\n",
+		"Warning: webhook not delivered within %s\n":                                                                             "警告: webhook が %s 以内に送信されませんでした\n",
+		"Enter the webhook secret to store in the keyring: ":                                                                     "キーリングに保存する webhook のシークレットを入力してください: ",
+		"Note: -context-cmd %s; its output is sent anyway\n":                                                                     "注意: -context-cmd %s。出力はそのまま送信されます\n",
+		"\ngitcommit sends your staged diff, file names, recent commit messages and what you type to %s to write the message.\n": "\ngitcommit はメッセージ作成のため、ステージ済みの diff、ファイル名、最近のコミットメッセージ、入力内容を %s に送信します。\n",
+		"See %s for how Anthropic handles API data. Nothing has been sent yet.\n":                                                "Anthropic による API データの取り扱いは %s を参照してください。まだ何も送信されていません。\n",
+		"Type yes to agree and continue, or no to stop (asked only once): ":                                                      "同意して続行するには yes、中止するには no を入力してください（この質問は一度だけです）: ",
+		"Warning: could not record your answer, so you'll be asked again: %v\n":                                                  "警告: 回答を保存できなかったため、次回も確認されます: %v\n",
+		"Nothing was sent; -dry-run-diff shows what would be.\n":                                                                 "何も送信されていません。送信内容は -dry-run-diff で確認できます。\n",
+		"Commit it? (y/n): ": "コミットしますか？ (y/n): ",
+		"\nCommand to make the commit (not run; -print-final-command):\n%s\n": "\nコミットを作成するコマンド（-print-final-command のため実行していません）:\n%s\n",
+	},
+	"zh": {
+		"Enter commit message: ":            "输入提交信息: ",
+		"\nSuggested commit message:\n%s\n": "\n建议的提交信息:\n%s\n",
+		"\nSuggested commit message (possibly truncated; n to regenerate):\n%s\n":          "\n建议的提交信息（可能不完整；按 n 重新生成）:\n%s\n",
+		"\nUse this message? (y/n/e to edit): ":                                            "\n使用这条信息吗？(y/n/e 编辑): ",
+		"Invalid option. Please enter y, n, or e.\n":                                       "无效的选项。请输入 y、n 或 e。\n",
+		"\nClaude asks: %s\nYour response (%s for the editor): ":                           "\nClaude 提问: %s\n你的回答（输入 %s 打开编辑器）: ",
+		"No answer written. Your response: ":                                               "没有写入回答。你的回答: ",
+		"\nResuming an earlier run. Claude asks: %s\nYour response: ":                      "\n继续之前的运行。Claude 提问: %s\n你的回答: ",
+		"Commit successful!\n":                                                             "提交成功！\n",
+		"Error: %v\n":                                                                      "错误: %v\n",
+		"Warning: %v\n":                                                                    "警告: %v\n",
+		"Error editing message: %v\n":                                                      "编辑信息时出错: %v\n",
+		"Error editing answer: %v\n":                                                       "编辑回答时出错: %v\n",
+		"No changes made. Use original message? (y/n): ":                                   "没有修改。使用原来的信息吗？(y/n): ",
+		"Append it to the commit body? (y/n): ":                                            "追加到提交正文吗？(y/n): ",
+		"\nContinue to write a commit message? (y/n): ":                                    "\n继续编写提交信息吗？(y/n): ",
+		"\nApply %s and commit it? (y/n): ":                                                "\n应用 %s 并提交吗？(y/n): ",
+		"Missing required section(s): %s. Commit anyway? (y/n): ":                          "缺少必需的部分: %s。仍然提交吗？(y/n): ",
+		"Warning: missing required section(s): %s\n":                                       "警告: 缺少必需的部分: %s\n",
+		"Generating a new suggestion.\n":                                                   "正在生成新的建议。\n",
+		"Aborted; nothing was committed.\n":                                                "已中止；没有提交任何内容。\n",
+		"\nFinal commit message (not committed; -suggest-only):\n%s\n":                     "\n最终的提交信息（未提交；-suggest-only）:\n%s\n",
+		"Re-open the editor? (y to edit again, n to abort): ":                              "重新打开编辑器吗？(y 再次编辑，n 中止): ",
+		"Use the sanitized message? (y/n): ":                                               "使用清理后的信息吗？(y/n): ",
+		"Why didn't it fit? (optional, kept in a local log; Enter to skip): ":              "为什么不合适？（可选，保存在本地日志中；按 Enter 跳过）: ",
+		"Also use this as feedback for the next suggestion? (y/n): ":                       "同时作为下一次建议的反馈吗？(y/n): ",
+		"Treat it as a commit message (m) or a question to answer (q)? ":                   "将其视为提交信息 (m) 还是需要回答的问题 (q)？",
+		"\nYour message looks good — commit as-is? (y to commit / g to generate anyway): ": "\n你的信息看起来不错——直接提交吗？(y 提交 / g 仍然生成): ",
+		"\nPush %s to %s? (y/n): ":                                                         "\n将 %s 推送到 %s 吗？(y/n): ",
+		"\nCreate the pull request? (y/n/e to edit): ":                                     "\n创建拉取请求吗？(y/n/e 编辑): ",
+		"Enter API key to store in the keyring: ":                                          "输入要保存到密钥环的 API 密钥: ",
+		"\nOnly %d line(s) changed (below -min-diff-lines %d). Commit your message as-is? (y to commit / g to generate anyway): ": "\n只修改了 %d 行（少于 -min-diff-lines %d）。直接使用你的信息提交吗？(y 提交 / g 仍然生成): ",
+		"Which type is this change? (%s; Enter for %s): ":                                                                         "这个修改是什么类型？(%s；按 Enter 使用 %s): ",
+		"Unknown type %q.\n":                          "未知的类型 %q。\n",
+		"Commit with them replaced by \"?\"? (y/n): ": "将它们替换为 \"?\" 后提交? (y/n): ",
+		"Warning: %s has no %s\n":                     "警告: %s 中没有 %s\n",
+		"Check again? (y to check again, e to edit the message first, n to stop): ":        "再次检查? (y 再次检查, e 先编辑消息, n 停止): ",
+		"Warning: the commit date is in the future\n":                                      "警告: 提交日期在未来\n",
+		"Warning: the message is %d bytes, over the limit of %d\n":                         "警告: 消息为 %d 字节, 超过了 %d 的限制\n",
+		"Use the shortened message? (y/n): ":                                               "使用缩短后的消息? (y/n): ",
+		"The edited message is empty; generating a new suggestion.\n":                      "编辑后的消息为空；正在生成新的建议。\n",
+		"Committing your original message; no message was generated.\n":                    "正在使用您的原始消息提交；未生成消息。\n",
+		"\nThe API request failed. Your original message was:\n%s\n":                       "\nAPI 请求失败。您的原始消息是：\n%s\n",
+		"Commit with your original message? (y/n/e to edit): ":                             "使用原始消息提交？(y/n/e 编辑): ",
+		"%s: %s (Enter to accept, or type another): ":                                      "%s: %s（按 Enter 接受，或输入其他值）: ",
+		"%s (Enter for none): ":                                                            "%s（按 Enter 表示无）: ",
+		"\nGenerate an improved version? (y/n): ":                                          "\n生成改进版本？(y/n): ",
+		"Commit this part? (y/n/e to edit): ":                                              "提交这一部分？(y/n/e 编辑): ",
+		"\nStage and commit these parts one at a time? (y/n): ":                            "\n逐个暂存并提交这些部分？(y/n): ",
+		"Warning: the subject is %.0f%% similar to an earlier commit on this branch: %s\n": "警告：主题与此分支上较早的提交相似度为 %.0f%%：%s\n",
+		"Reuse it? (y to commit as-is / i to improve it / n to write a new one): ":         "重用它？(y 原样提交 / i 改进 / n 重新编写): ",
+		"\nError: -yes keeps being asked the same question; stopping.\n":                   "\n错误：-yes 一直被问同一个问题；正在停止。\n",
+		"\nError: end of input.\n":                                                         "\n错误：输入结束。\n",
+		"\nError: stdin ended before this question was answered. Run gitcommit in a terminal, or pass -yes to answer yes/no questions with y.\n": "\n错误：在回答此问题之前 stdin 已结束。请在终端中运行 gitcommit，或使用 -yes 以 y 回答是/否问题。\n",
+		"Warning: webhook not delivered within %s\n":                                                                             "警告：webhook 未能在 %s 内送达\n",
+		"Enter the webhook secret to store in the keyring: ":                                                                     "输入要存入密钥环的 webhook 密钥： ",
+		"Note: -context-cmd %s; its output is sent anyway\n":                                                                     "注意：-context-cmd %s；其输出仍会发送\n",
+		"\ngitcommit sends your staged diff, file names, recent commit messages and what you type to %s to write the message.\n": "\ngitcommit 会将暂存的 diff、文件名、最近的提交信息以及你输入的内容发送到 %s 以撰写提交信息。\n",
+		"See %s for how Anthropic handles API data. Nothing has been sent yet.\n":                                                "Anthropic 如何处理 API 数据请参阅 %s。目前尚未发送任何内容。\n",
+		"Type yes to agree and continue, or no to stop (asked only once): ":                                                      "输入 yes 同意并继续，或输入 no 停止（仅询问一次）： ",
+		"Warning: could not record your answer, so you'll be asked again: %v\n":                                                  "警告：无法记录你的回答，下次仍会询问： %v\n",
+		"Nothing was sent; -dry-run-diff shows what would be.\n":                                                                 "未发送任何内容；-dry-run-diff 可显示将要发送的内容。\n",
+		"Commit it? (y/n): ": "提交吗？(y/n)： ",
+		"\nCommand to make the commit (not run; -print-final-command):\n%s\n": "\n用于提交的命令（未执行；-print-final-command）:\n%s\n",
+	},
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// imperativePrompt is added to the prompt by -imperative.
+const imperativePrompt = `Write the subject in the imperative mood, as if giving a command: "Add retry to webhook delivery", not "Added", "Adds" or "Adding". The first word must be a bare verb.`
+
+// commonVerbs are verbs that often start a subject. Their -s, -ed and -ing
+// forms are flagged even where the suffix rules below can't tell.
+var commonVerbs = []string{
+	"add", "allow", "avoid", "bump", "change", "clean", "convert", "create",
+	"delete", "disable", "document", "drop", "enable", "ensure", "extract",
+	"fix", "handle", "implement", "improve", "introduce", "merge", "move",
+	"optimize", "prevent", "reduce", "refactor", "remove", "rename",
+	"replace", "restore", "revert", "simplify", "support", "switch", "update",
+	"upgrade", "use",
+}
+
+// irregularPast are past forms that have no -ed.
+var irregularPast = map[string]bool{
+	"made": true, "wrote": true, "built": true, "ran": true, "broke": true,
+	"took": true, "gave": true, "brought": true, "kept": true, "left": true,
+	"found": true, "began": true, "chose": true, "rewrote": true,
+}
+
+// imperativeExceptions end like past tense or gerunds but are bare verbs
+// (or nouns) that commonly start a subject.
+var imperativeExceptions = map[string]bool{
+	"embed": true, "feed": true, "seed": true, "speed": true, "need": true,
+	"proceed": true, "exceed": true, "succeed": true, "shed": true, "shred": true,
+	"bring": true, "ring": true, "string": true, "ping": true, "sing": true,
+	"process": true, "address": true, "pass": true, "access": true,
+	"focus": true, "bias": true, "alias": true, "canvas": true,
+}
+
+var nonImperativeForms = func() map[string]bool {
+	forms := map[string]bool{}
+	for _, v := range commonVerbs {
+		stem := strings.TrimSuffix(v, "e")
+		forms[v+"s"], forms[v+"es"] = true, true
+		forms[stem+"ed"], forms[stem+"ing"] = true, true
+		if strings.HasSuffix(v, "y") {
+			forms[v[:len(v)-1]+"ied"], forms[v[:len(v)-1]+"ies"] = true, true
+		}
+	}
+	return forms
+}()
+
+// subjectFirstWord returns the first word of a subject's summary, after
+// any conventional type(scope): prefix or [tag], lowercased.
+func subjectFirstWord(subject string) string {
+	if _, _, ok := parseConventional(subject); ok {
+		_, subject, _ = strings.Cut(subject, ":")
+	}
+	subject = strings.TrimSpace(subject)
+	for strings.HasPrefix(subject, "[") {
+		end := strings.Index(subject, "]")
+		if end < 0 {
+			break
+		}
+		subject = strings.TrimSpace(subject[end+1:])
+	}
+	fields := strings.Fields(subject)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(fields[0], ".,:;!\"'`"))
+}
+
+// checkImperativeMood is a light heuristic: it reports false when the
+// subject's first word looks like a past tense ("Added"), a gerund
+// ("Adding") or a third-person form ("Adds") rather than a bare verb.
+func checkImperativeMood(subject string) bool {
+	w := subjectFirstWord(subject)
+	if w == "" || imperativeExceptions[w] {
+		return true
+	}
+	if nonImperativeForms[w] || irregularPast[w] {
+		return false
+	}
+	if len(w) > 4 && (strings.HasSuffix(w, "ed") && !strings.HasSuffix(w, "eed") || strings.HasSuffix(w, "ing")) {
+		return false
+	}
+	return true
+}
+
+// imperativeCheck is checkImperativeMood as a messageCheck.
+func imperativeCheck(message string) []string {
+	if subject, _ := splitMessage(message); !checkImperativeMood(subject) {
+		return []string{fmt.Sprintf(`the subject starts with %q; use a bare verb, such as "Add" or "Fix"`, subjectFirstWord(subject))}
+	}
+	return nil
+}
+
+// imperativeRetryPrompt asks once more for a suggestion whose subject
+// failed checkImperativeMood.
+func imperativeRetryPrompt(message string) string {
+	subject, _ := splitMessage(message)
+	return fmt.Sprintf("\n\nYou suggested:\n```\n%s\n```\nThe subject starts with %q, which isn't the imperative mood. Rewrite it so the subject starts with a bare verb (\"Add\", not \"Added\", \"Adds\" or \"Adding\").", message, subjectFirstWord(subject))
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestCheckImperativeMood(t *testing.T) {
+	tests := map[string]bool{
+		"Add retries":                      true,
+		"Added retries":                    false,
+		"Adds retries":                     false,
+		"Adding retries":                   false,
+		"Wrote the docs":                   false,
+		"Embed the schema":                 true,
+		"Process queued jobs":              true,
+		"Bring back the cache":             true,
+		"fix(parser): handled empty input": false,
+		"fix(parser): handle empty input":  true,
+		"[PROJ-1] Fixed the parser":        false,
+		"":                                 true,
+	}
+	for subject, want := range tests {
+		if got := checkImperativeMood(subject); got != want {
+			t.Errorf("checkImperativeMood(%q) = %v, want %v", subject, got, want)
+		}
+	}
+}
+
+func TestImperativeCheck(t *testing.T) {
+	if problems := imperativeCheck("Add retries\n\nUploads retried three times."); len(problems) != 0 {
+		t.Errorf("an imperative subject gave %q", problems)
+	}
+	problems := imperativeCheck("Added retries")
+	if len(problems) != 1 || problems[0] != `the subject starts with "added"; use a bare verb, such as "Add" or "Fix"` {
+		t.Errorf("imperativeCheck = %q", problems)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The API key is stored in the OS credential store under this service name.
+const keyringService = "gitcommit"
+const defaultKeyringAccount = "CLAUDE_API_KEY"
+
+// errKeyringUnsupported is returned on platforms without a supported
+// credential store helper.
+var errKeyringUnsupported = fmt.Errorf("no supported keyring on %s (needs macOS Keychain, secret-tool or Windows Credential Manager)", runtime.GOOS)
+
+// keyringGet reads the API key from the OS credential store using the
+// platform's own command-line helper, or Credential Manager's API on
+// Windows, so the key never lives in plaintext.
+func keyringGet(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		return windowsCredentialGet(account)
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", account)
+	default:
+		return "", errKeyringUnsupported
+	}
+	if _, err := exec.LookPath(cmd.Path); err != nil {
+		return "", errKeyringUnsupported
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error reading key from keyring: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// keyringSet stores the API key in the OS credential store.
+func keyringSet(account, key string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		return windowsCredentialSet(account, key)
+	case "darwin":
+		// -U updates an existing entry. security(1) only takes the key as
+		// an argument, where ps would show it, so the command is given on
+		// stdin to its interactive mode instead.
+		if strings.ContainsAny(key, "\r\n") {
+			return fmt.Errorf("error storing key in keyring: it contains a line break")
+		}
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			securityQuote(keyringService), securityQuote(account), securityQuote(key)))
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "store", "--label=gitcommit API key", "service", keyringService, "account", account)
+		cmd.Stdin = strings.NewReader(key)
+	default:
+		return errKeyringUnsupported
+	}
+	if _, err := exec.LookPath(cmd.Path); err != nil {
+		return errKeyringUnsupported
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error storing key in keyring: %v: %s", err, strings.TrimSpace(string(output)))
+	} else if runtime.GOOS == "darwin" {
+		// security -i reports a failed command but still exits 0, so
+		// check that the key reads back.
+		if stored, err := keyringGet(account); err != nil || stored != key {
+			return fmt.Errorf("error storing key in keyring: %s", strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// securityQuote quotes an argument for security -i, which splits its
+// input lines on spaces and honors double quotes and backslashes.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// runKeyring implements `gitcommit keyring set`, storing the key under the
+// given account (the active profile's, if it has one), and `gitcommit
+// keyring set webhook`, storing the webhook's shared secret.
+func runKeyring(args []string, account string, webhook *webhookConfig) error {
+	if len(args) == 2 && args[0] == "set" && args[1] == "webhook" {
+		if webhook == nil {
+			webhook = &webhookConfig{}
+		}
+		secret := getUserInput("Enter the webhook secret to store in the keyring: ")
+		if secret == "" {
+			return fmt.Errorf("no secret entered")
+		}
+		if err := keyringSet(webhook.secretAccount(), secret); err != nil {
+			return err
+		}
+		fmt.Println("Stored the webhook secret in the keyring.")
+		return nil
+	}
+	if len(args) != 1 || args[0] != "set" {
+		return fmt.Errorf("usage: gitcommit keyring set [webhook]")
+	}
+	key := getUserInput("Enter API key to store in the keyring: ")
+	if key == "" {
+		return fmt.Errorf("no key entered")
+	}
+	if err := keyringSet(account, key); err != nil {
+		return err
+	}
+	fmt.Println("Stored API key in the keyring. Use -keyring (or \"keyring\": true in config) to read it.")
+	return nil
+}
+
+// resolveAPIKey returns the API key, preferring the keyring when enabled and
+// falling back to the environment.
+func resolveAPIKey(useKeyring bool, verbose bool) string {
+	if useKeyring {
+		key, err := keyringGet(defaultKeyringAccount)
+		if err == nil && key != "" {
+			return key
+		}
+		if verbose && err != nil {
+			fmt.Printf("Warning: %v; falling back to CLAUDE_API_KEY\n", err)
+		}
+	}
+	return os.Getenv("CLAUDE_API_KEY")
+}
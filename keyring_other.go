@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+// Credential Manager exists only on Windows.
+
+func windowsCredentialGet(account string) (string, error) {
+	return "", errKeyringUnsupported
+}
+
+func windowsCredentialSet(account, key string) error {
+	return errKeyringUnsupported
+}
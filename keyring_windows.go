@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Windows Credential Manager, through advapi32 directly, since it has no
+// command-line helper that can read a secret back.
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = 1168
+)
+
+// winCredential is CREDENTIALW.
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialTarget names the generic credential for an account, as shown
+// in Credential Manager.
+func credentialTarget(account string) string {
+	return keyringService + ":" + account
+}
+
+func windowsCredentialGet(account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(credentialTarget(account))
+	if err != nil {
+		return "", err
+	}
+	var cred *winCredential
+	ok, _, callErr := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ok == 0 {
+		if callErr == syscall.Errno(errorNotFound) {
+			return "", fmt.Errorf("error reading key from Credential Manager: no %s credential stored", credentialTarget(account))
+		}
+		return "", fmt.Errorf("error reading key from Credential Manager: %v", callErr)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func windowsCredentialSet(account, key string) error {
+	target, err := syscall.UTF16PtrFromString(credentialTarget(account))
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	blob := []byte(key)
+	cred := winCredential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if ok, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); ok == 0 {
+		return fmt.Errorf("error storing key in Credential Manager: %v", callErr)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Key rotation modes for multiple API keys.
+const (
+	// rotateFailover starts each run with the first key and stays on a key
+	// until it is rate limited.
+	rotateFailover = "failover"
+	// rotateRoundRobin spreads requests over the keys, starting at a random
+	// key each run, and also moves on when a key is rate limited.
+	rotateRoundRobin = "round-robin"
+)
+
+// keyPool holds several API keys. It is shared by all requests of a run,
+// which may be concurrent in server mode.
+type keyPool struct {
+	keys    []string
+	mode    string
+	verbose bool
+
+	mu   sync.Mutex
+	next int
+}
+
+func newKeyPool(keys []string, mode string, verbose bool) (*keyPool, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no API keys configured")
+	}
+	p := &keyPool{keys: keys, mode: mode, verbose: verbose}
+	switch mode {
+	case "", rotateFailover:
+		p.mode = rotateFailover
+	case rotateRoundRobin:
+		p.next = rand.Intn(len(keys))
+	default:
+		return nil, fmt.Errorf("unknown key rotation %q (use %s or %s)", mode, rotateFailover, rotateRoundRobin)
+	}
+	return p, nil
+}
+
+// readKeyFile reads API keys from a file, one per line. Blank lines and
+// lines starting with # are ignored.
+func readKeyFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %v", err)
+	}
+	var keys []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			keys = append(keys, line)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no API keys in %s", path)
+	}
+	return keys, nil
+}
+
+// maskKey identifies a key in output without revealing it.
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "…"
+	}
+	return "…" + key[len(key)-4:]
+}
+
+// send makes a request with each key in turn until one isn't rate limited.
+// The last response is returned as-is if every key is.
+func (p *keyPool) send(do func(key string) (*http.Response, error)) (*http.Response, error) {
+	p.mu.Lock()
+	start := p.next
+	p.mu.Unlock()
+	for i := range p.keys {
+		n := (start + i) % len(p.keys)
+		resp, err := do(p.keys[n])
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && i < len(p.keys)-1 {
+			resp.Body.Close()
+			if p.verbose {
+				fmt.Printf("API key %d of %d (%s) is rate limited; trying the next one\n", n+1, len(p.keys), maskKey(p.keys[n]))
+			}
+			continue
+		}
+		if p.verbose {
+			fmt.Printf("Used API key %d of %d (%s)\n", n+1, len(p.keys), maskKey(p.keys[n]))
+		}
+		p.mu.Lock()
+		p.next = n
+		if p.mode == rotateRoundRobin {
+			p.next = (n + 1) % len(p.keys)
+		}
+		p.mu.Unlock()
+		return resp, nil
+	}
+	return nil, fmt.Errorf("no API keys configured")
+}
+
+// keyPool returns the pool of API keys from keyFile or the config, or nil
+// to use a single key. A profile with its own key source ignores the
+// top-level list, so its requests can't go out with another account's key.
+func (c *userConfig) keyPool(keyFile string, verbose bool) (*keyPool, error) {
+	keys := c.APIKeys
+	if c.active != nil && (c.active.APIKeyEnv != "" || c.active.KeyringAccount != "") {
+		keys = nil
+	}
+	if keyFile != "" {
+		var err error
+		if keys, err = readKeyFile(keyFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return newKeyPool(keys, c.KeyRotation, verbose)
+}
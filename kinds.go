@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// commitKind is a user-defined kind of commit, such as "release" or
+// "hotfix", with its own instructions and requirements.
+type commitKind struct {
+	// Prompt is added to the request for this kind of commit.
+	Prompt string `json:"prompt,omitempty"`
+	// Fields are footer lines ("Incident: INC-42") the message must carry.
+	// Their values are asked for before generation.
+	Fields []kindField `json:"fields,omitempty"`
+	// Lint are patterns the final message must match.
+	Lint []kindRule `json:"lint,omitempty"`
+	// Branches are glob patterns (hotfix/*) that select the kind when
+	// -kind isn't given.
+	Branches []string `json:"branches,omitempty"`
+}
+
+// kindField is a required footer field.
+type kindField struct {
+	Name string `json:"name"`
+	// Pattern, when set, is a regular expression the value must match.
+	Pattern string `json:"pattern,omitempty"`
+	// Question is asked for the value; the default names the field.
+	Question string `json:"question,omitempty"`
+}
+
+// kindRule is a pattern the message must match, and what to say if not.
+type kindRule struct {
+	Pattern string `json:"pattern"`
+	Message string `json:"message,omitempty"`
+}
+
+// activeKind is the kind selected for a run and the footer values given.
+type activeKind struct {
+	Name   string
+	Reason string
+	*commitKind
+	Values map[string]string
+}
+
+// selectKind picks the kind named by -kind, or the first one (by name)
+// with a branch pattern matching the current branch. It returns nil when
+// no kind applies.
+func selectKind(kinds map[string]*commitKind, name, branch string) (*activeKind, error) {
+	if name != "" {
+		k, ok := kinds[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown kind %q", name)
+		}
+		return &activeKind{Name: name, Reason: "-kind flag", commitKind: k}, nil
+	}
+	if branch == "" {
+		return nil, nil
+	}
+	names := make([]string, 0, len(kinds))
+	for n := range kinds {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		for _, pattern := range kinds[n].Branches {
+			if ok, _ := path.Match(pattern, branch); ok {
+				return &activeKind{Name: n, Reason: fmt.Sprintf("branch %s matches %s", branch, pattern), commitKind: kinds[n]}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// validate checks that the kind's patterns compile, so mistakes in the
+// config are reported before any work is done.
+func (k *commitKind) validate(name string) error {
+	for _, f := range k.Fields {
+		if f.Name == "" {
+			return fmt.Errorf("kind %s has a field without a name", name)
+		}
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			return fmt.Errorf("kind %s, field %s: bad pattern: %v", name, f.Name, err)
+		}
+	}
+	for _, r := range k.Lint {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("kind %s: bad lint pattern %q: %v", name, r.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// askFields asks for each required field's value until it is valid. The
+// run can't go on without them; the abort phrases still work.
+func (k *activeKind) askFields() {
+	k.Values = map[string]string{}
+	for _, f := range k.Fields {
+		question := f.Question
+		if question == "" {
+			question = fmt.Sprintf("%s (required for %s commits)", f.Name, k.Name)
+		}
+		re := regexp.MustCompile(f.Pattern)
+		for {
+			value := getUserInput(question + ": ")
+			if value == "" {
+				fmt.Printf("A %s commit needs %s.\n", k.Name, f.Name)
+				continue
+			}
+			if !re.MatchString(value) {
+				fmt.Printf("%q doesn't look like a valid %s (expected to match %s).\n", value, f.Name, f.Pattern)
+				continue
+			}
+			k.Values[f.Name] = value
+			break
+		}
+	}
+}
+
+func (k *activeKind) footers() []string {
+	var lines []string
+	for _, f := range k.Fields {
+		lines = append(lines, f.Name+": "+k.Values[f.Name])
+	}
+	return lines
+}
+
+func (k *activeKind) prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "This is a %s commit.", k.Name)
+	if k.Prompt != "" {
+		b.WriteString(" " + k.Prompt)
+	}
+	if footers := k.footers(); len(footers) > 0 {
+		b.WriteString("\nEnd the message with these footer lines, exactly as given:\n" + strings.Join(footers, "\n"))
+	}
+	return b.String()
+}
+
+// footerRe matches a footer line in git trailer form.
+var footerRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*: `)
+
+// addFooters appends the required footer lines the message doesn't have
+// yet, joining an existing footer block when there is one.
+func (k *activeKind) addFooters(message string) string {
+	message = strings.TrimRight(message, "\n")
+	var missing []string
+	for _, line := range k.footers() {
+		if !strings.Contains("\n"+message+"\n", "\n"+line+"\n") {
+			missing = append(missing, line)
+		}
+	}
+	if len(missing) == 0 {
+		return message
+	}
+	paragraphs := strings.Split(message, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	inFooter := len(paragraphs) > 1
+	for _, line := range strings.Split(last, "\n") {
+		if !footerRe.MatchString(line) {
+			inFooter = false
+		}
+	}
+	if inFooter {
+		return message + "\n" + strings.Join(missing, "\n")
+	}
+	return appendToBody(message, strings.Join(missing, "\n"))
+}
+
+// check is lint as a messageCheck, for the message with its footers added.
+func (k *activeKind) check(message string) []string {
+	return k.lint(k.addFooters(message))
+}
+
+// lint returns what the message lacks for this kind.
+func (k *activeKind) lint(message string) []string {
+	var problems []string
+	for _, r := range k.Lint {
+		if !regexp.MustCompile(r.Pattern).MatchString(message) {
+			msg := r.Message
+			if msg == "" {
+				msg = fmt.Sprintf("the message must match %s", r.Pattern)
+			}
+			problems = append(problems, msg)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestKindCheck(t *testing.T) {
+	k := &activeKind{Name: "hotfix", commitKind: &commitKind{
+		Fields: []kindField{{Name: "Incident"}},
+		Lint: []kindRule{
+			{Pattern: `(?m)^Incident: INC-\d+$`, Message: "name the incident"},
+			{Pattern: `(?i)rollback`},
+		},
+	}, Values: map[string]string{"Incident": "INC-42"}}
+
+	if problems := k.check("Fix the parser\n\nRollback: revert this commit."); len(problems) != 0 {
+		t.Errorf("with the footer added: %q", problems)
+	}
+	problems := k.check("Fix the parser")
+	if len(problems) != 1 || problems[0] != "the message must match (?i)rollback" {
+		t.Errorf("check = %q, want the rollback rule's default message", problems)
+	}
+	k.Values["Incident"] = "unknown"
+	if problems := k.check("Fix the parser\n\nRollback: revert."); len(problems) != 1 || problems[0] != "name the incident" {
+		t.Errorf("check = %q, want the incident rule's message", problems)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// languageNames spell out common language codes for the prompt.
+var languageNames = map[string]string{
+	"de": "German", "en": "English", "es": "Spanish", "fr": "French",
+	"it": "Italian", "ja": "Japanese", "ko": "Korean", "nl": "Dutch",
+	"pl": "Polish", "pt": "Portuguese", "ru": "Russian", "sv": "Swedish",
+	"tr": "Turkish", "uk": "Ukrainian", "zh": "Chinese",
+}
+
+// resolveMessageLanguage finds the language commit messages are written
+// in, and where it was set: -lang, then gitcommit.lang in git config (so
+// a repository can set it in .git/config), then lang in the repository's
+// .gitcommit.toml. An empty result leaves the language to the model.
+func resolveMessageLanguage(flagValue string) (lang, source string, err error) {
+	if flagValue != "" {
+		return flagValue, "-lang flag", nil
+	}
+	if v, ok := loadGitConfig()["gitcommit.lang"]; ok && v.Value != "" {
+		return v.Value, v.Origin, nil
+	}
+	values, p, err := readRepoFile()
+	if err != nil {
+		return "", "", err
+	}
+	if v := values["lang"]; v != "" {
+		return v, p, nil
+	}
+	return "", "", nil
+}
+
+// languagePrompt tells the model which language to write in. Codes are
+// spelled out; anything else is passed as written.
+func languagePrompt(lang string) string {
+	name := lang
+	if n, ok := languageNames[languageCode(lang)]; ok && !strings.Contains(lang, " ") {
+		name = n
+	}
+	return fmt.Sprintf("Write the commit message in %s, whatever language the changes or my message are in.", name)
+}
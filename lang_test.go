@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveMessageLanguage(t *testing.T) {
+	dir := testRepo(t)
+
+	if lang, source, err := resolveMessageLanguage(""); err != nil || lang != "" || source != "" {
+		t.Errorf("without settings: %q from %q, %v", lang, source, err)
+	}
+
+	writeFile(t, repoFileName, "# team settings\nlang = \"de\"\n")
+	lang, source, err := resolveMessageLanguage("")
+	if err != nil || lang != "de" || source != filepath.Join(dir, repoFileName) {
+		t.Errorf("from %s: %q from %q, %v", repoFileName, lang, source, err)
+	}
+
+	// git config wins over the repository file.
+	mustGit(t, "config", "gitcommit.lang", "ja")
+	resetGitConfigCache()
+	lang, source, err = resolveMessageLanguage("")
+	if err != nil || lang != "ja" || !strings.Contains(source, "config") {
+		t.Errorf("from git config: %q from %q, %v", lang, source, err)
+	}
+
+	// and -lang over both.
+	lang, source, err = resolveMessageLanguage("fr")
+	if err != nil || lang != "fr" || source != "-lang flag" {
+		t.Errorf("from -lang: %q from %q, %v", lang, source, err)
+	}
+}
+
+func TestResolveMessageLanguageBadRepoFile(t *testing.T) {
+	testRepo(t)
+	writeFile(t, repoFileName, "lang = \"de\n")
+	if _, _, err := resolveMessageLanguage(""); err == nil || !strings.Contains(err.Error(), repoFileName) {
+		t.Errorf("a malformed %s gave %v", repoFileName, err)
+	}
+}
+
+func TestLanguagePrompt(t *testing.T) {
+	tests := map[string]string{
+		"de":          "German",
+		"pt-BR":       "Portuguese",
+		"Old English": "Old English",
+	}
+	for lang, want := range tests {
+		if got := languagePrompt(lang); !strings.Contains(got, " in "+want+",") {
+			t.Errorf("languagePrompt(%q) = %q, want %s", lang, got, want)
+		}
+	}
+}
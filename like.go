@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// likeSearchCommits is how far back -like auto looks.
+	likeSearchCommits = 200
+	// minLikeScore is how closely (0-1) a commit's files must match the
+	// staged ones for -like auto to offer its message.
+	minLikeScore = 0.75
+)
+
+// likeCommit is an earlier commit whose message can be reused.
+type likeCommit struct {
+	Sha     string
+	Message string
+	// Auto is set for a commit found by -like auto, and Score is how
+	// closely its files match the staged ones.
+	Auto  bool
+	Score float64
+}
+
+// loadLike reads the message of the commit -like names.
+func loadLike(rev string) (*likeCommit, error) {
+	sha, err := gitRun("rev-parse", "--verify", "--quiet", "--short", rev+"^{commit}")
+	if err != nil || sha == "" {
+		return nil, fmt.Errorf("-like: %s is not a commit", rev)
+	}
+	message, err := gitRun("-c", "i18n.logOutputEncoding=UTF-8", "log", "-1", "--format=%B", sha)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the message of %s: %v", sha, err)
+	}
+	return &likeCommit{Sha: sha, Message: message}, nil
+}
+
+// fileSetSimilarity is the Jaccard index of two file sets: the files both
+// touch over the files either touches.
+func fileSetSimilarity(a, b []string) float64 {
+	set := map[string]bool{}
+	for _, p := range a {
+		set[p] = true
+	}
+	union, shared := len(set), 0
+	seen := map[string]bool{}
+	for _, p := range b {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if set[p] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// parseLogFiles reads `git log --format=%x1e%h --name-only` output into
+// each commit's short sha and changed files, newest first.
+func parseLogFiles(output string) (shas []string, files [][]string) {
+	for _, record := range strings.Split(output, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		if lines[0] == "" {
+			continue
+		}
+		var paths []string
+		for _, l := range lines[1:] {
+			if l = strings.TrimSpace(l); l != "" {
+				paths = append(paths, l)
+			}
+		}
+		shas = append(shas, lines[0])
+		files = append(files, paths)
+	}
+	return shas, files
+}
+
+// findLike looks through recent history for the commit whose changed
+// files best match paths, as recurring changes (a monthly dependency
+// bump, regenerated docs) do. Ties go to the most recent. It returns nil
+// when no commit matches at least minLikeScore.
+func findLike(paths []string) (*likeCommit, error) {
+	if len(paths) == 0 || !hasHead() {
+		return nil, nil
+	}
+	output, err := gitRun("log", fmt.Sprintf("-n%d", likeSearchCommits), "--no-merges", "--format=%x1e%h", "--name-only")
+	if err != nil {
+		return nil, fmt.Errorf("error searching history: %v", err)
+	}
+	shas, files := parseLogFiles(output)
+	best, bestScore := "", 0.0
+	for i, sha := range shas {
+		if score := fileSetSimilarity(paths, files[i]); score > bestScore {
+			best, bestScore = sha, score
+		}
+	}
+	if bestScore < minLikeScore {
+		return nil, nil
+	}
+	c, err := loadLike(best)
+	if err != nil {
+		return nil, err
+	}
+	c.Auto, c.Score = true, bestScore
+	return c, nil
+}
+
+// likeChoice is what to do with a recycled message.
+type likeChoice int
+
+const (
+	likeGenerate likeChoice = iota // ignore it
+	likeImprove                    // use it as the seed
+	likeVerbatim                   // commit it as-is, without the API
+)
+
+// offerLike shows a recycled message and asks what to do with it.
+func offerLike(c *likeCommit) likeChoice {
+	if c.Auto {
+		fmt.Printf("\nA recent commit, %s, changed the same files (%.0f%% match). Its message:\n%s\n", c.Sha, c.Score*100, c.Message)
+	} else {
+		fmt.Printf("\nMessage of %s:\n%s\n", c.Sha, c.Message)
+	}
+	switch getUserInput("Reuse it? (y to commit as-is / i to improve it / n to write a new one): ") {
+	case "y":
+		return likeVerbatim
+	case "i":
+		return likeImprove
+	}
+	return likeGenerate
+}
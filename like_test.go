@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFileSetSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want float64
+	}{
+		{[]string{"go.mod", "go.sum"}, []string{"go.sum", "go.mod"}, 1},
+		{[]string{"go.mod", "go.sum"}, []string{"go.mod", "go.sum", "vendor/modules.txt"}, 2.0 / 3},
+		{[]string{"go.mod"}, []string{"main.go"}, 0},
+		{[]string{"a", "b", "c", "d"}, []string{"a", "b", "c", "e"}, 3.0 / 5},
+		{[]string{"a", "a", "b"}, []string{"b", "a", "a"}, 1},
+		{nil, []string{"a"}, 0},
+		{nil, nil, 0},
+	}
+	for _, tt := range tests {
+		got := fileSetSimilarity(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("fileSetSimilarity(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+		if back := fileSetSimilarity(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+			t.Errorf("fileSetSimilarity is not symmetric for %q and %q: %.3f and %.3f", tt.a, tt.b, got, back)
+		}
+	}
+}
+
+func TestParseLogFiles(t *testing.T) {
+	output := "\x1eabc1234\n\ngo.mod\ngo.sum\n\x1edef5678\n\n\x1e0123abc\n\ndocs/a.md\n"
+	shas, files := parseLogFiles(output)
+	if strings.Join(shas, " ") != "abc1234 def5678 0123abc" {
+		t.Fatalf("shas = %q", shas)
+	}
+	if len(files) != 3 || strings.Join(files[0], " ") != "go.mod go.sum" || len(files[1]) != 0 || strings.Join(files[2], " ") != "docs/a.md" {
+		t.Errorf("files = %q", files)
+	}
+}
+
+// likeHistory is a history with a monthly dependency bump and regenerated
+// docs among ordinary commits.
+func likeHistory(t *testing.T) (bump, docs string) {
+	t.Helper()
+	testRepo(t)
+	commitFile(t, "main.go", "package main\n", "Add main")
+	writeFile(t, "go.mod", "module demo\n\nrequire x v1.0.0\n")
+	writeFile(t, "go.sum", "x v1.0.0 h1:a=\n")
+	mustGit(t, "add", ".")
+	mustGit(t, "commit", "-q", "-m", "Bump dependencies for March\n\ngo get -u ./...")
+	writeFile(t, "docs/cli.md", "# CLI\n")
+	writeFile(t, "docs/config.md", "# Config\n")
+	mustGit(t, "add", ".")
+	mustGit(t, "commit", "-q", "-m", "Regenerate the docs")
+	commitFile(t, "main.go", "package main\n\nfunc main() {}\n", "Add an empty main")
+	writeFile(t, "go.mod", "module demo\n\nrequire x v1.1.0\n")
+	writeFile(t, "go.sum", "x v1.1.0 h1:b=\n")
+	mustGit(t, "add", ".")
+	mustGit(t, "commit", "-q", "-m", "Bump dependencies for April\n\ngo get -u ./...")
+	bump = mustGit(t, "rev-parse", "--short", "HEAD")
+	docs = mustGit(t, "rev-parse", "--short", "HEAD~2")
+	return bump, docs
+}
+
+func TestFindLike(t *testing.T) {
+	bump, docs := likeHistory(t)
+	tests := []struct {
+		name   string
+		staged []string
+		want   string
+	}{
+		{"dependency bump matches the latest bump", []string{"go.mod", "go.sum"}, bump},
+		{"docs match the regeneration", []string{"docs/config.md", "docs/cli.md"}, docs},
+		{"docs with one new page", []string{"docs/cli.md", "docs/config.md", "docs/serve.md", "docs/hooks.md"}, ""},
+		{"code change matches nothing", []string{"main.go", "server.go"}, ""},
+		{"nothing staged", nil, ""},
+	}
+	for _, tt := range tests {
+		c, err := findLike(tt.staged)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		switch {
+		case tt.want == "" && c != nil:
+			t.Errorf("%s: offered %s (%.2f)", tt.name, c.Sha, c.Score)
+		case tt.want != "" && (c == nil || c.Sha != tt.want || !c.Auto):
+			t.Errorf("%s: got %+v, want %s", tt.name, c, tt.want)
+		}
+	}
+
+	c, err := findLike([]string{"go.mod", "go.sum"})
+	if err != nil || c.Message != "Bump dependencies for April\n\ngo get -u ./..." || c.Score != 1 {
+		t.Errorf("the bump's message = %+v, %v", c, err)
+	}
+}
+
+func TestLoadLike(t *testing.T) {
+	likeHistory(t)
+	c, err := loadLike("HEAD~2")
+	if err != nil || c.Message != "Regenerate the docs" || c.Auto {
+		t.Errorf("loadLike(HEAD~2) = %+v, %v", c, err)
+	}
+	if _, err := loadLike("HEAD:go.mod"); err == nil {
+		t.Error("loadLike accepted a blob")
+	}
+}
+
+func TestOfferLike(t *testing.T) {
+	defer func(old bool) { assumeYes = old }(assumeYes)
+	assumeYes = false
+	c := &likeCommit{Sha: "abc1234", Message: "Bump dependencies"}
+	for answer, want := range map[string]likeChoice{"y": likeVerbatim, "i": likeImprove, "n": likeGenerate, "": likeGenerate} {
+		withInput(t, answer+"\n")
+		if got := offerLike(c); got != want {
+			t.Errorf("answer %q: got %v, want %v", answer, got, want)
+		}
+	}
+}
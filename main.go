@@ -5,13 +5,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"regexp"
+	"runtime"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type Message struct {
@@ -20,47 +25,142 @@ type Message struct {
 }
 
 type MessagesRequest struct {
-	Model     string    `json:"model"`
-	System    string    `json:"system"`
-	Messages  []Message `json:"messages"`
-	MaxTokens int       `json:"max_tokens"`
+	Model       string      `json:"model"`
+	System      string      `json:"system"`
+	Messages    []Message   `json:"messages"`
+	MaxTokens   int         `json:"max_tokens"`
+	Temperature *float64    `json:"temperature,omitempty"`
+	Stream      bool        `json:"stream,omitempty"`
+	Tools       []Tool      `json:"tools,omitempty"`
+	ToolChoice  *ToolChoice `json:"tool_choice,omitempty"`
+}
+
+// Tool is a tool the model may call, with a JSON schema for its input.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 type ContentBlock struct {
+	Type string `json:"type"`
 	Text string `json:"text"`
+	// Input is a tool_use block's arguments.
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 type MessagesResponse struct {
 	Content []ContentBlock `json:"content"`
+	Usage   Usage          `json:"usage"`
 }
 
-func getDiff(all bool) (string, error) {
-	args := []string{"diff"}
-	if !all {
-		args = append(args, "--cached")
+const claudeModel = "claude-3-5-sonnet-20240620"
+
+const defaultAPIURL = "https://api.anthropic.com/v1/messages"
+
+var apiURL = defaultAPIURL
+
+// systemPromptFor returns the system prompt with any per-run additions.
+func systemPromptFor(opts apiOptions) string {
+	system := activePrompt.System
+	if opts.System != "" {
+		system = opts.System
+	}
+	if section := prefsPrompt(opts.Prefs); section != "" {
+		return system + "\n\n" + section
 	}
-	cmd := exec.Command("git", args...)
-	output, err := cmd.Output()
+	return system
+}
+
+// apiOptions carries per-run tuning for requests to the API.
+type apiOptions struct {
+	// Temperature is sent when non-nil; otherwise the API default is used.
+	Temperature *float64
+	// Prefs are the user's standing instructions for the system prompt.
+	Prefs []string
+	// Usage, when set, accumulates token usage across requests.
+	Usage *usageTotals
+	// System replaces the default system prompt when set.
+	System string
+	// History holds earlier turns of the conversation, sent before the prompt.
+	History []Message
+	// Stream prints the response as it arrives.
+	Stream bool
+	// Deadline bounds the whole request, including generation; zero means
+	// no limit.
+	Deadline time.Duration
+	// ConnectTimeout bounds connecting and the TLS handshake only.
+	ConnectTimeout time.Duration
+	// Keys, when set, replaces the single API key with several that are
+	// failed over or rotated between.
+	Keys *keyPool
+	// MaxTokens caps the response length; zero means the default of 4096.
+	MaxTokens int
+	// LowBandwidth compresses every request, however small.
+	LowBandwidth bool
+	// Verbose reports request sizes and compression fallbacks.
+	Verbose bool
+	// Tool, when set, is a tool the model must call. The response is not
+	// streamed and askClaude returns the tool's input JSON instead of text.
+	Tool *Tool
+	// EditKey lets a streamed response be stopped with editKey, which
+	// makes askClaude return errStreamStopped.
+	EditKey bool
+}
+
+// usageTotals is the token usage of every request made during a run.
+type usageTotals struct {
+	Requests     int
+	InputTokens  int
+	OutputTokens int
+}
+
+// getDiff returns the diff, leaving out the files exclude names (see
+// excludePathspecs). It reads at most maxDiffReadBytes; truncated reports
+// that the rest was dropped.
+func getDiff(all bool, exclude []string) (diff string, truncated bool, err error) {
+	diff, truncated, err = readCapped(gitCommand(diffArgs(all, exclude...)...), maxDiffReadBytes)
 	if err != nil {
-		return "", fmt.Errorf("error getting diff: %v", err)
+		return "", false, fmt.Errorf("error getting diff: %v", err)
 	}
-	return string(output), nil
+	return diff, truncated, nil
 }
 
-func askClaude(prompt string, apiKey string) (string, error) {
-	ctx := context.Background()
-	claudeModel := "claude-3-5-sonnet-20240620"
-	systemPrompt := `You are a Git commit message assistant. If you need more context, ask exactly one clear question. 
-If you have enough context, provide ONLY the commit message without any explanations or questions. 
-The commit message should follow best practices and be wrapped in triple backticks.`
+func askClaude(prompt string, apiKey string, opts apiOptions) (string, error) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	if opts.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Deadline)
+		defer cancel()
+	}
 
+	maxTokens := opts.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 4096
+	}
 	reqBody := MessagesRequest{
-		Model:  claudeModel,
-		System: systemPrompt,
-		Messages: []Message{
-			{Role: "user", Content: prompt},
-		},
-		MaxTokens: 4096,
+		Model:       claudeModel,
+		System:      systemPromptFor(opts),
+		Messages:    append(append([]Message{}, opts.History...), Message{Role: "user", Content: prompt}),
+		MaxTokens:   maxTokens,
+		Temperature: opts.Temperature,
+		Stream:      opts.Stream,
+	}
+	if opts.Tool != nil {
+		reqBody.Tools = []Tool{*opts.Tool}
+		reqBody.ToolChoice = &ToolChoice{Type: "tool", Name: opts.Tool.Name}
+		reqBody.Stream = false
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -68,19 +168,58 @@ The commit message should follow best practices and be wrapped in triple backtic
 		return "", fmt.Errorf("error marshaling request: %v", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("error creating request: %v", err)
+	if apiServer != nil && !reqBody.Stream && opts.Tool == nil {
+		text, err := completeViaServer(reqBody, opts)
+		if !errors.Is(err, errServerUnavailable) {
+			return text, err
+		}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
+	client := apiClient(opts.ConnectTimeout)
+	compressed := compressRequest(jsonBody, opts)
+	var send func(key string) (*http.Response, error)
+	send = func(key string) (*http.Response, error) {
+		body := jsonBody
+		if compressed != nil {
+			body = compressed
+		}
+		req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(body))
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %v", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", key)
+		req.Header.Set("anthropic-version", "2023-06-01")
+		if compressed != nil {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, requestError(ctx, opts.Deadline, err)
+		}
+		if compressed != nil && rejectsCompression(resp.StatusCode) {
+			// Retry once uncompressed; a genuinely bad request fails again
+			// and is reported as usual.
+			resp.Body.Close()
+			markCompressionRejected(apiURL)
+			compressed = nil
+			if opts.Verbose {
+				fmt.Printf("The API answered %s to a compressed request; retrying uncompressed\n", resp.Status)
+			}
+			return send(key)
+		}
+		return resp, nil
+	}
+	var resp *http.Response
+	if opts.Keys != nil {
+		resp, err = opts.Keys.send(send)
+	} else {
+		resp, err = send(apiKey)
+	}
 	if err != nil {
-		return "", fmt.Errorf("error making request: %v", err)
+		return "", err
 	}
 	defer resp.Body.Close()
 
@@ -88,56 +227,238 @@ The commit message should follow best practices and be wrapped in triple backtic
 		body, _ := io.ReadAll(resp.Body)
 		return "", fmt.Errorf("API error: %s - %s", resp.Status, string(body))
 	}
+	if reqBody.Stream {
+		var keys *keyWatcher
+		if opts.EditKey {
+			if keys = watchEditKey(stop); keys != nil {
+				fmt.Println(editKeyHint)
+			}
+		}
+		text, err := readStream(resp.Body, terminalOutput(), opts.Usage)
+		if keys.close() {
+			return "", &errStreamStopped{Text: text}
+		}
+		if err != nil {
+			return "", requestError(ctx, opts.Deadline, err)
+		}
+		return text, nil
+	}
 
 	var result MessagesResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return "", fmt.Errorf("error decoding response: %v", err)
 	}
+	if opts.Usage != nil {
+		opts.Usage.Requests++
+		opts.Usage.InputTokens += result.Usage.InputTokens
+		opts.Usage.OutputTokens += result.Usage.OutputTokens
+	}
 
+	if opts.Tool != nil {
+		for _, block := range result.Content {
+			if block.Type == "tool_use" && len(block.Input) > 0 {
+				return string(block.Input), nil
+			}
+		}
+		return "", fmt.Errorf("the response didn't call the %s tool", opts.Tool.Name)
+	}
 	if len(result.Content) == 0 {
 		return "", fmt.Errorf("empty response from API")
 	}
 	return result.Content[0].Text, nil
 }
 
+// abortPhrases, typed alone at any prompt, end the run without committing.
+// They can be changed with "abort_phrases" in the user config.
+var abortPhrases = []string{":q", "abort"}
+
+// atAbort holds cleanups, such as sending metrics, that must still run when
+// the user aborts from a prompt.
+var atAbort []func()
+
+// stdinReader is shared by every prompt, so answers piped in together
+// aren't lost to a reader that buffered ahead.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// assumeYes is set by -yes: yes/no questions are answered y without
+// reading stdin.
+var assumeYes bool
+
+// yesNoRe matches the answer list of a yes/no question, such as "(y/n)"
+// or "(y to commit / ...", in English or translated.
+var yesNoRe = regexp.MustCompile(`\(y[\s/)]`)
+
+// maxYesRepeats is how often -yes answers the same question before
+// concluding it is going around in circles.
+const maxYesRepeats = 3
+
+var yesAnswered = map[string]int{}
+
+// getUserInput prompts and returns the trimmed answer. Constant prompts
+// are translated here; formatted ones go through ask. With -yes, yes/no
+// questions are answered y. When stdin runs out, the run stops rather
+// than reading nothing forever: with -yes the remaining text prompts take
+// their empty default instead.
 func getUserInput(prompt string) string {
-	reader := bufio.NewReader(os.Stdin)
+	prompt = tr(prompt)
 	fmt.Print(prompt)
-	input, _ := reader.ReadString('\n')
-	return strings.TrimSpace(input)
+	if assumeYes && yesNoRe.MatchString(prompt) {
+		if yesAnswered[prompt]++; yesAnswered[prompt] > maxYesRepeats {
+			say("\nError: -yes keeps being asked the same question; stopping.\n")
+			abortRun()
+		}
+		fmt.Println("y")
+		return "y"
+	}
+	input, err := stdinReader.ReadString('\n')
+	if err != nil && input == "" {
+		if assumeYes {
+			fmt.Println()
+			return ""
+		}
+		if stdinIsTerminal() {
+			say("\nError: end of input.\n")
+		} else {
+			say("\nError: stdin ended before this question was answered. Run gitcommit in a terminal, or pass -yes to answer yes/no questions with y.\n")
+		}
+		abortRun()
+	}
+	input = strings.TrimSpace(input)
+	for _, phrase := range abortPhrases {
+		if input == phrase {
+			abortRun()
+		}
+	}
+	return input
 }
 
+// abortRun runs the registered cleanups and exits without committing.
+func abortRun() {
+	for i := len(atAbort) - 1; i >= 0; i-- {
+		atAbort[i]()
+	}
+	say("Aborted; nothing was committed.\n")
+	os.Exit(1)
+}
+
+// printRawResponse shows a response as it came back, for -raw-response.
+func printRawResponse(response string) {
+	fmt.Println("\n----- Full response -----")
+	fmt.Fprintln(terminalOutput(), strings.TrimRight(response, "\n"))
+	fmt.Println("----- End of response -----")
+}
+
+// extractCommitMessage returns the contents of the first fenced block in
+// response. Fences nested inside the message (e.g. a code sample opened with
+// "```go") are kept as part of the message: a fence line with an info string
+// opens a nested block and the next bare fence closes it.
 func extractCommitMessage(response string) string {
-	start := strings.Index(response, "```")
+	lines := strings.Split(strings.ReplaceAll(response, "\r\n", "\n"), "\n")
+	start := -1
+	for i, line := range lines {
+		trimmed := trimInvisible(line)
+		if !strings.HasPrefix(trimmed, "```") {
+			continue
+		}
+		// A single-line block: ```message```
+		if inner := strings.TrimPrefix(trimmed, "```"); strings.HasSuffix(inner, "```") && len(inner) > 3 {
+			return strings.TrimSpace(strings.TrimSuffix(inner, "```"))
+		}
+		start = i
+		break
+	}
 	if start == -1 {
 		return ""
 	}
-	end := strings.Index(response[start+3:], "```")
-	if end == -1 {
-		return ""
+
+	depth := 0
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := trimInvisible(lines[i])
+		if !strings.HasPrefix(trimmed, "```") {
+			continue
+		}
+		if strings.Trim(trimmed, "`") != "" {
+			depth++
+			continue
+		}
+		if depth > 0 {
+			depth--
+			continue
+		}
+		return strings.TrimSpace(strings.Join(lines[start+1:i], "\n"))
+	}
+	return ""
+}
+
+func editInEditor(message string) (string, error) {
+	editedStr, err := runEditor(message)
+	if err != nil {
+		return "", err
+	}
+	if editedStr == message {
+		confirm := getUserInput("No changes made. Use original message? (y/n): ")
+		if confirm != "y" {
+			return "", fmt.Errorf("edit cancelled")
+		}
+	}
+
+	return editedStr, nil
+}
+
+// editorCommand returns the editor to run, looked up the way git does:
+// $GIT_EDITOR, core.editor, $VISUAL, then $EDITOR, else vim (notepad on
+// Windows). The value is split into the program and its arguments so that
+// one such as "code --wait" works; source says where it came from.
+func editorCommand() (editor []string, source string) {
+	if fields := strings.Fields(os.Getenv("GIT_EDITOR")); len(fields) > 0 {
+		return fields, "$GIT_EDITOR"
+	}
+	if value, err := gitRun("config", "core.editor"); err == nil {
+		if fields := strings.Fields(value); len(fields) > 0 {
+			return fields, "core.editor"
+		}
+	}
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields, "$" + name
+		}
 	}
-	return strings.TrimSpace(response[start+3 : start+3+end])
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}, "the default"
+	}
+	return []string{"vim"}, "the default"
 }
 
-func editInVim(message string) (string, error) {
+// runEditor opens content in the editor and returns the saved result.
+func runEditor(content string) (string, error) {
 	tempFile, err := os.CreateTemp("", "commit-msg-*.txt")
 	if err != nil {
 		return "", fmt.Errorf("error creating temp file: %v", err)
 	}
 	defer os.Remove(tempFile.Name())
 
-	if _, err := tempFile.WriteString(message); err != nil {
+	if _, err := tempFile.WriteString(content); err != nil {
 		return "", fmt.Errorf("error writing to temp file: %v", err)
 	}
 	tempFile.Close()
 
-	cmd := exec.Command("vim", tempFile.Name())
+	if !fullScreenSupported() {
+		fmt.Println("\nThis terminal (TERM=dumb) can't run a full-screen editor, so enter the text here instead.")
+		return readLinesFallback(content)
+	}
+	editor, source := editorCommand()
+	// The file goes last, after any arguments the editor was given.
+	cmd := exec.Command(editor[0], append(editor[1:], tempFile.Name())...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("error running vim: %v", err)
+	if err := cmd.Start(); err != nil {
+		fmt.Printf("\nCould not start the editor %s, from %s (%v), so enter the text here instead.\n", editor[0], source, err)
+		return readLinesFallback(content)
+	}
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("error running %s: %v", editor[0], err)
 	}
 
 	editedContent, err := os.ReadFile(tempFile.Name())
@@ -145,22 +466,456 @@ func editInVim(message string) (string, error) {
 		return "", fmt.Errorf("error reading edited file: %v", err)
 	}
 
-	editedStr := string(editedContent)
-	if editedStr == message {
-		confirm := getUserInput("No changes made. Use original message? (y/n): ")
-		if confirm != "y" {
-			return "", fmt.Errorf("edit cancelled")
+	// Some editors, mostly on Windows, save UTF-8 with a byte order mark,
+	// which would otherwise end up invisibly at the start of the subject.
+	return strings.TrimPrefix(string(editedContent), "\uFEFF"), nil
+}
+
+// inputSentinel ends text typed at the terminal when no editor is available.
+const inputSentinel = "."
+
+// readLinesFallback reads text from stdin, line by line, until a line
+// holding only inputSentinel. It shows the text that would have been
+// edited first; entering nothing keeps it unchanged.
+func readLinesFallback(content string) (string, error) {
+	if strings.TrimSpace(content) != "" {
+		fmt.Printf("Current text:\n%s\n", strings.TrimRight(content, "\n"))
+	}
+	fmt.Printf("Type the new text and end it with a line containing only %q (just %q keeps the current text):\n", inputSentinel, inputSentinel)
+	var lines []string
+	for {
+		line, err := stdinReader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == inputSentinel {
+			break
 		}
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("error reading input: %v", err)
+		}
+		if err == nil || line != "" {
+			lines = append(lines, line)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if len(lines) == 0 {
+		return content, nil
 	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
 
-	return editedStr, nil
+// editAnswerTrigger, typed as the answer to a question, opens the editor to
+// compose a longer answer.
+const editAnswerTrigger = ":edit"
+
+// editAnswer opens the editor with the question shown as comments and
+// returns the answer with the comments removed.
+func editAnswer(question string) (string, error) {
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString(validationCommentPrefix() + "Write your answer above. Lines starting with \"" + validationCommentPrefix() + "\" are removed.\n")
+	for _, line := range strings.Split(strings.TrimSpace(question), "\n") {
+		b.WriteString(validationCommentPrefix() + line + "\n")
+	}
+	edited, err := runEditor(b.String())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stripValidationComments(edited)), nil
+}
+
+// unstagedChanges formats the staged diff together with the unstaged diff,
+// clearly labeled so the message describes only what will actually be
+// committed.
+func unstagedChanges(staged, unstaged string) string {
+	return fmt.Sprintf(`Only the STAGED changes below will be committed. Describe ONLY the staged
+changes in the commit message. The unstaged changes are provided purely as
+background on the broader work in progress and must not be described as part
+of this commit.
+
+=== STAGED CHANGES (will be committed) ===
+%s
+=== UNSTAGED CHANGES (NOT part of this commit, context only) ===
+%s`, staged, unstaged)
+}
+
+// messagePrompt asks for a commit message for the given change context.
+func messagePrompt(originalMessage, changes string) string {
+	return fmt.Sprintf(activePrompt.Message, originalMessage, changes)
+}
+
+// offerReviewerNote shows a heuristic reviewer note and adds it to the end
+// of the body, ahead of any trailers, only if the user explicitly accepts it.
+func offerReviewerNote(message string, all bool) string {
+	stats, err := getNumstat(all)
+	if err != nil {
+		say("Warning: %v\n", err)
+		return message
+	}
+	note := suggestReviewerNote(stats)
+	if note == "" {
+		return message
+	}
+	fmt.Printf("\nProposed reviewer note:\n%s\n", note)
+	if getUserInput("Append it to the commit body? (y/n): ") != "y" {
+		return message
+	}
+	return insertIntoBody(message, note)
 }
 
 const helpText = `Usage: gitcommit [options]
+       gitcommit <command> [args]
+
+Commands:
+  explain         Explain the staged change (what it does, risks, anything
+                  that looks unintentional) without committing, then
+                  optionally continue to write the commit message
+  hints list      Show learned conventional type/scope hints per path
+  hints clear     Forget all learned hints
+  notes show [commit]
+                  Show the rationale note stored by -notes (default HEAD)
+  pr-description [--from-context file]... [range]
+                  Write a pull request title and description for the
+                  commits in range (default @{upstream}..HEAD), using the
+                  records written by -export-context when given
+  patch-subject <range> [file]
+                  Write a cover letter for git format-patch (default file
+                  0000-cover-letter.patch) and suggest better subjects for
+                  weak patches; history is never rewritten
+  msg-filter [--sha commit] [--rate n] [--cache file]
+                  Rewrite the commit message on stdin as a conventional
+                  commit, for git filter-branch --msg-filter, filter-repo
+                  or a rebase --exec loop; the commit defaults to
+                  $GITCOMMIT_SHA or $GIT_COMMIT, results are cached so an
+                  interrupted rewrite resumes, and on any error the
+                  original message is written unchanged
+  batch [--message text] [--style text] [--report file] [--dry-run]
+        [--no-verify] manifest
+                  Write and commit a message in each repository listed in
+                  manifest (one path per line, optionally followed by
+                  "all" to commit all tracked changes), without prompting;
+                  failures are reported per repository and the rest go on
+  prefs add "<instruction>"
+                  Add a standing instruction, appended to every system prompt
+  prefs list      Show standing instructions
+  prefs remove n  Remove standing instruction number n
+  profile show    Show the active profile and why it was chosen
+  keyring set     Store the API key in the OS keyring (macOS Keychain, the
+                  Secret Service via secret-tool, or Windows Credential
+                  Manager)
+  keyring set webhook
+                  Store the shared secret that signs webhook payloads
+  serve [--socket path] [--idle duration]
+                  Serve JSON requests for editor integrations on a unix
+                  socket, keeping the config and API connection warm
+                  (default idle shutdown 30m)
+  restore-snapshot [ref]
+                  Restore the index and work tree from the latest backup
+                  taken before gitcommit changed the index
+  demo            Try gitcommit on a throwaway repository with a mock API, no
+                  API key or config needed
+  answer "<text>" Answer the question saved in .git/GITCOMMIT_QUESTION by a
+                  run without a terminal, and finish that run
 
 Options:
-  -a        Commit all changes (including unstaged)
-  -help     Display this help message
+  -a              Commit all changes (including unstaged)
+  -max-diff-bytes n
+                  Keep the diff sent to Claude under n bytes by replacing
+                  hunks or whole files with one-line summaries (default
+                  100000, 0 for no limit)
+  -truncate-strategy importance|largest-first|path-order
+                  What to summarize when over the limit: the least
+                  important hunks, ranking code over tests, config and docs
+                  and new control flow and error handling over deletions
+                  and imports (default); the largest files, keeping small
+                  diffs whole; or every file after the budget runs out in
+                  path order
+  -show-triage    Print each hunk's score and whether it was kept,
+                  summarized or dropped (lock files) to fit the limit
+  -stash ref      Describe a stash entry (e.g. stash@{0}, or just 0) instead of
+                  the staged changes; on accept, optionally apply the stash
+                  and commit exactly its changes
+  -symbols        List the top-level declarations (func Foo, type Bar, ...)
+                  added, removed or modified in changed Go files
+  -ignore-whitespace
+                  Send the diff with whitespace-only changes hidden (-w
+                  --ignore-blank-lines) and just list the files with
+                  formatting-only changes; if nothing else changed, propose
+                  a "Reformat ..." message without calling the API. The
+                  commit itself always includes every change
+  -split-by-dir n Commit the staged changes one directory group at a time,
+                  grouping by the first n directory levels (1 for
+                  services/, 2 for services/api/). Each group gets its own
+                  message and confirmation with only its files staged;
+                  between groups, and if you abort, the index goes back to
+                  the staged changes not yet committed
+  -suggest-split  Ask Claude whether the staged changes mix unrelated work
+                  and how to split them: the files (or hunks) and message
+                  of each commit. Nothing changes unless you then choose to
+                  commit the parts one at a time
+  -with-unstaged  Also send unstaged changes, labeled, as context only
+  -no-history     Don't include recent commits touching the changed files,
+                  or the earlier commits on the branch
+  -similar-subject f
+                  Warn when the final subject is at least this alike (0-1,
+                  compared locally) to an earlier commit on the branch since
+                  its upstream, as happens in stacks (default 0.8; 0 never
+                  warns). Those subjects are also sent so Claude can tell
+                  this commit apart
+  -related-history n
+                  Also include the n latest commits (at most 20) touching
+                  any of the changed files, so the message fits the ongoing
+                  work on them
+  -explain-rejection
+                  When you reject a suggestion (n), ask for a quick reason
+                  and append it to .git/gitcommit/rejections.jsonl for later
+                  analysis. The reason is only sent to the API if you also
+                  choose to use it as feedback for the next suggestion
+  -show-delta     Show a diff between your message and each suggestion
+  -review-note    Propose a reviewer note for the body (asks before adding)
+  -temperature t  Sampling temperature between 0 and 1 (default: API default)
+  -deterministic  Use temperature 0 and the pinned model for reproducible
+                  output, e.g. in CI. The API does not guarantee fully
+                  deterministic results, but this minimizes variation.
+  -structured     Have Claude return the subject and body as JSON fields
+                  through tool use, instead of a fenced block in text to
+                  extract; the response is then not streamed
+  -stream         Print the response as it is generated. If the connection
+                  drops after a usable message has arrived, it is offered
+                  marked "(possibly truncated)" instead of being lost.
+                  Press e while it streams to stop and edit what has
+                  arrived so far
+  -timeout d      Overall deadline for each request, including generation
+                  (default 5m; 0 for none)
+  -connect-timeout d
+                  Timeout for connecting to the API, separate from -timeout
+                  so hangs are caught without limiting long generations
+                  (default 10s)
+  -low-bandwidth  For slow connections: gzip every request, keep the diff
+                  under 20000 bytes, cap responses at 1024 tokens and don't
+                  stream. Requests over 8 KB are gzipped anyway; endpoints
+                  that refuse compression (415 or 400) get one uncompressed
+                  retry and aren't sent compressed bodies again. -verbose
+                  shows raw and compressed sizes
+  -dry-run-diff   Print the exact diff and context that would be sent to the
+                  API, then exit without calling it (no API key needed)
+  -ascii-punct    Convert curly quotes and dashes in the final message to ASCII
+  -suggest-only   Never run git commit; accepting (y) only prints the final
+                  message so you can commit it yourself
+  -print-final-command
+                  Never run git commit; accepting (y) prints a ready-to-paste
+                  command that pipes the final message into git commit -F -,
+                  quoted so that every character reaches git unchanged
+  -shell sh|powershell
+                  Shell to quote -print-final-command for (default sh)
+  -retry-edit     If an edited message is empty, has an over-long subject,
+                  no blank line after the subject, or fails another of the
+                  run's requirements, re-open the editor with comments
+                  explaining the problem instead of continuing
+  -show-diff      Show the diff below a scissors line when editing a message
+                  (default: git's commit.verbose)
+  -show-status    Show git status as comments when editing a message
+                  (default: git's commit.status)
+  -cleanup mode   Pass --cleanup=mode to git commit (default: commit.cleanup)
+  -gpg-sign       Sign the commit; -gpg-sign=false disables signing
+                  (default: git's commit.gpgsign). -verbose lists each git
+                  setting gitcommit follows and where its value came from
+  -encoding name  Commit the message in this encoding (default: git's
+                  i18n.commitEncoding): UTF-8, US-ASCII, ISO-8859-1,
+                  ISO-8859-15 or Windows-1252. Characters it lacks are
+                  listed and, if you agree, replaced by "?"
+  -subject-prefix tpl
+                  Prepend tpl to the subject before the length check, with
+                  {branch} replaced by the current branch and {ticket} by
+                  the key in it (e.g. "[{ticket}] " gives "[JIRA-123] ");
+                  also "subject_prefix" in the config
+  -prefix-order o Where the prefix goes in a conventional subject:
+                  after-type ("feat: [JIRA-123] ...", the default) or
+                  before-type ("[JIRA-123] feat: ..."); also "prefix_order"
+  -kind name      Use a commit kind from "kinds" in the config: its prompt
+                  addendum, required footer fields (asked for before
+                  generation and added to the message) and lint rules. A
+                  kind is also picked when the branch matches one of its
+                  "branches" patterns
+  -sections       Write the body as named sections (e.g. What:, Why:, How:)
+                  taken from "sections" in the config or the headings in
+                  git's commit.template; a trailing "?" marks a section as
+                  optional, and missing required sections are reported
+  -conventional   Write a conventional commit subject (type(scope): ...).
+                  The type is guessed locally from the change (test-only,
+                  docs, dependency files, new exported Go symbols, "fix"
+                  or an issue ref in your message) and shown with its
+                  confidence next to each suggestion; when the guess is
+                  unsure, you are asked which type to use
+  -lang l         Write the commit message in language l (a code such as
+                  de or a name such as Japanese); defaults to gitcommit.lang
+                  in git config, then lang in the repository's
+                  .gitcommit.toml
+  -rate-seed      Score the message you enter with local checks (length,
+                  structure, specificity against the changed files) and, if
+                  it scores well, offer to commit it without calling the API
+  -seed-threshold n
+                  Minimum score (0-100) for -rate-seed to offer your message
+                  as-is (default 80)
+  -min-diff-lines n
+                  When fewer than n lines changed, offer to commit the
+                  message you typed without calling the API (default 0,
+                  always call it); binary files and renames always call it
+  -changelog      End the message with a "Changelog: added|changed|..."
+                  trailer, and a Component: trailer if the repository asks
+                  for one, proposed by the model and confirmed by you. The
+                  allowed values, and whether the trailers are required, come
+                  from [changelog] in .gitcommit.toml
+  -recap          Before committing, show a one-screen recap of the session
+                  (your intent, rounds, questions and answers, checks,
+                  trailers and the final message) and ask once more
+  -no-consent-prompt
+                  Skip the one-time question, on first use, whether you
+                  agree to the diff being sent to the API; for scripts, CI
+                  and managed installs
+  -raw-response   Print the complete response, including anything outside
+                  the message's code fence, before the message extracted
+                  from it; to see how the reply was framed or debug
+                  extraction. A -stream response is already shown whole
+  -context-cmd c  Run the shell command c and send its output (stdout and
+                  stderr) as extra context, e.g. "go test ./..." to show
+                  what a fix fixes, or a script that prints the ticket. It
+                  gets 30s; a failure or timeout is reported and the output
+                  is sent anyway
+  -context-file f Send the file f, such as a saved test log, the same way.
+                  Both are made valid UTF-8, cut to their last 16 KiB and
+                  go through the secret scanner
+  -like rev       Start from the message of commit rev, for recurring changes
+                  such as a monthly dependency bump: commit it as-is without
+                  calling the API, or have Claude improve it for this change.
+                  With auto, the recent commit whose changed files best
+                  match the staged ones is offered, if any matches closely
+  -t name         Write the message from the named template in the config,
+                  filling its {placeholders} from --placeholder value flags
+                  given after it, or by asking; see Commit templates in the
+                  README
+  -critique       Have Claude review the message you type against the
+                  changes (too vague, missing the why, wrong scope, ...)
+                  without rewriting it, then offer to generate an improved
+                  version
+  -fallback-to-original
+                  If the API request fails, offer to commit the message you
+                  typed instead (after editing it, if you like), so an API
+                  outage doesn't lose it
+  -prompt-version v
+                  Use an earlier version of the built-in prompts, to compare
+                  results or roll back after an upgrade. The version in use
+                  is recorded in metrics, -export-context records and the
+                  -explain-rejection log
+  -blank-lines r  Blank-line normalization for the final message, as a
+                  comma-separated list: gap (exactly one blank line after
+                  the subject), collapse (no runs of blank lines in the
+                  body), trim (no leading or trailing blank lines); "none"
+                  turns it off (default: all three, or "blank_lines" in the
+                  config)
+  -auto-reflow-subject
+                  Move the overflow of a subject longer than 72 characters
+                  into the first line of the body instead of just warning
+  -no-prefs       Ignore standing instructions (see prefs) for this run
+  -statsd addr    Send per-run metrics (duration, tokens, model, outcome) to a
+                  StatsD endpoint over UDP; never blocks or fails a commit
+  -no-webhook     Don't post to the webhook in the user config for this
+                  commit
+  -keyring        Read the API key from the OS keyring, falling back to
+                  CLAUDE_API_KEY when it is unavailable
+  -use-server     Send API requests through a running gitcommit serve when
+                  there is one, falling back to calling the API directly
+  -key-file file  Read several API keys from file, one per line, and move to
+                  the next key when one is rate limited (429). Keys can also
+                  be listed as "api_keys" in the config; set "key_rotation"
+                  to "round-robin" to spread requests over them. -verbose
+                  shows which key was used
+  -config file    Read the user config from file instead of the default
+                  location; it must exist and parse. Profiles, git config
+                  and flags still apply on top
+  -profile name   Use a named profile from the config (API endpoint, key
+                  source, secret scanner, instructions); otherwise
+                  GITCOMMIT_PROFILE or the first profile whose "remotes"
+                  patterns match this repository's remotes
+  -export-context file
+                  After committing, append a markdown record of the run
+                  (final message, your intent, questions and answers, files)
+                  to file, for pr-description --from-context; the secret
+                  scanner is applied to it
+  -pr             After committing, push the branch and open a pull request
+                  with gh, titled and described by Claude (using the
+                  -export-context file when given); each step is confirmed
+  -notes          After committing, store an extended design rationale as a
+                  git note (never blocks the commit)
+  -notes-ref ref  Notes ref for -notes (default refs/notes/gitcommit)
+  -run-before cmd Run cmd (e.g. "go test ./...") through the shell just
+                  before committing; if it fails, nothing is committed and
+                  its output is shown along with the final message
+  -date d         Set the author and committer date: now, an absolute date
+                  (2006-01-02, "2006-01-02 15:04:05" or RFC 3339) or any
+                  date git understands ("3 days ago", "yesterday 17:00",
+                  "last friday"). The effective date is shown with each
+                  suggestion, and a date in the future is warned about.
+                  "now" is the time of the commit and sets only the author
+                  date, unless -tz asks for the committer date in a zone
+  -author-date-only
+                  Apply -date only to the author date; the committer date
+                  stays the current time
+  -tz zone        Timezone for the commit date, as a name (Europe/Berlin,
+                  UTC) or an offset (+0200); dates without an offset are
+                  read in it
+  -imperative     Insist on the imperative mood ("Add", not "Added", "Adds"
+                  or "Adding"); a suggestion whose first word looks
+                  otherwise is sent back once
+  -allow-long-body
+                  Don't hold small changes to short messages. By default a
+                  change of up to 3 lines gets only a subject, up to 10 at
+                  most 2 body lines and up to 40 at most 6 (body_limits in
+                  the user config); longer suggestions are sent back to be
+                  shortened
+  -max-message-bytes n
+                  Stop before committing a message longer than n bytes
+                  (default 0, no limit), offering to cut the body to fit;
+                  the subject and closing trailers are kept
+  -check          After the message is accepted, check that the commit would
+                  succeed without making it: git commit --dry-run, then the
+                  pre-commit, prepare-commit-msg and commit-msg hooks run
+                  through git hook run against the message in a temporary
+                  file. Each step and the first failure are reported, and
+                  you can fix things, edit the message and check again
+  -early-hooks    Start the pre-commit hook as soon as the staged changes
+                  are read, so it runs while the message is written; if it
+                  passed and the staged tree is unchanged, the commit skips
+                  only pre-commit (commit-msg and other hooks still run),
+                  otherwise hooks run as usual
+  -no-verify      Skip git hooks when committing (passes --no-verify)
+  -verbose        Print extra details, such as which hooks will run and where
+                  and how long gathering repository information took
+  -read-only      Change nothing: every git command that could change the
+                  repository (commit, add, stash, reset, notes, update-ref,
+                  push, ...) is shown as "would run: git ..." instead of
+                  run. Also read_only in the user config. -early-hooks,
+                  -run-before, -context-cmd and -pr, which run other
+                  programs, are refused
+  -yes            Answer y to every yes/no question (use the suggestion,
+                  commit, ...) without reading stdin, for scripts and CI.
+                  Text prompts still read stdin and take their empty
+                  default once it ends. Without -yes, answers can be piped
+                  in line by line; when stdin ends before a question is
+                  answered, the run stops without committing
+  -C dir          Run as if gitcommit was started in dir, like git -C
+  -color mode     auto (default) colors diffs and passes escape sequences in
+                  streamed responses through on a terminal unless NO_COLOR
+                  is set; always and never force it on or off, e.g. for logs
+                  or pipelines that render color
+  -no-color       Same as -color never
+  -debug-git      Log every git command to stderr, with the environment
+                  variables it sets and the ones kept from it. git only
+                  sees PATH, HOME, the locale, GIT_*, SSH_* and the like;
+                  commands that run hooks or credential helpers see the
+                  whole environment
+  -help           Display this help message
 
 When run, the program will:
 1. Ask for an initial commit message
@@ -168,94 +923,1384 @@ When run, the program will:
 3. Present options to:
    - Accept the suggested message (y)
    - Reject it (n)
-   - Edit it in vim (e)
+   - Edit it (e) in the editor git would use ($GIT_EDITOR, core.editor,
+     $VISUAL, $EDITOR, else vim or notepad); if it can't be started, type
+     the message at the prompt instead, ending it with a line containing
+     only "."
+
+Answer a question with :edit to write a longer answer in the editor.
+Without a terminal (in a hook, or with stdin redirected), a question is
+saved to .git/GITCOMMIT_QUESTION and gitcommit exits with status 3; answer
+it in that file and run gitcommit again, or use gitcommit answer.
+Type :q or abort at any prompt to stop without committing (configurable
+with "abort_phrases" in the user config).
+Prompts are shown in the language of LC_ALL, LC_MESSAGES or LANG, or
+"language" in the user config (de, es, ja, zh; English otherwise); the
+answer letters stay y/n/e.
 
 Environment:
-  CLAUDE_API_KEY    Required API key for Claude`
+  CLAUDE_API_KEY    API key for Claude (required unless -keyring is used)
+  GITCOMMIT_PROFILE Profile to use when -profile isn't given
+  GITCOMMIT_SOCKET  Socket for serve and -use-server (default in
+                    $XDG_RUNTIME_DIR or a private temp directory)`
+
+// flagArgs returns the flags given on the command line, except skip, for
+// running gitcommit again with them. -C is left out too: the run has
+// already changed to that directory, which the new one starts in, so a
+// relative -C would be applied twice.
+func flagArgs(skip string) []string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != skip && f.Name != "C" {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	return append(args, templateArgs(templateValues)...)
+}
+
+// runSubcommand dispatches `gitcommit <command> ...`.
+func runSubcommand(args []string, notesRef, profileName string) error {
+	switch args[0] {
+	case "notes":
+		return runNotes(args[1:], notesRef)
+	case "hints":
+		return runHints(args[1:])
+	case "prefs":
+		return runPrefs(args[1:])
+	case "keyring":
+		cfg, err := loadProfileConfig(profileName)
+		if err != nil {
+			return err
+		}
+		return runKeyring(args[1:], cfg.keyringAccount(), cfg.Webhook)
+	case "profile":
+		return runProfile(args[1:], profileName)
+	case "patch-subject":
+		return runPatchSubject(args[1:], profileName)
+	case "serve":
+		return runServe(args[1:], profileName)
+	case "serve-repo":
+		return runServeRepo(args[1:], profileName)
+	case "pr-description":
+		return runPRDescription(args[1:], profileName)
+	case "restore-snapshot":
+		return runRestoreSnapshot(args[1:])
+	case "answer":
+		return runAnswer(args[1:])
+	case "msg-filter":
+		return runMsgFilter(args[1:], profileName)
+	case "batch":
+		return runBatch(args[1:], profileName)
+	case "demo":
+		return runDemo(args[1:])
+	}
+	flag.Usage()
+	return fmt.Errorf("unknown command %q", args[0])
+}
 
 func main() {
 	help := flag.Bool("help", false, "display help message")
+	yes := flag.Bool("yes", false, "answer y to every yes/no question, for non-interactive runs")
+	colorFlag := flag.String("color", "auto", "color output: auto, always or never")
+	noColor := flag.Bool("no-color", false, "never color output (same as -color never)")
+	workDir := flag.String("C", "", "run as if started in this directory")
+	debugGitFlag := flag.Bool("debug-git", false, "log every git command and its environment changes to stderr")
+	configFile := flag.String("config", "", "read the user config from this file instead of the default location")
+	readOnlyFlag := flag.Bool("read-only", false, "show git commands that would change the repository instead of running them")
 	allChanges := flag.Bool("a", false, "commit all changes")
+	reviewNote := flag.Bool("review-note", false, "propose a reviewer note for the body")
+	withUnstaged := flag.Bool("with-unstaged", false, "also send unstaged changes as context")
+	temperature := flag.Float64("temperature", -1, "sampling temperature (0-1); negative uses the API default")
+	similarSubject := flag.Float64("similar-subject", defaultSimilarSubject, "warn when the subject is this alike (0-1) to an earlier one on the branch; 0 never warns")
+	noHistory := flag.Bool("no-history", false, "do not include recent commits touching the changed files")
+	relatedHistory := flag.Int("related-history", 0, "include up to n of the latest commits touching any of the changed files (max 20)")
+	noVerify := flag.Bool("no-verify", false, "skip git hooks when committing")
+	verbose := flag.Bool("verbose", false, "print extra details about the run")
+	dryRunDiff := flag.Bool("dry-run-diff", false, "print what would be sent to the API and exit")
+	normalizePunct := flag.Bool("ascii-punct", false, "convert curly quotes and dashes in the message to ASCII")
+	suggestOnly := flag.Bool("suggest-only", false, "print the final message instead of committing")
+	printFinalCommand := flag.Bool("print-final-command", false, "print the git commit command with the final message instead of committing")
+	shell := flag.String("shell", shellPOSIX, "shell to quote -print-final-command for: sh or powershell")
+	autoReflow := flag.Bool("auto-reflow-subject", false, "move the overflow of a long subject into the body")
+	noPrefs := flag.Bool("no-prefs", false, "ignore standing instructions for this run")
+	statsdAddr := flag.String("statsd", "", "send run metrics to this StatsD host:port")
+	showDelta := flag.Bool("show-delta", false, "show a diff between your message and the suggestion")
+	noWebhook := flag.Bool("no-webhook", false, "don't post to the configured webhook for this commit")
+	useKeyring := flag.Bool("keyring", false, "read the API key from the OS keyring")
+	useServer := flag.Bool("use-server", false, "send API requests through a running gitcommit serve")
+	keyFile := flag.String("key-file", "", "read several API keys from this file, one per line, and fail over between them")
+	profileName := flag.String("profile", "", "use the named profile from the config")
+	maxDiffBytes := flag.Int("max-diff-bytes", defaultMaxDiffBytes, "summarize files to keep the diff under this many bytes (0 for no limit)")
+	truncateStrategy := flag.String("truncate-strategy", strategyImportance, "what to summarize when the diff is too large: importance, largest-first or path-order")
+	showTriage := flag.Bool("show-triage", false, "print which hunks were kept, summarized or dropped to fit the diff")
+	notes := flag.Bool("notes", false, "store an extended rationale as a git note after committing")
+	notesRef := flag.String("notes-ref", "", "notes ref for -notes (default refs/notes/gitcommit)")
+	retryEdit := flag.Bool("retry-edit", false, "re-open the editor until the edited message is valid")
+	symbols := flag.Bool("symbols", false, "list the changed top-level Go declarations in the prompt")
+	ignoreWhitespace := flag.Bool("ignore-whitespace", false, "send the diff with whitespace-only changes hidden")
+	exportPath := flag.String("export-context", "", "append a markdown record of the run to this file after committing")
+	stashRef := flag.String("stash", "", "generate the message for a stash entry instead of the index")
+	useSections := flag.Bool("sections", false, "fill a structured body with named sections such as What/Why/How")
+	rateSeed := flag.Bool("rate-seed", false, "score your message locally and offer to commit it as-is")
+	changelogFlag := flag.Bool("changelog", false, "end the message with Changelog: (and Component:) trailers for release tooling")
+	structured := flag.Bool("structured", false, "have Claude return the message as structured fields through tool use")
+	showRecap := flag.Bool("recap", false, "sum up the session and ask once more before committing")
+	noConsentPrompt := flag.Bool("no-consent-prompt", false, "don't ask for agreement before the first run sends anything to the API")
+	rawResponse := flag.Bool("raw-response", false, "print the complete response before the message extracted from it")
+	contextCmd := flag.String("context-cmd", "", "run this shell command and send its output as extra context")
+	contextFile := flag.String("context-file", "", "send this file, such as a test log, as extra context")
+	templateName := flag.String("t", "", "fill in this template from the config, with values from --name value flags after it")
+	likeFlag := flag.String("like", "", "start from the message of this commit, or with auto of the recent commit that changed the same files")
+	critique := flag.Bool("critique", false, "review the message you type against the diff before offering an improved one")
+	fallbackToOriginal := flag.Bool("fallback-to-original", false, "offer to commit your own message if the API request fails")
+	minDiffLines := flag.Int("min-diff-lines", 0, "offer to commit your message as-is when fewer lines changed (0 always calls the API)")
+	conventional := flag.Bool("conventional", false, "use a conventional subject, with a type guessed locally and asked for when unsure")
+	seedThreshold := flag.Int("seed-threshold", defaultSeedThreshold, "minimum score for -rate-seed to offer your message as-is")
+	stream := flag.Bool("stream", false, "print the response as it is generated")
+	timeout := flag.Duration("timeout", defaultRequestTimeout, "overall deadline for each API request, including generation")
+	connectTimeout := flag.Duration("connect-timeout", defaultConnectTimeout, "timeout for connecting to the API")
+	deterministic := flag.Bool("deterministic", false, "use temperature 0 for reproducible output")
+	subjectPrefix := flag.String("subject-prefix", "", "prepend this template ({branch}, {ticket}) to the subject")
+	prefixOrder := flag.String("prefix-order", "", "put -subject-prefix after-type or before-type of a conventional subject")
+	explainRejections := flag.Bool("explain-rejection", false, "ask for a reason when you reject a suggestion and log it locally")
+	suggestSplitFlag := flag.Bool("suggest-split", false, "suggest how to split the staged changes into several commits")
+	splitDepth := flag.Int("split-by-dir", 0, "commit staged changes one directory group at a time, grouping at this depth")
+	pinPrompt := flag.String("prompt-version", "", "use an earlier version of the built-in prompts")
+	blankLines := flag.String("blank-lines", "", "blank-line rules for the message: gap, collapse, trim (comma-separated) or none")
+	dateFlag := flag.String("date", "", "author and committer date: now, an absolute date or a git date expression")
+	authorDateOnly := flag.Bool("author-date-only", false, "apply -date to the author date only")
+	tzFlag := flag.String("tz", "", "timezone for the commit date (e.g. Europe/Berlin or +0200)")
+	lowBandwidth := flag.Bool("low-bandwidth", false, "compress requests, shrink the diff and responses, and don't stream")
+	langFlag := flag.String("lang", "", "write the commit message in this language (default: gitcommit.lang or .gitcommit.toml)")
+	kindName := flag.String("kind", "", "use a commit kind from the config (e.g. release, hotfix)")
+	openPR := flag.Bool("pr", false, "push the branch and open a pull request with gh after committing")
+	imperative := flag.Bool("imperative", false, "insist on an imperative subject and ask again once if it isn't")
+	allowLongBody := flag.Bool("allow-long-body", false, "don't limit the body length for small changes")
+	maxMessageBytes := flag.Int("max-message-bytes", 0, "refuse messages over this many bytes unless the body is cut to fit (0 for no limit)")
+	checkOnly := flag.Bool("check", false, "check that the commit would succeed, hooks included, without committing")
+	earlyHooks := flag.Bool("early-hooks", false, "run the pre-commit hook while the message is written and skip it at commit time if it passed")
+	runBefore := flag.String("run-before", "", "run this command before committing and abort if it fails")
+	flag.Bool("show-diff", false, "show the diff below the message in the editor (default: commit.verbose)")
+	flag.Bool("show-status", false, "show git status as comments in the editor (default: commit.status)")
+	flag.String("cleanup", "", "cleanup mode passed to git commit (default: commit.cleanup)")
+	flag.Bool("gpg-sign", false, "sign the commit, or not with -gpg-sign=false (default: commit.gpgsign)")
+	flag.String("encoding", "", "encoding to commit the message in (default: i18n.commitEncoding)")
 	flag.Usage = func() {
 		fmt.Println(helpText)
 	}
-	flag.Parse()
+	args, values, err := splitTemplateArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	templateValues = values
+	flag.CommandLine.Parse(args)
 
-	if *help || len(flag.Args()) > 0 {
+	if *help {
 		flag.Usage()
 		return
 	}
-
-	apiKey := os.Getenv("CLAUDE_API_KEY")
-	if apiKey == "" {
-		fmt.Println("Please set CLAUDE_API_KEY environment variable")
+	if *workDir != "" {
+		// Like git -C: everything, including paths git prints relative to
+		// the working directory, is then relative to it.
+		if err := os.Chdir(*workDir); err != nil {
+			say("Error: -C: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	debugGit = *debugGitFlag
+	if *noColor {
+		*colorFlag = "never"
+	}
+	if err := setColorMode(*colorFlag); err != nil {
+		say("Error: %v\n", err)
+		os.Exit(1)
+	}
+	assumeYes = *yes
+	if *configFile != "" {
+		if err := useConfigFile(*configFile); err != nil {
+			say("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	skipConsent = *noConsentPrompt
+	if *readOnlyFlag {
+		readOnly = true
+	} else if c, err := loadUserConfig(); err == nil {
+		readOnly = c.ReadOnly
+	}
+	// explain runs the normal flow with an explanation step up front, so it
+	// shares all context gathering and API settings with message generation.
+	explain := flag.NArg() == 1 && flag.Arg(0) == "explain"
+	if flag.NArg() > 0 && !explain {
+		if *notesRef == "" {
+			*notesRef = notesRefFromConfig()
+		}
+		if err := runSubcommand(flag.Args(), *notesRef, *profileName); err != nil {
+			say("Error: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	originalMessage := getUserInput("Enter commit message: ")
+	if *printFinalCommand {
+		if *stashRef != "" || *splitDepth > 0 {
+			fmt.Println("Error: -print-final-command can't be combined with -stash or -split-by-dir")
+			return
+		}
+		if _, err := finalCommand("", *shell, "", commitOptions{}); err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+		// Nothing is committed, as with -suggest-only.
+		*suggestOnly = true
+	}
+	if readOnly {
+		if name := readOnlyConflict(*earlyHooks, *openPR, *runBefore, *contextCmd); name != "" {
+			fmt.Printf("Error: -read-only can't be combined with %s, which runs commands outside git\n", name)
+			return
+		}
+	}
 
-	diff, err := getDiff(*allChanges)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+	if *openPR {
+		if *suggestOnly || *splitDepth > 0 {
+			fmt.Println("Error: -pr can't be combined with -suggest-only or -split-by-dir")
+			return
+		}
+		if _, err := exec.LookPath("gh"); err != nil {
+			fmt.Println("Error: -pr needs the GitHub CLI (gh); see https://cli.github.com")
+			return
+		}
+	}
+
+	if *suggestSplitFlag && (*allChanges || *stashRef != "" || *splitDepth > 0 || explain) {
+		fmt.Println("Error: -suggest-split can't be combined with -a, -stash, -split-by-dir or explain")
 		return
 	}
-	if diff == "" {
-		fmt.Println("No staged changes found. Stage your changes first.")
+	if *splitDepth > 0 {
+		if *allChanges || *stashRef != "" || explain {
+			fmt.Println("Error: -split-by-dir can't be combined with -a, -stash or explain")
+			return
+		}
+		// Each group runs with the same flags, minus the split itself.
+		if err := runSplitByDir(*splitDepth, flagArgs("split-by-dir")); err != nil {
+			say("Error: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	prompt := fmt.Sprintf(`Help me write a better git commit message. Here's my original message:
-"%s"
-
-Here are the changes:
-%s`, originalMessage, diff)
+	if *pinPrompt != "" {
+		if err := usePromptVersion(*pinPrompt); err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+	}
+	notePromptUpgrade()
 
-	for {
-		response, err := askClaude(prompt, apiKey)
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+	if *lowBandwidth {
+		*stream = false
+		if *maxDiffBytes == 0 || *maxDiffBytes > lowBandwidthDiffBytes {
+			*maxDiffBytes = lowBandwidthDiffBytes
+		}
+	}
+	opts := apiOptions{Stream: *stream, Deadline: *timeout, ConnectTimeout: *connectTimeout, LowBandwidth: *lowBandwidth, Verbose: *verbose}
+	if *lowBandwidth {
+		opts.MaxTokens = lowBandwidthMaxTokens
+	}
+	if *deterministic {
+		zero := 0.0
+		opts.Temperature = &zero
+	} else if *temperature >= 0 {
+		if *temperature > 1 {
+			fmt.Println("Error: -temperature must be between 0 and 1")
 			return
 		}
+		opts.Temperature = temperature
+	}
 
-		commitMsg := extractCommitMessage(response)
-		if commitMsg != "" {
-			fmt.Printf("\nSuggested commit message:\n%s\n", commitMsg)
-			answer := getUserInput("\nUse this message? (y/n/e to edit): ")
+	cfg, err := loadUserConfig()
+	if err != nil {
+		say("Warning: %v\n", err)
+		cfg = &userConfig{}
+	}
+	if cfg.Language != "" {
+		if err := setLanguage(cfg.Language); err != nil {
+			say("Warning: %v\n", err)
+		}
+	}
+	if len(cfg.AbortPhrases) > 0 {
+		abortPhrases = cfg.AbortPhrases
+	}
+	if err := applyProfile(cfg, *profileName); err != nil {
+		say("Error: %v\n", err)
+		return
+	}
+	if *verbose && cfg.active != nil {
+		fmt.Printf("Using profile %s (%s)\n", cfg.active.Name, cfg.active.Reason)
+	}
 
-			var finalMessage string
-			switch answer {
-			case "y":
-				finalMessage = commitMsg
-			case "e":
-				edited, err := editInVim(commitMsg)
-				if err != nil {
-					fmt.Printf("Error editing message: %v\n", err)
-					return
-				}
-				finalMessage = strings.TrimSpace(edited)
-			case "n":
-				continue
-			default:
-				fmt.Println("Invalid option. Please enter y, n, or e.")
-				continue
-			}
+	overrides := settingFlags{}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "show-diff", "show-status", "cleanup", "gpg-sign", "encoding":
+			overrides[f.Name] = f.Value.String()
+		}
+	})
+	settings, err := resolveGitSettings(overrides)
+	if err != nil {
+		say("Error: %v\n", err)
+		return
+	}
+	commentChar = settings.CommentChar
+	if *verbose {
+		settings.print()
+	}
+	messageLang, langSource, err := resolveMessageLanguage(*langFlag)
+	if err != nil {
+		say("Error: %v\n", err)
+		return
+	}
+	if *verbose && messageLang != "" {
+		fmt.Printf("Writing the message in %s (%s)\n", messageLang, langSource)
+	}
+	if *subjectPrefix == "" {
+		*subjectPrefix = cfg.SubjectPrefix
+	}
+	if *prefixOrder == "" {
+		*prefixOrder = cfg.PrefixOrder
+	}
+	if *prefixOrder == "" {
+		*prefixOrder = prefixAfterType
+	}
+	if !validPrefixOrder(*prefixOrder) {
+		fmt.Printf("Error: unknown -prefix-order %q (use %s or %s)\n", *prefixOrder, prefixAfterType, prefixBeforeType)
+		return
+	}
+	if *subjectPrefix != "" {
+		if *subjectPrefix, err = expandPrefix(*subjectPrefix, currentBranch()); err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+	}
+	blankRules := defaultBlankLines
+	if *blankLines == "" {
+		*blankLines = cfg.BlankLines
+	}
+	if *blankLines != "" {
+		if blankRules, err = parseBlankLineRules(*blankLines); err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+	}
+	var template *activeTemplate
+	if *templateName != "" {
+		if *likeFlag != "" {
+			fmt.Println("Error: -t and -like both give the message; use one")
+			return
+		}
+		if template, err = selectTemplate(cfg.Templates, *templateName, templateValues); err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+		if missing := template.missing(); len(missing) > 0 && !stdinIsTerminal() {
+			fmt.Printf("Error: -t %s needs %s\n", template.Name, strings.Join(missing, ", "))
+			return
+		}
+	}
+	// A template committed as it is needs no API.
+	skipAPI := template != nil && !template.Generate
+	kind, err := selectKind(cfg.Kinds, *kindName, currentBranch())
+	if err == nil && kind != nil {
+		err = kind.validate(kind.Name)
+	}
+	if err != nil {
+		say("Error: %v\n", err)
+		return
+	}
+	if *verbose && kind != nil {
+		fmt.Printf("Using commit kind %s (%s)\n", kind.Name, kind.Reason)
+	}
+	changelog, err := loadChangelogSchema(*changelogFlag)
+	if err != nil {
+		say("Error: %v\n", err)
+		return
+	}
+	date, authorOnly, err := commitDateAt(*dateFlag, *tzFlag, *authorDateOnly, time.Now())
+	if err != nil {
+		say("Error: %v\n", err)
+		return
+	}
+	if *authorDateOnly && date == "" {
+		fmt.Println("Error: -author-date-only needs -date or -tz")
+		return
+	}
+	if !*noPrefs {
+		opts.Prefs = cfg.Prefs
+		warnPrefsLength(opts.Prefs)
+	}
 
-			args := []string{"commit"}
-			if *allChanges {
-				args = append(args, "-a")
-			}
-			args = append(args, "-m", finalMessage)
-			cmd := exec.Command("git", args...)
-			if err := cmd.Run(); err != nil {
-				fmt.Printf("Error making commit: %v\n", err)
-				return
+	metrics := &runMetrics{Start: time.Now(), Model: claudeModel, PromptVersion: promptVersion, Outcome: "aborted"}
+	opts.Usage = &metrics.Usage
+	if *statsdAddr == "" {
+		*statsdAddr = cfg.StatsdAddr
+	}
+	if *statsdAddr != "" {
+		emit := func() {
+			if err := metrics.emitStatsd(*statsdAddr); err != nil && *verbose {
+				say("Warning: %v\n", err)
 			}
-			fmt.Println("Commit successful!")
-			return
 		}
+		defer emit()
+		atAbort = append(atAbort, emit)
+	}
 
-		// If no commit message was found, treat the response as a question
-		moreInfo := getUserInput(fmt.Sprintf("\nClaude asks: %s\nYour response: ", response))
+	if *useServer {
+		client := &serverClient{path: defaultSocketPath()}
+		if cfg.active != nil {
+			client.profile = cfg.active.Name
+		}
+		if err := client.ping(); err == nil {
+			apiServer = client
+		} else if *verbose {
+			fmt.Printf("Warning: no server on %s (%v); calling the API directly\n", client.path, err)
+		}
+	}
+
+	pool, err := cfg.keyPool(*keyFile, *verbose)
+	if err != nil {
+		say("Error: %v\n", err)
+		return
+	}
+	var apiKey string
+	if pool != nil {
+		opts.Keys = pool
+		apiKey = pool.keys[0]
+	} else if apiKey, err = cfg.apiKey(*useKeyring, *verbose); err != nil && !*dryRunDiff && apiServer == nil {
+		say("Error: %v\n", err)
+		return
+	}
+	if apiKey == "" && !*dryRunDiff && apiServer == nil && !skipAPI {
+		fmt.Println("Please set CLAUDE_API_KEY environment variable or store a key with gitcommit keyring set")
+		return
+	}
+	if !*dryRunDiff && !skipConsent && !skipAPI && !confirmConsent() {
+		say("Nothing was sent; -dry-run-diff shows what would be.\n")
+		return
+	}
+
+	if *verbose {
+		if *noVerify {
+			fmt.Println("Hooks will be skipped (-no-verify)")
+		} else {
+			printHooks()
+		}
+	}
+
+	var snap *repoSnapshot
+	if *stashRef != "" {
+		if *allChanges || *withUnstaged || *runBefore != "" {
+			fmt.Println("Error: -stash can't be combined with -a, -with-unstaged or -run-before")
+			return
+		}
+		if *stashRef, err = resolveStash(*stashRef); err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+		snap, err = gatherStashSnapshot(*stashRef, !*noHistory)
+	} else {
+		snap, err = gatherSnapshot(*allChanges, !*noHistory)
+	}
+	if err != nil {
+		say("Error: %v\n", err)
+		return
+	}
+	if *verbose {
+		snap.printTimings()
+	}
+	settings.Diff = snap.Diff
+	if len(snap.Oversized) > 0 {
+		say("These files are too large to send (over %s); only their names go in the prompt:\n", formatBytes(maxFileDiffBytes))
+		for _, f := range snap.Oversized {
+			fmt.Printf("  %s (%s)\n", f.Path, formatBytes(int(f.Size)))
+		}
+		if getUserInput("Continue without their content? (y/n): ") != "y" {
+			say("Nothing was sent. If they were staged by accident, unstage them with git restore --staged <path>.\n")
+			return
+		}
+	}
+	if snap.Truncated {
+		say("Warning: the diff is over %s; only its first part was read.\n", formatBytes(maxDiffReadBytes))
+	}
+	if snap.Diff == "" && len(snap.Oversized) == 0 {
+		if *stashRef != "" {
+			fmt.Printf("%s has no changes to tracked files.\n", *stashRef)
+			return
+		}
+		fmt.Println("No staged changes found. Stage your changes first.")
+		return
+	}
+
+	if *checkOnly && (*suggestOnly || *stashRef != "" || *openPR) {
+		fmt.Println("Error: -check can't be combined with -suggest-only, -stash or -pr")
+		return
+	}
+
+	var early *earlyHook
+	if *earlyHooks && !*dryRunDiff && !*suggestOnly && !*checkOnly {
+		if *allChanges || *stashRef != "" || *noVerify {
+			fmt.Println("Error: -early-hooks can't be combined with -a, -stash or -no-verify")
+			return
+		}
+		if early, err = startEarlyHook(); err != nil {
+			say("Warning: %v\n", err)
+		} else if early == nil {
+			if *verbose {
+				fmt.Println("No pre-commit hook is installed; -early-hooks has nothing to do.")
+			}
+		} else {
+			defer early.stop()
+			atAbort = append(atAbort, early.stop)
+			if *verbose {
+				fmt.Printf("Running %s in the background.\n", early.hook.Path)
+			}
+		}
+	}
+
+	if !validTruncateStrategy(*truncateStrategy) {
+		fmt.Printf("Error: unknown -truncate-strategy %q (use %s, %s or %s)\n", *truncateStrategy, strategyImportance, strategyLargestFirst, strategyPathOrder)
+		return
+	}
+	promptDiff := snap.Diff
+	var formattingFiles []string
+	if *ignoreWhitespace {
+		// Only the prompt ignores whitespace; the commit is unchanged.
+		whitespace, err := getWhitespaceDiff(*allChanges, *stashRef, excludePathspecs(snap.Oversized))
+		if err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+		formattingFiles = formattingOnlyFiles(snap.Diff, whitespace)
+		promptDiff = whitespace
+	}
+	var diff string
+	if *truncateStrategy == strategyImportance {
+		var triage []*hunkTriage
+		diff, triage = triageDiff(promptDiff, *maxDiffBytes)
+		if n := trimmedHunks(triage); n > 0 {
+			fmt.Printf("Diff exceeds %d bytes; summarized %d of %d hunk(s) by importance.\n", *maxDiffBytes, n, len(triage))
+		}
+		if *showTriage {
+			printTriage(triage)
+		}
+	} else {
+		var summarized int
+		diff, summarized = truncateDiff(promptDiff, *maxDiffBytes, *truncateStrategy)
+		if summarized > 0 {
+			fmt.Printf("Diff exceeds %d bytes; summarized %d file(s) (%s).\n", *maxDiffBytes, summarized, *truncateStrategy)
+		}
+		if *showTriage {
+			fmt.Println("Hunk triage only applies to -truncate-strategy importance.")
+		}
+	}
+
+	changes := "Here are the changes:\n" + diff
+	if *withUnstaged {
+		if *allChanges {
+			fmt.Println("Note: -with-unstaged has no effect with -a; all changes are committed.")
+		} else {
+			big, err := findOversized(true)
+			if err != nil {
+				say("Error: %v\n", err)
+				return
+			}
+			unstaged, _, err := getDiff(true, excludePathspecs(big))
+			if err != nil {
+				say("Error: %v\n", err)
+				return
+			}
+			if unstaged != "" {
+				unstaged, _ = truncateDiff(unstaged, *maxDiffBytes, *truncateStrategy)
+				changes = unstagedChanges(diff, unstaged)
+			}
+		}
+	}
+
+	if section := changedFilesPrompt(snap.Changes); section != "" {
+		changes += "\n\n" + section
+	}
+	if section := oversizedPrompt(snap.Oversized); section != "" {
+		changes += "\n\n" + section
+	}
+	if *symbols {
+		if section := symbolsPrompt(changedSymbols(snap.Diff, snap.Changes, *allChanges, *stashRef)); section != "" {
+			changes += "\n\n" + section
+		}
+	}
+
+	var localMessage, localReason string
+	if raw, err := getRawChanges(*allChanges); err == nil && *stashRef == "" {
+		facts, only := modeFacts(raw)
+		if section := modeFactsPrompt(facts); section != "" {
+			changes += "\n\n" + section
+		}
+		if section := submodulePrompt(findSubmoduleChanges(raw)); section != "" {
+			changes += "\n\n" + section
+		}
+		if only {
+			localMessage = modeOnlyMessage(facts)
+			localReason = "Only file modes and symlinks changed"
+		}
+	}
+	if len(formattingFiles) > 0 {
+		changes += "\n\n" + formattingPrompt(formattingFiles)
+		if len(formattingFiles) == diffFileCount(snap.Diff) {
+			localMessage = formattingMessage(formattingFiles)
+			localReason = "Only formatting changed"
+		}
+	}
+
+	paths := changePaths(snap.Changes)
+	if section := historyPrompt(snap.History); section != "" {
+		changes += "\n\n" + section
+	}
+	var branchLines []string
+	if !*noHistory && *stashRef == "" {
+		branchLines = branchCommits()
+		if section := branchPrompt(branchLines); section != "" {
+			changes += "\n\n" + section
+		}
+	}
+	if *relatedHistory > 0 {
+		if section := relatedHistoryPrompt(getRelatedHistory(paths, *relatedHistory), snap.History); section != "" {
+			changes += "\n\n" + section
+		}
+	}
+	if state, err := loadRepoState(); err == nil {
+		if section := hintsPrompt(state, paths); section != "" {
+			changes += "\n\n" + section
+		}
+	}
+	if section := fileHintsPrompt(cfg.FileHints, paths); section != "" {
+		changes += "\n\n" + section
+	}
+	if dependencyUpdateOnly(paths) {
+		if deps := dependencyChanges(snap.Diff); len(deps) > 0 {
+			changes += "\n\n" + dependencyPrompt(deps, *conventional)
+			if *verbose {
+				fmt.Printf("Dependency update: %d version %s found in the manifests\n", len(deps), plural(len(deps), "change", "changes"))
+			}
+		}
+	}
+
+	// Supplied context goes in ahead of the secret scanner, so it is
+	// checked and redacted like the diff.
+	var supplied []*extraContext
+	if *contextCmd != "" {
+		c, err := runContextCmd(*contextCmd)
+		if err != nil {
+			say("Warning: %v\n", err)
+		} else {
+			if c.Status != "" {
+				say("Note: -context-cmd %s; its output is sent anyway\n", c.Status)
+			}
+			supplied = append(supplied, c)
+		}
+	}
+	if *contextFile != "" {
+		if c, err := readContextFile(*contextFile); err != nil {
+			say("Warning: %v\n", err)
+		} else {
+			supplied = append(supplied, c)
+		}
+	}
+	for _, c := range supplied {
+		if c.Text != "" {
+			changes += "\n\n" + c.prompt()
+		}
+	}
+
+	flagged := false
+	if cfg.Scanner != nil {
+		changes, flagged, err = scanContent(cfg.Scanner, changes)
+		if err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+	}
+	if !flagged {
+		snap.cache()
+	}
+
+	if *suggestSplitFlag && !*dryRunDiff {
+		files, err := getNameStatus(false)
+		if err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+		plan, left, err := suggestSplit(changes, files, apiKey, opts)
+		if err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+		if len(plan.Commits) == 0 {
+			fmt.Println("Error: the suggestion has no usable commits")
+			return
+		}
+		printSplitPlan(plan, left)
+		if len(plan.Commits) < 2 {
+			return
+		}
+		if plan.splitsByHunk() {
+			fmt.Println("\nSome files are split by hunk; stage each part with git add -p and commit it yourself.")
+			return
+		}
+		if getUserInput("\nStage and commit these parts one at a time? (y/n): ") != "y" {
+			return
+		}
+		if err := commitSplitPlan(plan, files, commitOptions{NoVerify: *noVerify, Sign: settings.Sign}, settings); err != nil {
+			say("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if explain && !*dryRunDiff {
+		history, err := explainChanges(changes, apiKey, opts)
+		if err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+		if getUserInput("\nContinue to write a commit message? (y/n): ") != "y" {
+			return
+		}
+		// Continue the same conversation so the explanation informs the message.
+		opts.History = history
+	}
+
+	var (
+		originalMessage string
+		likeChosen      likeChoice
+		prompt          string
+		session         runSession
+		sections        []section
+	)
+	if *useSections {
+		sections = resolveSections(cfg)
+	}
+	pending := resumeQuestion()
+	if pending != nil {
+		// The saved prompt already carries everything below, built from
+		// the same changes.
+		originalMessage = pending.Message
+		prompt = pending.Prompt + fmt.Sprintf("\n\nAdditional context: %s", pending.Answer)
+		session.resumeExchanges(pending.Exchanges)
+		session.asked(pending.Question, pending.Answer)
+		if kind != nil {
+			kind.Values = pending.KindValues
+		}
+	} else {
+		var like *likeCommit
+		switch *likeFlag {
+		case "":
+		case "auto":
+			if like, err = findLike(paths); err != nil {
+				say("Warning: %v\n", err)
+			} else if like == nil && *verbose {
+				fmt.Println("No recent commit changed the same files.")
+			}
+		default:
+			if like, err = loadLike(*likeFlag); err != nil {
+				say("Error: %v\n", err)
+				return
+			}
+		}
+		if like != nil {
+			likeChosen = offerLike(like)
+		}
+		if template != nil {
+			template.askMissing()
+			originalMessage = template.message()
+			if !template.Generate {
+				likeChosen = likeVerbatim
+			}
+		} else if likeChosen != likeGenerate {
+			originalMessage = like.Message
+		} else {
+			originalMessage = getUserInput("Enter commit message: ")
+		}
+		prompt = messagePrompt(originalMessage, changes)
+		if kind != nil {
+			kind.askFields()
+			prompt += "\n\n" + kind.prompt()
+		}
+		if template != nil {
+			prompt += "\n\n" + template.prompt()
+		}
+		if sections != nil {
+			prompt += "\n\n" + sectionsPrompt(sections)
+		}
+		if *subjectPrefix != "" {
+			prompt += "\n\n" + subjectPrefixPrompt(*subjectPrefix)
+		}
+		if messageLang != "" {
+			prompt += "\n\n" + languagePrompt(messageLang)
+		}
+	}
+
+	if *critique && pending == nil && !*dryRunDiff {
+		if strings.TrimSpace(originalMessage) == "" {
+			fmt.Println("Error: -critique needs a message to review")
+			return
+		}
+		history, err := critiqueMessage(originalMessage, changes, apiKey, opts)
+		if err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+		if getUserInput("\nGenerate an improved version? (y/n): ") != "y" {
+			return
+		}
+		opts.History = history
+		prompt += "\n\nWrite an improved version of my message that addresses your critique."
+	}
+
+	var guess typeGuess
+	if *conventional {
+		formattingOnly := len(formattingFiles) > 0 && len(formattingFiles) == diffFileCount(snap.Diff)
+		guess = guessType(typeFactsFor(originalMessage, paths, changedSymbols(snap.Diff, snap.Changes, *allChanges, *stashRef), formattingOnly))
+		// A resumed prompt already names the type.
+		if pending == nil {
+			if guess.Confidence < lowTypeConfidence {
+				fmt.Printf("\nNot sure about the commit type: %s\n", guess)
+				guess = askType(guess)
+			}
+			prompt += "\n\n" + typePrompt(guess)
+		}
+	}
+
+	// Small changes get small messages unless -allow-long-body.
+	bodyLimit := -1
+	if !*allowLongBody && sections == nil {
+		stats, err := getNumstat(*allChanges)
+		if err != nil && *verbose {
+			say("Warning: %v\n", err)
+		}
+		if n, ok := changedLines(stats); ok {
+			if bodyLimit = bodyLineLimit(cfg.BodyLimits, n); bodyLimit >= 0 {
+				if pending == nil {
+					prompt += "\n\n" + bodyLimitPrompt(n, bodyLimit)
+				}
+				if tokens := bodyLimitTokens(bodyLimit); opts.MaxTokens == 0 || opts.MaxTokens > tokens {
+					opts.MaxTokens = tokens
+				}
+			}
+		}
+	}
+	shortenings := 0
+	if *imperative && pending == nil {
+		prompt += "\n\n" + imperativePrompt
+	}
+	if changelog != nil && pending == nil {
+		prompt += "\n\n" + changelog.prompt()
+	}
+	moodRetried := false
+
+	if *dryRunDiff {
+		fmt.Println("The following would be sent to the API (no request was made):")
+		if section := prefsPrompt(opts.Prefs); section != "" {
+			fmt.Printf("\nStanding instructions added to the system prompt:\n%s\n", section)
+		}
+		fmt.Println("----- BEGIN -----")
+		fmt.Println(prompt)
+		fmt.Println("----- END -----")
+		return
+	}
+
+	// meetsKind adds the kind's footers to an accepted message and checks
+	// its lint rules, reporting what is missing.
+	meetsKind := func(message string) (string, bool) {
+		if kind == nil {
+			return message, true
+		}
+		message = kind.addFooters(message)
+		problems := kind.lint(message)
+		if len(problems) == 0 {
+			return message, true
+		}
+		fmt.Printf("\nThe message doesn't meet the requirements for %s commits:\n", kind.Name)
+		for _, p := range problems {
+			fmt.Printf("  - %s\n", p)
+		}
+		prompt += fmt.Sprintf("\n\nThis suggestion didn't meet the requirements for %s commits:\n```\n%s\n```\nProblems: %s", kind.Name, message, strings.Join(problems, "; "))
+		return message, false
+	}
+
+	// editChecks hold an edited message to the run's requirements before
+	// the editor is left for good.
+	var editChecks []messageCheck
+	if *conventional {
+		editChecks = append(editChecks, conventionalCheck(*subjectPrefix))
+	}
+	if kind != nil {
+		editChecks = append(editChecks, kind.check)
+	}
+	if sections != nil {
+		editChecks = append(editChecks, sectionsCheck(sections))
+	}
+	if *imperative {
+		editChecks = append(editChecks, imperativeCheck)
+	}
+	if *maxMessageBytes > 0 {
+		editChecks = append(editChecks, sizeCheck(*maxMessageBytes))
+	}
+
+	// finish takes an accepted message through the final checks and commits it.
+	finish := func(finalMessage string) {
+		finalMessage = offerSanitized(finalMessage, *normalizePunct)
+		finalMessage = normalizeBlankLines(finalMessage, blankRules)
+		if *subjectPrefix != "" {
+			finalMessage = applySubjectPrefix(finalMessage, *subjectPrefix, *prefixOrder)
+		}
+		finalMessage = checkSubjectLength(finalMessage, *autoReflow)
+		if subject, _ := splitMessage(finalMessage); len(branchLines) > 0 {
+			if line, score, ok := similarBranchSubject(subject, branchLines, *similarSubject); ok {
+				say("Warning: the subject is %.0f%% similar to an earlier commit on this branch: %s\n", score*100, line)
+			}
+		}
+		if *reviewNote {
+			finalMessage = offerReviewerNote(finalMessage, *allChanges)
+		}
+		// Trailers go in after every edit to the body.
+		if changelog != nil {
+			finalMessage = changelog.confirm(finalMessage)
+		}
+
+		if *maxMessageBytes > 0 {
+			var ok bool
+			if finalMessage, ok = checkMessageSize(finalMessage, *maxMessageBytes); !ok {
+				fmt.Printf("\nFinal commit message (not committed; over -max-message-bytes):\n%s\n", finalMessage)
+				return
+			}
+		}
+
+		// -date was validated at startup; resolving it again makes "now"
+		// the time of the commit.
+		when, _ := commitDate(*dateFlag, *tzFlag, time.Now())
+		commitOpts := commitOptions{All: *allChanges, NoVerify: *noVerify, Sign: settings.Sign, Date: when, AuthorDateOnly: authorOnly}
+		if settings.CleanupFlag {
+			commitOpts.Cleanup = settings.Cleanup
+		}
+
+		if *printFinalCommand {
+			metrics.Outcome = "accepted"
+			// The message is pasted as UTF-8, so that is what git is told
+			// it is in.
+			if !settings.utf8Encoding() {
+				commitOpts.Encoding = "UTF-8"
+			}
+			command, err := finalCommand(finalMessage, *shell, *workDir, commitOpts)
+			if err != nil {
+				say("Error: %v\n", err)
+				return
+			}
+			say("\nCommand to make the commit (not run; -print-final-command):\n%s\n", command)
+			return
+		}
+		if *suggestOnly {
+			metrics.Outcome = "accepted"
+			say("\nFinal commit message (not committed; -suggest-only):\n%s\n", finalMessage)
+			return
+		}
+
+		// The message stays UTF-8 for display and verification; git gets it
+		// in the commit encoding.
+		encoded := finalMessage
+		if !settings.utf8Encoding() {
+			var lost []rune
+			encoded, finalMessage, lost, err = encodeMessage(finalMessage, settings.Encoding)
+			if err != nil {
+				say("Error: %v\n", err)
+				return
+			}
+			if len(lost) > 0 {
+				say("Warning: %s has no %s\n", settings.Encoding, describeRunes(lost))
+				if getUserInput("Commit with them replaced by \"?\"? (y/n): ") != "y" {
+					fmt.Printf("\nFinal commit message (not committed):\n%s\n", finalMessage)
+					return
+				}
+			}
+		}
+
+		if *checkOnly {
+			metrics.Outcome = "accepted"
+			for {
+				steps, hooked, err := checkCommit(encoded, *allChanges, *noVerify, settings.Cleanup)
+				if err != nil {
+					say("Error: %v\n", err)
+					break
+				}
+				if printCheck(steps, encoded, hooked) {
+					break
+				}
+				choice := getUserInput("Check again? (y to check again, e to edit the message first, n to stop): ")
+				if choice == "e" {
+					edited, err := editUntilValid(finalMessage, settings, editChecks...)
+					if err != nil {
+						say("Error: %v\n", err)
+						break
+					}
+					var lost []rune
+					encoded, finalMessage, lost, _ = encodeMessage(edited, settings.Encoding)
+					if len(lost) > 0 {
+						say("Warning: %s has no %s\n", settings.Encoding, describeRunes(lost))
+					}
+				} else if choice != "y" {
+					break
+				}
+			}
+			fmt.Printf("\nFinal commit message (not committed; -check):\n%s\n", finalMessage)
+			return
+		}
+
+		if *showRecap {
+			subject, _ := splitMessage(finalMessage)
+			checks := []string{fmt.Sprintf("subject is %d of at most %d characters", utf8.RuneCountInString(subject), maxSubjectLength)}
+			if line, score, ok := similarBranchSubject(subject, branchLines, *similarSubject); ok {
+				checks = append(checks, fmt.Sprintf("subject is %.0f%% similar to %s", score*100, line))
+			}
+			if changelog != nil {
+				checks = append(checks, changelog.problems(finalMessage)...)
+			}
+			session.Intent = originalMessage
+			session.recap(finalMessage, checks).print()
+			if getUserInput("Commit it? (y/n): ") != "y" {
+				fmt.Printf("\nFinal commit message (not committed):\n%s\n", finalMessage)
+				return
+			}
+		}
+
+		if *runBefore != "" {
+			fmt.Printf("Running %s...\n", *runBefore)
+			if err := runBeforeCommit(*runBefore); err != nil {
+				say("Error: %v\n", err)
+				fmt.Printf("\nFinal commit message (not committed):\n%s\n", finalMessage)
+				return
+			}
+		}
+
+		var (
+			commitOutput string
+			err          error
+		)
+		if settings.EncodingFlag {
+			commitOpts.Encoding = settings.Encoding
+		}
+		if early != nil && early.passed() {
+			if dir, err := hooksWithoutPreCommit(early.dir); err != nil {
+				say("Warning: %v\n", err)
+			} else {
+				defer os.RemoveAll(dir)
+				commitOpts.HooksPath = dir
+			}
+		}
+		if *stashRef != "" {
+			if ask("\nApply %s and commit it? (y/n): ", *stashRef) != "y" {
+				fmt.Printf("\nFinal commit message (%s was not applied):\n%s\n", *stashRef, finalMessage)
+				return
+			}
+			commitOutput, err = commitStash(*stashRef, encoded, snap.Changes, commitOpts)
+		} else {
+			commitOutput, err = gitCommit(encoded, commitOpts)
+		}
+		if err != nil {
+			say("Error: %v\n", err)
+			return
+		}
+		metrics.Outcome = "accepted"
+		say("Commit successful!\n")
+		if cfg.Webhook != nil && cfg.Webhook.URL != "" && !*noWebhook {
+			wait := sendWebhook(cfg.Webhook, metrics, finalMessage)
+			defer wait()
+			atAbort = append(atAbort, wait)
+		}
+		verifyCommittedMessage(finalMessage, commitOutput, settings.Cleanup, !*noVerify)
+		learnFromCommit(finalMessage, paths)
+		if *notes {
+			if *notesRef == "" {
+				*notesRef = cfg.notesRef()
+			}
+			addRationaleNote(prompt, finalMessage, apiKey, opts, *notesRef)
+		}
+		if *exportPath != "" {
+			exportContext(*exportPath, contextRecord{Message: finalMessage, Intent: originalMessage, Exchanges: session.exchanges(), PromptVersion: promptVersion}, cfg.Scanner)
+		}
+		if *openPR {
+			var contextFiles []string
+			if *exportPath != "" {
+				contextFiles = []string{*exportPath}
+			}
+			if err := openPullRequest(contextFiles, apiKey, opts); err != nil {
+				say("Error: %v\n", err)
+			}
+		}
+	}
+
+	if likeChosen == likeVerbatim {
+		if message, ok := meetsKind(originalMessage); ok {
+			finish(message)
+			return
+		}
+		if apiKey == "" && apiServer == nil {
+			fmt.Println("Please set CLAUDE_API_KEY environment variable or store a key with gitcommit keyring set")
+			return
+		}
+		fmt.Println("Generating a message instead.")
+	}
+
+	// Trivial changes can skip the API altogether; a resumed run already
+	// chose to generate.
+	trivialDeclined := false
+	if *minDiffLines > 0 && originalMessage != "" && len(session.exchanges()) == 0 {
+		stats, err := getNumstat(*allChanges)
+		if err != nil && *verbose {
+			say("Warning: %v\n", err)
+		}
+		if n, ok := changedLines(stats); ok && n < *minDiffLines {
+			if offerTrivial(n, *minDiffLines) {
+				if message, ok := meetsKind(originalMessage); ok {
+					finish(message)
+					return
+				}
+				fmt.Println("Generating a message instead.")
+			}
+			trivialDeclined = true
+		}
+	}
+
+	if *rateSeed && originalMessage != "" && !trivialDeclined {
+		stats, err := getNumstat(*allChanges)
+		if err != nil && *verbose {
+			say("Warning: %v\n", err)
+		}
+		if offerSeed(originalMessage, stats, *seedThreshold) {
+			if message, ok := meetsKind(originalMessage); ok {
+				finish(message)
+				return
+			}
+			fmt.Println("Generating a message instead.")
+		}
+	}
+
+	for {
+		var response, commitMsg string
+		var truncated bool
+		if localMessage != "" {
+			// Mode, symlink and formatting-only changes are fully described
+			// by the facts above, so offer a message without calling the
+			// API first.
+			commitMsg, localMessage = localMessage, ""
+			fmt.Printf("\n%s; this message was generated locally without calling the API.\n", localReason)
+		} else {
+			if opts.Stream {
+				fmt.Println()
+			}
+			genOpts := opts
+			genOpts.EditKey = true
+			if *structured {
+				genOpts.Tool = &commitMessageTool
+			}
+			response, err = askClaude(prompt, apiKey, genOpts)
+			var stopped *errStreamStopped
+			if errors.As(err, &stopped) {
+				edited, err := editInEditor(settings.wrap(stoppedBuffer(stopped.Text, originalMessage)))
+				if err != nil {
+					say("Error editing message: %v\n", err)
+					return
+				}
+				edited = strings.TrimSpace(stripValidationComments(settings.unwrap(edited)))
+				if edited == "" {
+					say("The edited message is empty; generating a new suggestion.\n")
+					continue
+				}
+				if message, ok := meetsKind(edited); ok {
+					finish(message)
+					return
+				}
+				say("Generating a new suggestion.\n")
+				continue
+			}
+			if *rawResponse && !opts.Stream {
+				var partial *partialResponseError
+				if errors.As(err, &partial) {
+					printRawResponse(partial.Text)
+				} else if err == nil {
+					printRawResponse(response)
+				}
+			}
+			if err != nil {
+				if commitMsg, truncated = salvagePartial(err); !truncated {
+					say("Error: %v\n", err)
+					if *fallbackToOriginal && originalMessage != "" {
+						if message, ok := offerOriginal(originalMessage, settings); ok {
+							if message, ok := meetsKind(message); ok {
+								say("Committing your original message; no message was generated.\n")
+								finish(message)
+							}
+						}
+					}
+					return
+				}
+			} else if *structured {
+				reply, err := parseStructured(response)
+				if err != nil {
+					say("Error: %v\n", err)
+					return
+				}
+				if commitMsg = reply.message(); commitMsg == "" {
+					response = reply.Question
+				}
+			} else {
+				c := classifyResponse(response)
+				if *verbose {
+					fmt.Printf("Response classified as %s (confidence %.2f: %s)\n", c.Kind, c.Confidence, c.Reason)
+				}
+				if c.Confidence < minConfidence {
+					c = confirmKind(response, c)
+				}
+				if c.Kind == responseMessage {
+					commitMsg = c.Message
+				}
+			}
+		}
+
+		if commitMsg != "" && response != "" {
+			session.add(stepSuggested, commitMsg)
+		}
+
+		var missing []string
+		if sections != nil && commitMsg != "" {
+			commitMsg, missing = assembleSections(commitMsg, sections)
+		}
+
+		if commitMsg != "" && response != "" && !truncated && bodyLimit >= 0 && shortenings < maxShortenings {
+			if n := bodyLines(commitMsg); n > bodyLimit {
+				shortenings++
+				fmt.Printf("\nThe suggestion has %d body lines for a change this small (limit %d); asking for a shorter one.\n", n, bodyLimit)
+				prompt += shortenPrompt(commitMsg, bodyLimit)
+				continue
+			}
+		}
+
+		if commitMsg != "" && response != "" && *imperative && !moodRetried {
+			if subject, _ := splitMessage(commitMsg); !checkImperativeMood(subject) {
+				moodRetried = true
+				fmt.Printf("\nThe suggested subject doesn't look imperative (%q); asking again.\n", subjectFirstWord(subject))
+				prompt += imperativeRetryPrompt(commitMsg)
+				continue
+			}
+		}
+
+		if commitMsg != "" {
+			if truncated {
+				say("\nSuggested commit message (possibly truncated; n to regenerate):\n%s\n", commitMsg)
+			} else {
+				say("\nSuggested commit message:\n%s\n", commitMsg)
+			}
+			if *conventional {
+				fmt.Println(guess)
+				subject, _, _ := strings.Cut(commitMsg, "\n")
+				if typ, _, ok := parseConventional(subject); !ok {
+					fmt.Println("Warning: the suggestion is not a conventional commit subject")
+				} else if typ != guess.Type {
+					fmt.Printf("Note: the suggestion uses %s instead.\n", typ)
+				}
+			}
+			if *showDelta && originalMessage != "" {
+				printDelta(originalMessage, commitMsg)
+			}
+			if len(missing) > 0 {
+				say("Warning: missing required section(s): %s\n", strings.Join(missing, ", "))
+			}
+			if date != "" {
+				now := time.Now()
+				fmt.Println(describeCommitDate(date, authorOnly, now))
+				if t, err := time.Parse(time.RFC3339, date); err == nil && t.After(now.Add(time.Minute)) {
+					say("Warning: the commit date is in the future\n")
+				}
+			}
+			answer := getUserInput("\nUse this message? (y/n/e to edit): ")
+
+			var finalMessage string
+			switch answer {
+			case "y":
+				finalMessage = commitMsg
+			case "e":
+				var edited string
+				if *retryEdit {
+					edited, err = editUntilValid(commitMsg, settings, editChecks...)
+				} else {
+					edited, err = editInEditor(settings.wrap(commitMsg))
+					edited = settings.unwrap(edited)
+				}
+				if err != nil {
+					say("Error editing message: %v\n", err)
+					return
+				}
+				finalMessage = strings.TrimSpace(edited)
+				if finalMessage != commitMsg {
+					session.add(stepEdited, finalMessage)
+				}
+				if sections != nil {
+					if _, missing := assembleSections(finalMessage, sections); len(missing) > 0 &&
+						ask("Missing required section(s): %s. Commit anyway? (y/n): ", strings.Join(missing, ", ")) != "y" {
+						continue
+					}
+				}
+			case "n":
+				metrics.Rejections++
+				session.add(stepRejected, commitMsg)
+				if *explainRejections {
+					if reason := explainRejection(commitMsg); reason != "" {
+						prompt += fmt.Sprintf("\n\nYou suggested:\n```\n%s\n```\nI rejected it: %s", commitMsg, reason)
+					}
+				}
+				continue
+			default:
+				say("Invalid option. Please enter y, n, or e.\n")
+				continue
+			}
+
+			finalMessage, ok := meetsKind(finalMessage)
+			if !ok {
+				say("Generating a new suggestion.\n")
+				continue
+			}
+			finish(finalMessage)
+			return
+		}
+
+		// If no commit message was found, treat the response as a question
+		if !stdinIsTerminal() {
+			q := &pendingQuestion{Args: flagArgs(""), Key: questionKey(), Message: originalMessage, Prompt: prompt, Exchanges: session.exchanges(), Question: response}
+			if kind != nil {
+				q.KindValues = kind.Values
+			}
+			stopForAnswer(q)
+		}
+		moreInfo := ask("\nClaude asks: %s\nYour response (%s for the editor): ", response, editAnswerTrigger)
+		for moreInfo == editAnswerTrigger {
+			edited, err := editAnswer(response)
+			if err != nil {
+				say("Error editing answer: %v\n", err)
+			} else if edited != "" {
+				moreInfo = edited
+				break
+			}
+			moreInfo = getUserInput("No answer written. Your response: ")
+		}
 		prompt += fmt.Sprintf("\n\nAdditional context: %s", moreInfo)
+		session.asked(response, moreInfo)
 	}
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// TestMain lets the test binary stand in for gitcommit when a test runs
+// gitcommit itself, as the server does for each repository.
+func TestMain(m *testing.M) {
+	if os.Getenv("GITCOMMIT_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestFlagArgs(t *testing.T) {
+	defer func(old *flag.FlagSet) { flag.CommandLine = old }(flag.CommandLine)
+	flag.CommandLine = flag.NewFlagSet("gitcommit", flag.ContinueOnError)
+	flag.String("C", "", "")
+	flag.String("profile", "", "")
+	flag.Int("split-by-dir", 0, "")
+	flag.Bool("verbose", false, "")
+	if err := flag.CommandLine.Parse([]string{"-C", "sub", "-profile", "work", "-split-by-dir", "1", "-verbose"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The run has already changed to sub; a child given -C sub again
+	// would look for sub/sub.
+	want := []string{"-profile=work", "-verbose=true"}
+	if got := flagArgs("split-by-dir"); !reflect.DeepEqual(got, want) {
+		t.Errorf("flagArgs = %q, want %q", got, want)
+	}
+}
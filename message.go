@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSubjectLength is the conventional upper bound for a commit subject.
+const maxSubjectLength = 72
+
+// splitMessage separates a commit message into its subject line and body.
+func splitMessage(message string) (subject, body string) {
+	subject, body, _ = strings.Cut(message, "\n")
+	return subject, strings.TrimLeft(body, "\n")
+}
+
+func joinMessage(subject, body string) string {
+	if body == "" {
+		return subject
+	}
+	return subject + "\n\n" + body
+}
+
+// blankLineRules selects what normalizeBlankLines does.
+type blankLineRules struct {
+	// Gap ensures exactly one blank line between subject and body.
+	Gap bool
+	// Collapse reduces runs of blank lines in the body to one.
+	Collapse bool
+	// Trim removes blank lines before the subject and after the body.
+	Trim bool
+}
+
+// defaultBlankLines is "gap,collapse,trim", the shape git itself produces.
+var defaultBlankLines = blankLineRules{Gap: true, Collapse: true, Trim: true}
+
+// parseBlankLineRules parses a comma-separated list of rules (gap,
+// collapse, trim), or "none".
+func parseBlankLineRules(spec string) (blankLineRules, error) {
+	var rules blankLineRules
+	if spec == "none" {
+		return rules, nil
+	}
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(name) {
+		case "gap":
+			rules.Gap = true
+		case "collapse":
+			rules.Collapse = true
+		case "trim":
+			rules.Trim = true
+		default:
+			return rules, fmt.Errorf("unknown blank-line rule %q (use gap, collapse, trim or none)", name)
+		}
+	}
+	return rules, nil
+}
+
+// normalizeBlankLines applies rules to message. Lines holding only
+// whitespace count as blank and are emptied when a rule touches them.
+// Blank lines before the subject and after the body are set aside first,
+// so only the trim rule removes them.
+func normalizeBlankLines(message string, rules blankLineRules) string {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	blank := func(i int) bool { return strings.TrimSpace(lines[i]) == "" }
+	start, end := 0, len(lines)
+	for start < end && blank(start) {
+		start++
+	}
+	for end > start && blank(end-1) {
+		end--
+	}
+	var leading, trailing []string
+	if !rules.Trim {
+		leading, trailing = lines[:start], lines[end:]
+	}
+	if start == end {
+		return strings.Join(slices.Concat(leading, trailing), "\n")
+	}
+
+	out := slices.Concat(leading, lines[start:start+1])
+	i := start + 1
+	if rules.Gap {
+		for i < end && blank(i) {
+			i++
+		}
+		if i < end {
+			out = append(out, "")
+		}
+	}
+	for ; i < end; i++ {
+		if rules.Collapse && blank(i) {
+			if out[len(out)-1] == "" {
+				continue
+			}
+			out = append(out, "")
+			continue
+		}
+		out = append(out, lines[i])
+	}
+	return strings.Join(append(out, trailing...), "\n")
+}
+
+// reflowSubject moves the part of an over-long subject past the last word
+// boundary within limit into the first line of the body. It returns the
+// message unchanged and false if the subject fits or has no usable boundary.
+func reflowSubject(message string, limit int) (string, bool) {
+	subject, body := splitMessage(message)
+	if utf8.RuneCountInString(subject) <= limit {
+		return message, false
+	}
+
+	cut := -1
+	count := 0
+	for i, r := range subject {
+		if count > limit {
+			break
+		}
+		if r == ' ' {
+			cut = i
+		}
+		count++
+	}
+	if cut <= 0 {
+		return message, false
+	}
+
+	head := strings.TrimRight(subject[:cut], " ")
+	overflow := strings.TrimSpace(subject[cut:])
+	if body == "" {
+		body = overflow
+	} else {
+		body = overflow + "\n\n" + body
+	}
+	return joinMessage(head, body), true
+}
+
+// checkSubjectLength warns about an over-long subject, or reflows it when
+// reflow is set.
+func checkSubjectLength(message string, reflow bool) string {
+	subject, _ := splitMessage(message)
+	n := utf8.RuneCountInString(subject)
+	if n <= maxSubjectLength {
+		return message
+	}
+	if reflow {
+		if reflowed, ok := reflowSubject(message, maxSubjectLength); ok {
+			fmt.Printf("\nSubject was %d characters; moved the overflow into the body:\n%s\n", n, reflowed)
+			return reflowed
+		}
+	}
+	fmt.Printf("Warning: subject is %d characters (recommended maximum is %d)\n", n, maxSubjectLength)
+	return message
+}
+
+// trailerLineRe matches a git trailer line such as "Signed-off-by: A <a@b>".
+var trailerLineRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*: \S`)
+
+// truncatedMarker replaces the part of a body cut by -max-message-bytes.
+const truncatedMarker = "[...]"
+
+// truncateBody shortens the body, at a line boundary, until the message
+// fits in limit bytes. The subject and a closing block of trailers are
+// kept whole; ok is false when they alone don't fit.
+func truncateBody(message string, limit int) (string, bool) {
+	if len(message) <= limit {
+		return message, true
+	}
+	subject, body := splitMessage(message)
+	body = strings.TrimRight(body, "\n")
+	var trailers string
+	paragraphs := strings.Split(body, "\n\n")
+	if last := strings.TrimLeft(paragraphs[len(paragraphs)-1], "\n"); last != "" {
+		isTrailers := true
+		for _, line := range strings.Split(last, "\n") {
+			isTrailers = isTrailers && trailerLineRe.MatchString(line)
+		}
+		if isTrailers {
+			trailers = last
+			body = strings.TrimRight(strings.Join(paragraphs[:len(paragraphs)-1], "\n\n"), "\n")
+		}
+	}
+	lines := strings.Split(body, "\n")
+	for k := len(lines) - 1; k >= 0; k-- {
+		kept := strings.TrimRight(strings.Join(lines[:k], "\n"), "\n")
+		if kept != "" {
+			kept += "\n\n"
+		}
+		kept += truncatedMarker
+		if trailers != "" {
+			kept += "\n\n" + trailers
+		}
+		if candidate := joinMessage(subject, kept); len(candidate) <= limit {
+			return candidate, true
+		}
+	}
+	return message, false
+}
+
+// checkMessageSize enforces -max-message-bytes: a message over the limit
+// is only used if the user agrees to cut its body to fit.
+func checkMessageSize(message string, limit int) (string, bool) {
+	if len(message) <= limit {
+		return message, true
+	}
+	say("Warning: the message is %d bytes, over the limit of %d\n", len(message), limit)
+	truncated, ok := truncateBody(message, limit)
+	if !ok {
+		fmt.Println("The subject and trailers alone don't fit, so the body can't be cut to size.")
+		return message, false
+	}
+	fmt.Printf("\nWith the body cut to fit (%d bytes):\n%s\n", len(truncated), truncated)
+	if getUserInput("Use the shortened message? (y/n): ") != "y" {
+		return message, false
+	}
+	return truncated, true
+}
+
+// sizeCheck enforces -max-message-bytes on an edited message.
+func sizeCheck(limit int) messageCheck {
+	return func(message string) []string {
+		if len(message) > limit {
+			return []string{fmt.Sprintf("the message is %d bytes; keep it to %d or fewer", len(message), limit)}
+		}
+		return nil
+	}
+}
+
+// commentChar starts comment lines in the editor buffer; it follows
+// core.commentChar.
+var commentChar = "#"
+
+// validationCommentPrefix marks explanatory lines that gitcommit adds to the
+// editor buffer; they are removed again before the message is used.
+func validationCommentPrefix() string {
+	return commentChar + " gitcommit: "
+}
+
+// messageCheck returns what keeps a message from meeting one of the run's
+// requirements, such as -conventional.
+type messageCheck func(message string) []string
+
+// validateMessage returns the problems that make a message unusable,
+// followed by those the checks find.
+func validateMessage(message string, checks ...messageCheck) []string {
+	if strings.TrimSpace(message) == "" {
+		return []string{"the message is empty"}
+	}
+	var problems []string
+	subject, rest, hasBody := strings.Cut(message, "\n")
+	if n := utf8.RuneCountInString(subject); n > maxSubjectLength {
+		problems = append(problems, fmt.Sprintf("the subject is %d characters; keep it to %d or fewer", n, maxSubjectLength))
+	}
+	if hasBody && rest != "" && !strings.HasPrefix(rest, "\n") {
+		problems = append(problems, "separate the subject from the body with a blank line")
+	}
+	for _, check := range checks {
+		problems = append(problems, check(message)...)
+	}
+	return problems
+}
+
+func stripValidationComments(message string) string {
+	lines := strings.Split(message, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, validationCommentPrefix()) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// editUntilValid opens the editor and, while the result fails validation,
+// re-opens it with comments explaining what to fix, until the message is
+// valid, and passes the checks, or the user gives up.
+func editUntilValid(message string, settings *gitSettings, checks ...messageCheck) (string, error) {
+	buffer := message
+	for {
+		edited, err := editInEditor(settings.wrap(buffer))
+		if err != nil {
+			return "", err
+		}
+		edited = strings.TrimSpace(stripValidationComments(settings.unwrap(edited)))
+		problems := validateMessage(edited, checks...)
+		if len(problems) == 0 {
+			return edited, nil
+		}
+
+		fmt.Println("\nThe edited message needs fixing:")
+		for _, p := range problems {
+			fmt.Printf("  - %s\n", p)
+		}
+		if getUserInput("Re-open the editor? (y to edit again, n to abort): ") != "y" {
+			return "", fmt.Errorf("edit aborted")
+		}
+		var b strings.Builder
+		for _, p := range problems {
+			b.WriteString(validationCommentPrefix() + p + "\n")
+		}
+		b.WriteString(validationCommentPrefix() + "these lines are removed automatically\n")
+		b.WriteString(edited)
+		buffer = b.String()
+	}
+}
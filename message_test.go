@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeBlankLines(t *testing.T) {
+	var (
+		none     = blankLineRules{}
+		gap      = blankLineRules{Gap: true}
+		collapse = blankLineRules{Collapse: true}
+		trim     = blankLineRules{Trim: true}
+		noTrim   = blankLineRules{Gap: true, Collapse: true}
+	)
+	tests := []struct {
+		name  string
+		in    string
+		rules blankLineRules
+		want  string
+	}{
+		{"clean", "Fix it\n\nBody.", defaultBlankLines, "Fix it\n\nBody."},
+		{"leading and trailing", "\n\n  \nFix it\n\nBody.\n\n\n", defaultBlankLines, "Fix it\n\nBody."},
+		{"runs of blank lines", "Fix it\n\nOne.\n\n\n\nTwo.\n \t\n\nThree.", defaultBlankLines, "Fix it\n\nOne.\n\nTwo.\n\nThree."},
+		{"missing gap", "Fix it\nBody.", defaultBlankLines, "Fix it\n\nBody."},
+		{"wide gap", "Fix it\n\n\n\nBody.", defaultBlankLines, "Fix it\n\nBody."},
+		{"crlf", "Fix it\r\n\r\n\r\nBody.\r\n\r\n", defaultBlankLines, "Fix it\n\nBody."},
+		{"subject only", "Fix it\n\n\n", defaultBlankLines, "Fix it"},
+		{"only blank lines", "\n \n\n", defaultBlankLines, ""},
+
+		{"none", "\nFix it\nBody.\n\n\nMore.\n\n", none, "\nFix it\nBody.\n\n\nMore.\n\n"},
+		{"gap alone", "\nFix it\nBody.\n\n\nMore.\n\n", gap, "\nFix it\n\nBody.\n\n\nMore.\n\n"},
+		{"gap alone, subject only", "Fix it\n\n", gap, "Fix it\n\n"},
+		{"collapse alone", "\nFix it\nBody.\n\n\nMore.\n\n", collapse, "\nFix it\nBody.\n\nMore.\n\n"},
+		{"trim alone", "\nFix it\nBody.\n\n\nMore.\n\n", trim, "Fix it\nBody.\n\n\nMore."},
+		{"trailing kept without trim", "Fix it\n\n\nBody.\n\n\n", noTrim, "Fix it\n\nBody.\n\n\n"},
+		{"trailing newline kept without trim", "Fix it\nBody.\n", noTrim, "Fix it\n\nBody.\n"},
+		{"leading kept without trim", "\n\nFix it\n\n\nBody.", noTrim, "\n\nFix it\n\nBody."},
+	}
+	for _, tt := range tests {
+		if got := normalizeBlankLines(tt.in, tt.rules); got != tt.want {
+			t.Errorf("%s: normalizeBlankLines(%q, %+v) = %q, want %q", tt.name, tt.in, tt.rules, got, tt.want)
+		}
+	}
+}
+
+func TestParseBlankLineRules(t *testing.T) {
+	tests := map[string]blankLineRules{
+		"none":               {},
+		"gap":                {Gap: true},
+		"collapse":           {Collapse: true},
+		"trim":               {Trim: true},
+		"gap, collapse,trim": defaultBlankLines,
+	}
+	for spec, want := range tests {
+		if got, err := parseBlankLineRules(spec); err != nil || got != want {
+			t.Errorf("parseBlankLineRules(%q) = %+v, %v; want %+v", spec, got, err, want)
+		}
+	}
+	for _, spec := range []string{"", "gap,squash", "none,gap"} {
+		if _, err := parseBlankLineRules(spec); err == nil {
+			t.Errorf("parseBlankLineRules(%q) was accepted", spec)
+		}
+	}
+}
+
+// scriptedEditor makes GIT_EDITOR a script that saves each of saves in
+// turn, keeping what it was given as buffer0, buffer1 and so on in the
+// returned directory.
+func scriptedEditor(t *testing.T, saves ...string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the editor is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nn=$(ls \"$EDITOR_DIR\" | grep -c '^buffer')\ncp \"$1\" \"$EDITOR_DIR/buffer$n\"\ncp \"$EDITOR_DIR/save$n\" \"$1\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "editor"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, save := range saves {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("save%d", i)), []byte(save), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("EDITOR_DIR", dir)
+	t.Setenv("GIT_EDITOR", filepath.Join(dir, "editor"))
+	t.Setenv("TERM", "xterm")
+	return dir
+}
+
+// TestEditUntilValidReopens saves a message that fails a check, then a
+// fixed one once the editor is opened again with the problem explained.
+func TestEditUntilValidReopens(t *testing.T) {
+	noTODO := func(message string) []string {
+		if strings.Contains(message, "TODO") {
+			return []string{"finish the TODO"}
+		}
+		return nil
+	}
+	long := "Fix " + strings.Repeat("the parser ", 8)
+	dir := scriptedEditor(t, long+"\n", "Fix the parser\n\nTODO: say why\n", "Fix the parser\n\nIt dropped the last line.\n")
+	withInput(t, "y\ny\n")
+
+	got, err := editUntilValid("Fix parser", nil, noTODO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Fix the parser\n\nIt dropped the last line." {
+		t.Errorf("editUntilValid = %q", got)
+	}
+	for i, want := range []string{"the subject is", "finish the TODO"} {
+		buffer, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("buffer%d", i+1)))
+		if err != nil {
+			t.Fatalf("the editor was not opened a %d time", i+2)
+		}
+		if !strings.Contains(string(buffer), validationCommentPrefix()+want) {
+			t.Errorf("buffer %d doesn't explain %q:\n%s", i+1, want, buffer)
+		}
+	}
+
+	scriptedEditor(t, "Fix the parser\n\nTODO\n")
+	withInput(t, "n\n")
+	if _, err := editUntilValid("Fix parser", nil, noTODO); err == nil {
+		t.Error("declining to edit again was not an error")
+	}
+}
+
+func TestValidateMessageChecks(t *testing.T) {
+	calls := 0
+	check := func(string) []string { calls++; return []string{"checked"} }
+	if got := validateMessage("Fix the parser\nBody.", check); len(got) != 2 || got[1] != "checked" {
+		t.Errorf("validateMessage = %q, want the blank line problem and the check's", got)
+	}
+	if got := validateMessage(" \n", check); len(got) != 1 || calls != 1 {
+		t.Errorf("an empty message gave %q and ran the check %d times", got, calls)
+	}
+}
+
+func TestSizeCheck(t *testing.T) {
+	check := sizeCheck(14)
+	if problems := check("Fix the parser"); len(problems) != 0 {
+		t.Errorf("a message at the limit gave %q", problems)
+	}
+	if problems := check("Fix the parser!"); len(problems) != 1 || problems[0] != "the message is 15 bytes; keep it to 14 or fewer" {
+		t.Errorf("a message over the limit gave %q", problems)
+	}
+	// Bytes, not characters, are counted.
+	if problems := check("Fix the pärser"); len(problems) != 1 {
+		t.Errorf("a 15-byte message of 14 characters gave %q", problems)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// metricsTimeout bounds how long emitting metrics may take. Metrics are
+// fire-and-forget and must never hold up or fail a commit.
+const metricsTimeout = 500 * time.Millisecond
+
+// runMetrics records what happened during one invocation.
+type runMetrics struct {
+	Start         time.Time
+	Model         string
+	PromptVersion string
+	Usage         usageTotals
+	Outcome       string // "accepted" once committed (or shown by -suggest-only and the like), "rejected" or "aborted"
+	Rejections    int
+}
+
+// statsdLines renders the metrics in StatsD format with DogStatsD-style
+// tags, which statsd_exporter and most agents accept.
+func (m *runMetrics) statsdLines() []string {
+	tags := fmt.Sprintf("|#model:%s,prompt:%s,outcome:%s", m.Model, m.PromptVersion, m.Outcome)
+	return []string{
+		fmt.Sprintf("gitcommit.duration_ms:%d|ms%s", time.Since(m.Start).Milliseconds(), tags),
+		fmt.Sprintf("gitcommit.requests:%d|c%s", m.Usage.Requests, tags),
+		fmt.Sprintf("gitcommit.tokens.input:%d|c%s", m.Usage.InputTokens, tags),
+		fmt.Sprintf("gitcommit.tokens.output:%d|c%s", m.Usage.OutputTokens, tags),
+		fmt.Sprintf("gitcommit.rejections:%d|c%s", m.Rejections, tags),
+		fmt.Sprintf("gitcommit.runs:1|c%s", tags),
+	}
+}
+
+// emitStatsd sends the metrics to a StatsD endpoint over UDP. Errors are
+// returned for verbose reporting only.
+func (m *runMetrics) emitStatsd(addr string) error {
+	conn, err := net.DialTimeout("udp", addr, metricsTimeout)
+	if err != nil {
+		return fmt.Errorf("error connecting to statsd: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(metricsTimeout))
+	if _, err := conn.Write([]byte(strings.Join(m.statsdLines(), "\n"))); err != nil {
+		return fmt.Errorf("error sending metrics: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	modeFile    = "100644"
+	modeExec    = "100755"
+	modeSymlink = "120000"
+	modeGitlink = "160000"
+)
+
+// rawChange is one entry of `git diff --raw`.
+type rawChange struct {
+	OldMode, NewMode string
+	OldSha, NewSha   string
+	Status           byte
+	Path             string
+}
+
+func getRawChanges(all bool) ([]rawChange, error) {
+	cmd := gitCommand(diffArgs(all, "--raw", "-z", "--no-abbrev")...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting raw diff: %v", err)
+	}
+	return parseRaw(string(output)), nil
+}
+
+// parseRaw parses `git diff --raw -z` output. Rename detection is not
+// requested, so each entry has exactly one path.
+func parseRaw(output string) []rawChange {
+	var changes []rawChange
+	fields := strings.Split(output, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		meta := strings.Fields(strings.TrimPrefix(fields[i], ":"))
+		if len(meta) != 5 || meta[4] == "" {
+			continue
+		}
+		changes = append(changes, rawChange{
+			OldMode: meta[0],
+			NewMode: meta[1],
+			OldSha:  meta[2],
+			NewSha:  meta[3],
+			Status:  meta[4][0],
+			Path:    fields[i+1],
+		})
+	}
+	return changes
+}
+
+// symlinkTarget returns the target stored for a symlink blob. A null sha
+// means the content is only in the work tree (with -a), so read the link.
+func symlinkTarget(sha, path string) string {
+	if isNullSha(sha) {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "?"
+		}
+		return target
+	}
+	output, err := gitCommand("cat-file", "blob", sha).Output()
+	if err != nil {
+		return "?"
+	}
+	return string(output)
+}
+
+func isNullSha(sha string) bool {
+	return strings.Trim(sha, "0") == ""
+}
+
+// modeFact describes a mode, symlink or file-type change in plain words.
+// ok is false for ordinary content changes.
+func modeFact(ch rawChange) (fact string, ok bool) {
+	switch {
+	case ch.Status == 'A' && ch.NewMode == modeSymlink:
+		return fmt.Sprintf("add symlink %s -> %s", ch.Path, symlinkTarget(ch.NewSha, ch.Path)), true
+	case ch.Status == 'D' && ch.OldMode == modeSymlink:
+		return fmt.Sprintf("remove symlink %s", ch.Path), true
+	case ch.Status == 'M' && ch.OldMode == modeSymlink && ch.NewMode == modeSymlink:
+		return fmt.Sprintf("point symlink %s -> %s (was %s)", ch.Path,
+			symlinkTarget(ch.NewSha, ch.Path), symlinkTarget(ch.OldSha, "")), true
+	case ch.Status == 'T' && ch.NewMode == modeSymlink:
+		return fmt.Sprintf("replace file %s with a symlink -> %s", ch.Path, symlinkTarget(ch.NewSha, ch.Path)), true
+	case ch.Status == 'T' && ch.OldMode == modeSymlink:
+		return fmt.Sprintf("replace symlink %s with a regular file", ch.Path), true
+	case ch.Status == 'M' && ch.OldMode != ch.NewMode && ch.OldMode != modeGitlink && ch.NewMode != modeGitlink:
+		suffix := ""
+		if ch.OldSha != ch.NewSha && !isNullSha(ch.NewSha) {
+			suffix = " (content also changed)"
+		}
+		switch {
+		case ch.OldMode == modeFile && ch.NewMode == modeExec:
+			return fmt.Sprintf("make %s executable%s", ch.Path, suffix), true
+		case ch.OldMode == modeExec && ch.NewMode == modeFile:
+			return fmt.Sprintf("make %s non-executable%s", ch.Path, suffix), true
+		}
+		return fmt.Sprintf("change mode of %s from %s to %s%s", ch.Path, ch.OldMode, ch.NewMode, suffix), true
+	}
+	return "", false
+}
+
+// modeFacts returns facts for every mode/symlink entry and whether the
+// whole diff consists only of such entries.
+func modeFacts(changes []rawChange) (facts []string, only bool) {
+	only = len(changes) > 0
+	for _, ch := range changes {
+		fact, ok := modeFact(ch)
+		if ok {
+			facts = append(facts, fact)
+		}
+		// A mode change that also edits content still needs the model. With
+		// -a the new content is only in the work tree, so it is unknown.
+		contentEdit := ch.Status == 'M' && ch.OldMode != modeSymlink && ch.OldSha != ch.NewSha
+		if !ok || contentEdit {
+			only = false
+		}
+	}
+	return facts, only
+}
+
+func modeFactsPrompt(facts []string) string {
+	if len(facts) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("File mode and symlink changes (describe these as facts, not as content edits):\n")
+	for _, f := range facts {
+		fmt.Fprintf(&b, "- %s\n", f)
+	}
+	return b.String()
+}
+
+// modeOnlyMessage builds a deterministic message for a diff that consists
+// only of mode and symlink changes.
+func modeOnlyMessage(facts []string) string {
+	if len(facts) == 1 {
+		return capitalize(facts[0])
+	}
+	var b strings.Builder
+	b.WriteString("Update file modes and symlinks\n\n")
+	for _, f := range facts {
+		fmt.Fprintf(&b, "- %s\n", capitalize(f))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestModeFacts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs symlinks and executable bits")
+	}
+	tests := []struct {
+		name  string
+		setup func(t *testing.T) // before the commit
+		edit  func(t *testing.T) // staged, or left in the work tree with all
+		all   bool
+		facts []string
+		only  bool
+	}{
+		{
+			name:  "made executable",
+			setup: func(t *testing.T) { writeFile(t, "scripts/deploy.sh", "#!/bin/sh\n") },
+			edit:  func(t *testing.T) { mustGit(t, "update-index", "--chmod=+x", "scripts/deploy.sh") },
+			facts: []string{"make scripts/deploy.sh executable"},
+			only:  true,
+		},
+		{
+			name:  "made executable in the work tree",
+			setup: func(t *testing.T) { writeFile(t, "run.sh", "#!/bin/sh\n") },
+			edit: func(t *testing.T) {
+				if err := os.Chmod("run.sh", 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			all:   true,
+			facts: []string{"make run.sh executable"},
+			// Whether the content changed too is only known once staged.
+			only: false,
+		},
+		{
+			name: "made non-executable",
+			setup: func(t *testing.T) {
+				writeFile(t, "tool", "#!/bin/sh\n")
+				if err := os.Chmod("tool", 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			edit:  func(t *testing.T) { mustGit(t, "update-index", "--chmod=-x", "tool") },
+			facts: []string{"make tool non-executable"},
+			only:  true,
+		},
+		{
+			name:  "mode and content",
+			setup: func(t *testing.T) { writeFile(t, "build.sh", "#!/bin/sh\n") },
+			edit: func(t *testing.T) {
+				writeFile(t, "build.sh", "#!/bin/sh\nmake\n")
+				mustGit(t, "add", "build.sh")
+				mustGit(t, "update-index", "--chmod=+x", "build.sh")
+			},
+			facts: []string{"make build.sh executable (content also changed)"},
+			only:  false,
+		},
+		{
+			name:  "symlink retargeted",
+			setup: func(t *testing.T) { symlink(t, "releases/v1", "current") },
+			edit: func(t *testing.T) {
+				os.Remove("current")
+				symlink(t, "releases/v2", "current")
+				mustGit(t, "add", "current")
+			},
+			facts: []string{"point symlink current -> releases/v2 (was releases/v1)"},
+			only:  true,
+		},
+		{
+			name: "symlink added",
+			edit: func(t *testing.T) {
+				symlink(t, "config.prod.json", "config.json")
+				mustGit(t, "add", "config.json")
+			},
+			facts: []string{"add symlink config.json -> config.prod.json"},
+			only:  true,
+		},
+		{
+			name:  "symlink removed",
+			setup: func(t *testing.T) { symlink(t, "elsewhere", "link") },
+			edit:  func(t *testing.T) { mustGit(t, "rm", "-q", "link") },
+			facts: []string{"remove symlink link"},
+			only:  true,
+		},
+		{
+			name:  "file replaced by symlink",
+			setup: func(t *testing.T) { writeFile(t, "latest", "v1\n") },
+			edit: func(t *testing.T) {
+				os.Remove("latest")
+				symlink(t, "v2", "latest")
+				mustGit(t, "add", "latest")
+			},
+			facts: []string{"replace file latest with a symlink -> v2"},
+			only:  true,
+		},
+		{
+			name:  "symlink replaced by file",
+			setup: func(t *testing.T) { symlink(t, "v1", "latest") },
+			edit: func(t *testing.T) {
+				os.Remove("latest")
+				writeFile(t, "latest", "v2\n")
+				mustGit(t, "add", "latest")
+			},
+			facts: []string{"replace symlink latest with a regular file"},
+			only:  true,
+		},
+		{
+			name:  "mode change beside a content change",
+			setup: func(t *testing.T) { writeFile(t, "a.sh", "#!/bin/sh\n"); writeFile(t, "b.txt", "b\n") },
+			edit: func(t *testing.T) {
+				mustGit(t, "update-index", "--chmod=+x", "a.sh")
+				writeFile(t, "b.txt", "b2\n")
+				mustGit(t, "add", "b.txt")
+			},
+			facts: []string{"make a.sh executable"},
+			only:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo(t)
+			writeFile(t, "README", "fixture\n")
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			mustGit(t, "add", "-A")
+			mustGit(t, "commit", "-q", "-m", "Fixture")
+			tt.edit(t)
+
+			changes, err := getRawChanges(tt.all)
+			if err != nil {
+				t.Fatal(err)
+			}
+			facts, only := modeFacts(changes)
+			if !reflect.DeepEqual(facts, tt.facts) || only != tt.only {
+				t.Errorf("modeFacts = %q, %v; want %q, %v", facts, only, tt.facts, tt.only)
+			}
+		})
+	}
+}
+
+func TestModeOnlyMessage(t *testing.T) {
+	if got, want := modeOnlyMessage([]string{"make run.sh executable"}), "Make run.sh executable"; got != want {
+		t.Errorf("one fact: got %q, want %q", got, want)
+	}
+	got := modeOnlyMessage([]string{"make run.sh executable", "point symlink current -> v2 (was v1)"})
+	want := "Update file modes and symlinks\n\n- Make run.sh executable\n- Point symlink current -> v2 (was v1)"
+	if got != want {
+		t.Errorf("two facts: got %q, want %q", got, want)
+	}
+}
+
+// symlink creates a symlink at name pointing to target.
+func symlink(t *testing.T, target, name string) {
+	t.Helper()
+	if err := os.Symlink(target, name); err != nil {
+		t.Fatal(err)
+	}
+}
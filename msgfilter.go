@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// msgFilterPromptVersion versions the msg-filter prompt; cached results
+// from another version are not reused.
+const msgFilterPromptVersion = "msg-filter-v1"
+
+const msgFilterSystemPrompt = `You rewrite existing git commit messages as conventional commits
+(type(scope): summary). You are given the original message and the
+commit's diff. Keep every fact from the original message and add only
+what the diff shows; don't invent motivation. Use one of the types feat,
+fix, refactor, perf, test, docs, build, ci, style, chore or revert. Keep
+the subject under 72 characters, and keep trailers (Signed-off-by: and
+the like) at the end unchanged. Never ask questions. Respond with ONLY the
+new message wrapped in triple backticks.`
+
+// msgFilterDiffBytes keeps each request small; history rewrites send
+// thousands of them.
+const msgFilterDiffBytes = 30000
+
+// msgFilterRetries is how often a rate-limited request is retried, with
+// doubling waits, before falling back to the original message.
+const msgFilterRetries = 5
+
+// msgFilterRecord is one result in the cache file, which is also what an
+// interrupted rewrite resumes from.
+type msgFilterRecord struct {
+	Sha           string `json:"sha"`
+	PromptVersion string `json:"prompt_version"`
+	Message       string `json:"message"`
+	// Fallback is set when the original message was kept because the
+	// suggestion failed validation.
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// msgFilterLog reports progress on stderr; stdout carries only the message.
+func msgFilterLog(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "gitcommit msg-filter: "+format+"\n", args...)
+}
+
+func msgFilterCachePath() (string, error) {
+	output, err := gitRun("rev-parse", "--git-path", "gitcommit/msg-filter.jsonl")
+	if err != nil {
+		return "", fmt.Errorf("error locating git directory: %v", err)
+	}
+	return filepath.Abs(output)
+}
+
+// loadMsgFilterCache reads the cached results for the current prompt
+// version. Later records for a commit replace earlier ones.
+func loadMsgFilterCache(p string) map[string]msgFilterRecord {
+	cache := map[string]msgFilterRecord{}
+	f, err := os.Open(p)
+	if err != nil {
+		return cache
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var r msgFilterRecord
+		if json.Unmarshal(scanner.Bytes(), &r) == nil && r.PromptVersion == msgFilterPromptVersion {
+			cache[r.Sha] = r
+		}
+	}
+	return cache
+}
+
+func appendMsgFilterCache(p string, r msgFilterRecord) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("error creating cache directory: %v", err)
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening cache: %v", err)
+	}
+	defer f.Close()
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("error encoding cache record: %v", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// waitForRate spaces requests at most rate per minute across processes,
+// since filter tools start gitcommit once per commit. The time of the last
+// request is kept next to the cache.
+func waitForRate(cachePath string, rate int) {
+	if rate <= 0 {
+		return
+	}
+	stamp := cachePath + ".last"
+	interval := time.Minute / time.Duration(rate)
+	if data, err := os.ReadFile(stamp); err == nil {
+		if ns, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			if wait := time.Until(time.Unix(0, ns).Add(interval)); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+	}
+	os.WriteFile(stamp, []byte(strconv.FormatInt(time.Now().UnixNano(), 10)), 0644)
+}
+
+// validConventional checks a rewritten message strictly: a conventional
+// subject of a known type within the length limit.
+func validConventional(message string) error {
+	subject, _, _ := strings.Cut(message, "\n")
+	typ, _, ok := parseConventional(subject)
+	if !ok {
+		return fmt.Errorf("subject %q is not a conventional commit subject", subject)
+	}
+	known := false
+	for _, t := range append(conventionalTypes, "revert") {
+		if typ == t {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown type %q", typ)
+	}
+	if len([]rune(subject)) > maxSubjectLength {
+		return fmt.Errorf("subject is longer than %d characters", maxSubjectLength)
+	}
+	return nil
+}
+
+// rewriteMessage asks for the conventional form of one commit's message,
+// retrying while rate limited.
+func rewriteMessage(sha, original, apiKey string, opts apiOptions, cachePath string, rate int) (string, error) {
+	diff, err := gitRun("show", "--format=", "--stat", "--patch", sha)
+	if err != nil {
+		return "", err
+	}
+	diff, _ = truncateDiff(diff, msgFilterDiffBytes, strategyImportance)
+	prompt := fmt.Sprintf("Original message:\n```\n%s\n```\n\nDiff of commit %s:\n%s", original, sha, diff)
+
+	opts.System = msgFilterSystemPrompt
+	wait := 2 * time.Second
+	for attempt := 0; ; attempt++ {
+		waitForRate(cachePath, rate)
+		response, err := askClaude(prompt, apiKey, opts)
+		if err == nil {
+			message := extractCommitMessage(response)
+			if message == "" {
+				return "", fmt.Errorf("the response had no message")
+			}
+			return message, nil
+		}
+		if !strings.HasPrefix(err.Error(), "API error: 429") || attempt == msgFilterRetries {
+			return "", err
+		}
+		msgFilterLog("rate limited; retrying in %s", wait)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// runMsgFilter implements `gitcommit msg-filter`: it reads a commit message
+// on stdin and writes its conventional rewrite to stdout, for
+// `git filter-branch --msg-filter`, a git filter-repo message callback or
+// a rebase --exec loop. It never prompts, and on any error it writes the
+// original message unchanged so a rewrite never stalls.
+func runMsgFilter(args []string, profileName string) error {
+	fs := flag.NewFlagSet("msg-filter", flag.ContinueOnError)
+	sha := fs.String("sha", "", "commit whose message is on stdin (default $GITCOMMIT_SHA or $GIT_COMMIT)")
+	rate := fs.Int("rate", 0, "at most this many API requests per minute (0 for no limit)")
+	cacheFile := fs.String("cache", "", "cache and resume file (default .git/gitcommit/msg-filter.jsonl)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: gitcommit msg-filter [--sha commit] [--rate n] [--cache file] < message")
+	}
+
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("error reading message: %v", err)
+	}
+	original := string(input)
+	keep := func(reason error) error {
+		msgFilterLog("keeping the original message: %v", reason)
+		fmt.Print(original)
+		return nil
+	}
+
+	rev := *sha
+	if rev == "" {
+		rev = os.Getenv("GITCOMMIT_SHA")
+	}
+	if rev == "" {
+		rev = os.Getenv("GIT_COMMIT")
+	}
+	if rev == "" {
+		return keep(errors.New("no commit given (use --sha, GITCOMMIT_SHA or GIT_COMMIT)"))
+	}
+	full, err := gitRun("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return keep(fmt.Errorf("unknown commit %s", rev))
+	}
+
+	cachePath := *cacheFile
+	if cachePath == "" {
+		if cachePath, err = msgFilterCachePath(); err != nil {
+			return keep(err)
+		}
+	}
+	if r, ok := loadMsgFilterCache(cachePath)[full]; ok {
+		fmt.Print(r.Message)
+		return nil
+	}
+
+	apiKey, opts, err := subcommandAPI(profileName)
+	if err != nil {
+		return keep(err)
+	}
+	zero := 0.0
+	opts.Temperature = &zero
+	message, err := rewriteMessage(full, strings.TrimSpace(original), apiKey, opts, cachePath, *rate)
+	if err != nil {
+		// Not cached: a rerun tries again.
+		return keep(err)
+	}
+	record := msgFilterRecord{Sha: full, PromptVersion: msgFilterPromptVersion, Message: message + "\n"}
+	if err := validConventional(message); err != nil {
+		record.Message, record.Fallback = original, true
+		msgFilterLog("keeping the original message of %s: %v", full[:12], err)
+	}
+	if err := appendMsgFilterCache(cachePath, record); err != nil {
+		msgFilterLog("warning: %v", err)
+	}
+	fmt.Print(record.Message)
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const defaultNotesRef = "refs/notes/gitcommit"
+
+const rationalePrompt = `The commit has been made with the message above. Now write an extended design
+rationale for it, to be stored as a git note rather than in the commit
+message: the motivation, the alternatives considered, trade-offs, and
+anything a future maintainer should know. Use the conversation so far,
+including my original intent and answers. Respond with plain text only.`
+
+// addRationaleNote asks for an extended rationale and stores it as a note
+// on HEAD. Failures are reported but never affect the commit.
+func addRationaleNote(prompt, finalMessage, apiKey string, opts apiOptions, ref string) {
+	opts.History = append(append([]Message{}, opts.History...),
+		Message{Role: "user", Content: prompt},
+		Message{Role: "assistant", Content: "```\n" + finalMessage + "\n```"},
+	)
+	// The rationale goes straight into the note; don't print it.
+	opts.Stream = false
+	rationale, err := askClaude(rationalePrompt, apiKey, opts)
+	if err != nil {
+		fmt.Printf("Warning: could not generate rationale note: %v\n", err)
+		return
+	}
+	cmd := gitCommand("notes", "--ref", ref, "add", "-f", "-F", "-", "HEAD")
+	cmd.Stdin = strings.NewReader(strings.TrimSpace(rationale) + "\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		fmt.Printf("Warning: could not store rationale note: %v: %s\n", err, strings.TrimSpace(string(output)))
+		return
+	}
+	fmt.Printf("Stored rationale in %s. Notes are not pushed by default; share them with:\n  git push origin %s\n", ref, ref)
+}
+
+// runNotes implements `gitcommit notes show [sha]`.
+func runNotes(args []string, ref string) error {
+	if len(args) < 1 || len(args) > 2 || args[0] != "show" {
+		return fmt.Errorf("usage: gitcommit notes show [commit]")
+	}
+	rev := "HEAD"
+	if len(args) == 2 {
+		rev = args[1]
+	}
+	output, err := gitCommand("notes", "--ref", ref, "show", rev).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("no rationale note for %s in %s", rev, ref)
+	}
+	fmt.Print(string(output))
+	return nil
+}
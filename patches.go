@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// rangeCommit is a commit read from a revision range.
+type rangeCommit struct {
+	Sha     string
+	Subject string
+	Body    string
+	Stat    string
+}
+
+// rangeCommits reads the commits in a revision range, oldest first.
+func rangeCommits(rng string) ([]rangeCommit, error) {
+	output, err := gitCommand("log", "--reverse", "--format=%H%x00%s%x00%b%x1e", rng, "--").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error reading commits in %s: %v", rng, err)
+	}
+	var commits []rangeCommit
+	for _, record := range strings.Split(string(output), "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		c := rangeCommit{Sha: fields[0], Subject: fields[1], Body: strings.TrimSpace(fields[2])}
+		if stat, err := gitCommand("show", "--stat", "--format=", c.Sha).Output(); err == nil {
+			c.Stat = strings.TrimSpace(string(stat))
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+func rangePrompt(commits []rangeCommit) string {
+	var b strings.Builder
+	for i, c := range commits {
+		fmt.Fprintf(&b, "Commit %d of %d (%s)\nSubject: %s\n", i+1, len(commits), c.Sha[:12], c.Subject)
+		if c.Body != "" {
+			fmt.Fprintf(&b, "Body:\n%s\n", c.Body)
+		}
+		if c.Stat != "" {
+			fmt.Fprintf(&b, "Files:\n%s\n", c.Stat)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+const patchSystemPrompt = `You help prepare patch series for mailing-list review with git format-patch.
+Given the commits in a series, write a cover letter and point out weak patch
+subjects. Respond with ONLY a JSON object wrapped in triple backticks, with
+these fields:
+  "subject": the cover letter subject, without any [PATCH] prefix
+  "body": the cover letter text summarizing the series for reviewers
+  "rewords": a list of {"sha": "<commit sha>", "subject": "<better subject>"}
+             for commits whose subjects are vague or misleading; leave it
+             empty when the existing subjects are fine`
+
+type patchSeriesSuggestion struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	Rewords []struct {
+		Sha     string `json:"sha"`
+		Subject string `json:"subject"`
+	} `json:"rewords"`
+}
+
+// coverLetter renders the cover letter by filling in git's own template, so
+// the file matches what `git format-patch --cover-letter` produces.
+func coverLetter(rng string, s patchSeriesSuggestion) (string, error) {
+	output, err := gitCommand("format-patch", "--cover-letter", "--stdout", rng).Output()
+	if err != nil {
+		return "", fmt.Errorf("error running git format-patch: %v", err)
+	}
+	letter := string(output)
+	// The cover letter is the first message of the mbox stream.
+	if i := strings.Index(letter[1:], "\nFrom "); i >= 0 {
+		letter = letter[:i+2]
+	}
+	letter = strings.Replace(letter, "*** SUBJECT HERE ***", s.Subject, 1)
+	letter = strings.Replace(letter, "*** BLURB HERE ***", strings.TrimSpace(s.Body), 1)
+	return letter, nil
+}
+
+// runPatchSubject implements `gitcommit patch-subject <range> [file]`.
+func runPatchSubject(args []string, profileName string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: gitcommit patch-subject <range> [cover-letter-file]")
+	}
+	rng := args[0]
+	out := "0000-cover-letter.patch"
+	if len(args) == 2 {
+		out = args[1]
+	}
+
+	commits, err := rangeCommits(rng)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("no commits in %s", rng)
+	}
+
+	apiKey, opts, err := subcommandAPI(profileName)
+	if err != nil {
+		return err
+	}
+	opts.System = patchSystemPrompt
+	response, err := askClaude(fmt.Sprintf("Here is a series of %d patches:\n\n%s", len(commits), rangePrompt(commits)), apiKey, opts)
+	if err != nil {
+		return err
+	}
+	var suggestion patchSeriesSuggestion
+	if err := json.Unmarshal([]byte(extractCommitMessage(response)), &suggestion); err != nil {
+		return fmt.Errorf("error parsing suggestion: %v", err)
+	}
+
+	letter, err := coverLetter(rng, suggestion)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, []byte(letter), 0644); err != nil {
+		return fmt.Errorf("error writing cover letter: %v", err)
+	}
+	fmt.Printf("[PATCH 0/%d] %s\n\n%s\n\nWrote cover letter to %s.\n", len(commits), suggestion.Subject, strings.TrimSpace(suggestion.Body), out)
+	fmt.Printf("Generate the patches next to it with:\n  git format-patch %s\n", rng)
+
+	if len(suggestion.Rewords) == 0 {
+		return nil
+	}
+	head, _ := gitRun("rev-parse", "HEAD")
+	fmt.Println("\nSuggested subject improvements (nothing has been changed):")
+	for _, r := range suggestion.Rewords {
+		var old string
+		for _, c := range commits {
+			if len(r.Sha) >= 7 && strings.HasPrefix(c.Sha, r.Sha) {
+				r.Sha, old = c.Sha, c.Subject
+			}
+		}
+		if old == "" {
+			continue
+		}
+		fmt.Printf("\n%s\n  was: %s\n  now: %s\n", r.Sha[:12], old, r.Subject)
+		if r.Sha == head {
+			fmt.Printf("  apply: git commit --amend --only -m %s\n", shellQuote(r.Subject))
+		} else {
+			fmt.Printf("  apply: git rebase -i %s~1  (mark the first commit as \"reword\")\n", r.Sha[:12])
+		}
+	}
+	return nil
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// subcommandAPI resolves the API key and user settings for subcommands that
+// call the API outside the main flow, once the first-run agreement to send
+// data to it has been given.
+func subcommandAPI(profileName string) (string, apiOptions, error) {
+	cfg, err := loadProfileConfig(profileName)
+	if err != nil {
+		return "", apiOptions{}, err
+	}
+	opts := apiOptions{Prefs: cfg.Prefs, Deadline: defaultRequestTimeout, ConnectTimeout: defaultConnectTimeout}
+	if opts.Keys, err = cfg.keyPool("", false); err != nil {
+		return "", apiOptions{}, err
+	}
+	var apiKey string
+	if opts.Keys != nil {
+		apiKey = opts.Keys.keys[0]
+	} else if apiKey, err = cfg.apiKey(false, false); err != nil {
+		return "", apiOptions{}, err
+	}
+	if apiKey == "" {
+		return "", apiOptions{}, fmt.Errorf("please set CLAUDE_API_KEY environment variable or store a key with gitcommit keyring set")
+	}
+	if err := requireConsent(); err != nil {
+		return "", apiOptions{}, err
+	}
+	return apiKey, opts, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// prRemote returns the remote the branch pushes to: its configured
+// remote, or origin.
+func prRemote(branch string) string {
+	if remote, err := gitRun("config", "--get", "branch."+branch+".remote"); err == nil && remote != "" && remote != "." {
+		return remote
+	}
+	return "origin"
+}
+
+// prBase returns the remote's default branch (e.g. main), which the pull
+// request targets.
+func prBase(remote string) (string, error) {
+	ref, err := gitRun("symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return "", fmt.Errorf("can't tell the default branch of %s; set it with: git remote set-head %s --auto", remote, remote)
+	}
+	return strings.TrimPrefix(ref, remote+"/"), nil
+}
+
+// existingPR returns the URL of the open pull request for the current
+// branch, if there is one.
+func existingPR() string {
+	output, err := exec.Command("gh", "pr", "view", "--json", "url", "--jq", ".url").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func pushBranch(remote, branch string) error {
+	args := []string{"push"}
+	if _, err := gitRun("rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		args = append(args, "-u", remote, branch)
+	}
+	cmd := gitCommand(args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error pushing %s: %v", branch, err)
+	}
+	return nil
+}
+
+// openPullRequest implements -pr: after a commit it pushes the branch and
+// creates a pull request with gh, using a title and body from
+// prDescription. Each step is confirmed first, and declining one stops
+// there.
+func openPullRequest(contextFiles []string, apiKey string, opts apiOptions) error {
+	branch := currentBranch()
+	if branch == "" {
+		return fmt.Errorf("HEAD is detached; check out a branch to open a pull request")
+	}
+	remote := prRemote(branch)
+	base, err := prBase(remote)
+	if err != nil {
+		return err
+	}
+	if branch == base {
+		return fmt.Errorf("%s is the default branch of %s; commit on another branch to open a pull request", branch, remote)
+	}
+
+	if ask("\nPush %s to %s? (y/n): ", branch, remote) != "y" {
+		fmt.Println("Not pushed; no pull request was created.")
+		return nil
+	}
+	if err := pushBranch(remote, branch); err != nil {
+		return err
+	}
+	if url := existingPR(); url != "" {
+		fmt.Printf("The branch already has a pull request, now updated: %s\n", url)
+		return nil
+	}
+
+	// The description is a one-off request, not part of the message
+	// conversation.
+	opts.History, opts.Stream = nil, false
+	fmt.Println("Writing the pull request description...")
+	s, err := prDescription(remote+"/"+base+"..HEAD", contextFiles, apiKey, opts)
+	if err != nil {
+		return err
+	}
+	title, body := strings.TrimSpace(s.Title), strings.TrimSpace(s.Body)
+	for {
+		fmt.Printf("\nPull request into %s:\n%s\n\n%s\n", base, title, body)
+		choice := getUserInput("\nCreate the pull request? (y/n/e to edit): ")
+		switch choice {
+		case "y":
+			cmd := exec.Command("gh", "pr", "create", "--base", base, "--head", branch, "--title", title, "--body-file", "-")
+			cmd.Stdin = strings.NewReader(body + "\n")
+			cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("error running gh pr create: %v", err)
+			}
+			return nil
+		case "n":
+			fmt.Println("No pull request was created; the branch is pushed, so you can still run gh pr create.")
+			return nil
+		case "e":
+			edited, err := runEditor(title + "\n\n" + body + "\n")
+			if err != nil {
+				say("Error: %v\n", err)
+				continue
+			}
+			first, rest, _ := strings.Cut(strings.TrimSpace(edited), "\n")
+			if strings.TrimSpace(first) == "" {
+				fmt.Println("The first line is the title and can't be empty.")
+				continue
+			}
+			title, body = strings.TrimSpace(first), strings.TrimSpace(rest)
+		default:
+			say("Invalid option. Please enter y, n, or e.\n")
+		}
+	}
+}
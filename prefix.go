@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Where -subject-prefix goes relative to a conventional commit type.
+const (
+	// prefixAfterType gives "feat: [JIRA-123] ...", which keeps the subject
+	// parseable by conventional commit tooling.
+	prefixAfterType = "after-type"
+	// prefixBeforeType gives "[JIRA-123] feat: ...".
+	prefixBeforeType = "before-type"
+)
+
+// ticketRe finds an issue key such as JIRA-123 in a branch name.
+var ticketRe = regexp.MustCompile(`[A-Z][A-Z0-9]+-[0-9]+`)
+
+// currentBranch returns the checked-out branch, or "" when HEAD is
+// detached. It works before the first commit, too.
+func currentBranch() string {
+	output, err := gitCommand("symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// expandPrefix substitutes {branch} and {ticket} in a -subject-prefix
+// template. It fails when the template needs a value the branch can't
+// provide, rather than producing a prefix like "[] ".
+func expandPrefix(tpl, branch string) (string, error) {
+	if strings.Contains(tpl, "{branch}") {
+		if branch == "" {
+			return "", fmt.Errorf("-subject-prefix uses {branch}, but HEAD is not on a branch")
+		}
+		tpl = strings.ReplaceAll(tpl, "{branch}", branch)
+	}
+	if strings.Contains(tpl, "{ticket}") {
+		ticket := ticketRe.FindString(strings.ToUpper(branch))
+		if ticket == "" {
+			return "", fmt.Errorf("-subject-prefix uses {ticket}, but branch %q has no ticket key like ABC-123", branch)
+		}
+		tpl = strings.ReplaceAll(tpl, "{ticket}", ticket)
+	}
+	return tpl, nil
+}
+
+// applySubjectPrefix prepends prefix to the subject, placing it before or
+// after a conventional commit type according to order. A subject that
+// already carries the prefix is left alone.
+func applySubjectPrefix(message, prefix, order string) string {
+	subject, rest, hasRest := strings.Cut(message, "\n")
+	if strings.Contains(subject, strings.TrimSpace(prefix)) {
+		return message
+	}
+	typ := conventionalRe.FindString(subject)
+	if order == prefixBeforeType || typ == "" {
+		subject = prefix + subject
+	} else {
+		subject = typ + prefix + subject[len(typ):]
+	}
+	if !hasRest {
+		return subject
+	}
+	return subject + "\n" + rest
+}
+
+func validPrefixOrder(order string) bool {
+	return order == prefixAfterType || order == prefixBeforeType
+}
+
+func subjectPrefixPrompt(prefix string) string {
+	return fmt.Sprintf("The prefix %q will be added to the subject afterwards; don't include it, and keep the rest of the subject within %d characters.", prefix, maxSubjectLength-utf8.RuneCountInString(prefix))
+}
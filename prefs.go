@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxPrefsLength is a sanity cap on the combined length of standing
+// instructions; past it they start crowding out the actual task.
+const maxPrefsLength = 1000
+
+func prefsLength(prefs []string) int {
+	n := 0
+	for _, p := range prefs {
+		n += len(p)
+	}
+	return n
+}
+
+// prefsPrompt formats standing instructions for the system prompt.
+func prefsPrompt(prefs []string) string {
+	if len(prefs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Always follow these standing instructions from the user:\n")
+	for _, p := range prefs {
+		fmt.Fprintf(&b, "- %s\n", p)
+	}
+	return b.String()
+}
+
+func warnPrefsLength(prefs []string) {
+	if n := prefsLength(prefs); n > maxPrefsLength {
+		fmt.Printf("Warning: standing instructions total %d characters (recommended maximum is %d); consider removing some\n", n, maxPrefsLength)
+	}
+}
+
+// runPrefs implements `gitcommit prefs add|list|remove`.
+func runPrefs(args []string) error {
+	const usage = `usage: gitcommit prefs add "<instruction>" | list | remove <number>`
+	if len(args) == 0 {
+		return fmt.Errorf(usage)
+	}
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		instruction := strings.TrimSpace(strings.Join(args[1:], " "))
+		if instruction == "" {
+			return fmt.Errorf(usage)
+		}
+		cfg.Prefs = append(cfg.Prefs, instruction)
+		if err := saveUserConfig(cfg); err != nil {
+			return err
+		}
+		fmt.Printf("Added standing instruction %d.\n", len(cfg.Prefs))
+		warnPrefsLength(cfg.Prefs)
+	case "list":
+		if len(cfg.Prefs) == 0 {
+			fmt.Println("No standing instructions.")
+			return nil
+		}
+		for i, p := range cfg.Prefs {
+			fmt.Printf("%d. %s\n", i+1, p)
+		}
+		warnPrefsLength(cfg.Prefs)
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf(usage)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 || n > len(cfg.Prefs) {
+			return fmt.Errorf("no standing instruction %q; see gitcommit prefs list", args[1])
+		}
+		removed := cfg.Prefs[n-1]
+		cfg.Prefs = append(cfg.Prefs[:n-1], cfg.Prefs[n:]...)
+		if err := saveUserConfig(cfg); err != nil {
+			return err
+		}
+		fmt.Printf("Removed: %s\n", removed)
+	default:
+		return fmt.Errorf(usage)
+	}
+	return nil
+}
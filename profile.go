@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// profileEnv selects a profile when -profile isn't given.
+const profileEnv = "GITCOMMIT_PROFILE"
+
+// providerAnthropic is the only supported provider; profiles name it so a
+// config written for another provider fails loudly instead of leaking.
+const providerAnthropic = "anthropic"
+
+// profile bundles the settings that differ between accounts, e.g. personal
+// open source work and a corporate gateway. Settings it leaves out keep
+// their top-level values.
+type profile struct {
+	Provider string `json:"provider,omitempty"`
+	// APIURL replaces the messages endpoint, e.g. for a corporate gateway.
+	APIURL string `json:"api_url,omitempty"`
+	// APIKeyEnv names the environment variable holding this profile's key.
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+	// KeyringAccount reads the key from the OS keyring under this account.
+	KeyringAccount string `json:"keyring_account,omitempty"`
+	// Scanner replaces the top-level secret scanner.
+	Scanner *scannerConfig `json:"secret_scanner,omitempty"`
+	// Prefs are added to the top-level standing instructions.
+	Prefs []string `json:"prefs,omitempty"`
+	// Remotes are patterns such as "github.com/mycorp/*" that select this
+	// profile automatically when a remote of the repository matches.
+	Remotes []string `json:"remotes,omitempty"`
+}
+
+// activeProfile records which profile is in use and why.
+type activeProfile struct {
+	Name   string
+	Reason string
+	*profile
+}
+
+// normalizeRemote turns a remote URL such as git@github.com:mycorp/x.git or
+// https://user@github.com/mycorp/x into github.com/mycorp/x.
+func normalizeRemote(url string) string {
+	url = strings.TrimSuffix(strings.TrimSpace(url), ".git")
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	} else if host, rest, ok := strings.Cut(url, ":"); ok && !strings.Contains(host, "/") {
+		// scp-like syntax: [user@]host:path
+		url = host + "/" + rest
+	}
+	if at := strings.Index(url, "@"); at >= 0 && at < strings.Index(url+"/", "/") {
+		url = url[at+1:]
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+// remoteURLs returns the normalized URLs of the repository's remotes.
+func remoteURLs() []string {
+	output, err := gitRun("config", "--get-regexp", `^remote\..*\.url$`)
+	if err != nil {
+		return nil
+	}
+	var urls []string
+	for _, line := range strings.Split(output, "\n") {
+		if _, url, ok := strings.Cut(line, " "); ok {
+			urls = append(urls, normalizeRemote(url))
+		}
+	}
+	return urls
+}
+
+// matchRemote reports whether url matches pattern. A trailing "/*" also
+// matches nested paths, so "gitlab.com/mycorp/*" covers subgroups.
+func matchRemote(pattern, url string) bool {
+	pattern = normalizeRemote(pattern)
+	if ok, _ := path.Match(pattern, url); ok {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(url, prefix+"/")
+	}
+	return false
+}
+
+// selectProfile picks the profile named by -profile, then by
+// GITCOMMIT_PROFILE, then the first one (by name) whose remote patterns
+// match this repository. It returns nil when no profile applies.
+func selectProfile(cfg *userConfig, name string) (*activeProfile, error) {
+	reason := "-profile flag"
+	if name == "" {
+		name, reason = os.Getenv(profileEnv), profileEnv+" environment variable"
+	}
+	if name != "" {
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q (from %s)", name, reason)
+		}
+		return &activeProfile{Name: name, Reason: reason, profile: p}, nil
+	}
+
+	names := make([]string, 0, len(cfg.Profiles))
+	for n := range cfg.Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	urls := remoteURLs()
+	for _, n := range names {
+		for _, pattern := range cfg.Profiles[n].Remotes {
+			for _, url := range urls {
+				if matchRemote(pattern, url) {
+					return &activeProfile{Name: n, Reason: fmt.Sprintf("remote %s matches %q", url, pattern), profile: cfg.Profiles[n]}, nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// applyProfile selects a profile and folds its settings into cfg and the
+// API endpoint.
+func applyProfile(cfg *userConfig, name string) error {
+	active, err := selectProfile(cfg, name)
+	if err != nil || active == nil {
+		return err
+	}
+	if active.Provider != "" && active.Provider != providerAnthropic {
+		return fmt.Errorf("profile %q: unsupported provider %q", active.Name, active.Provider)
+	}
+	if active.APIURL != "" {
+		apiURL = active.APIURL
+	}
+	if active.Scanner != nil {
+		cfg.Scanner = active.Scanner
+	}
+	cfg.Prefs = append(cfg.Prefs, active.Prefs...)
+	cfg.active = active
+	return nil
+}
+
+// loadProfileConfig loads the user config with the selected profile
+// applied. Use loadUserConfig instead when the config will be saved.
+func loadProfileConfig(name string) (*userConfig, error) {
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := applyProfile(cfg, name); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// apiKey resolves the key for the active profile. A profile with its own
+// key source never falls back to CLAUDE_API_KEY, so its diffs can't be sent
+// with another account's key by accident.
+func (c *userConfig) apiKey(useKeyring, verbose bool) (string, error) {
+	if p := c.active; p != nil {
+		switch {
+		case p.KeyringAccount != "":
+			key, err := keyringGet(p.KeyringAccount)
+			if err != nil {
+				return "", fmt.Errorf("profile %q: %v", p.Name, err)
+			}
+			return key, nil
+		case p.APIKeyEnv != "":
+			if key := os.Getenv(p.APIKeyEnv); key != "" {
+				return key, nil
+			}
+			return "", fmt.Errorf("profile %q: %s is not set", p.Name, p.APIKeyEnv)
+		}
+	}
+	return resolveAPIKey(useKeyring || c.Keyring, verbose), nil
+}
+
+// keyringAccount returns the keyring account for the active profile.
+func (c *userConfig) keyringAccount() string {
+	if c.active != nil && c.active.KeyringAccount != "" {
+		return c.active.KeyringAccount
+	}
+	return defaultKeyringAccount
+}
+
+// runProfile implements `gitcommit profile show`.
+func runProfile(args []string, name string) error {
+	if len(args) != 1 || args[0] != "show" {
+		return fmt.Errorf("usage: gitcommit profile show")
+	}
+	cfg, err := loadProfileConfig(name)
+	if err != nil {
+		return err
+	}
+	p := cfg.active
+	if p == nil {
+		fmt.Println("No profile is active; using the top-level settings.")
+		return nil
+	}
+	fmt.Printf("Active profile: %s\nChosen by: %s\n", p.Name, p.Reason)
+	fmt.Printf("API endpoint: %s\n", apiURL)
+	switch {
+	case p.KeyringAccount != "":
+		fmt.Printf("API key: keyring account %s\n", p.KeyringAccount)
+	case p.APIKeyEnv != "":
+		fmt.Printf("API key: $%s\n", p.APIKeyEnv)
+	default:
+		fmt.Println("API key: top-level setting")
+	}
+	if cfg.Scanner != nil {
+		fmt.Printf("Secret scanner: %s (policy %s)\n", strings.Join(cfg.Scanner.Command, " "), cfg.Scanner.policy())
+	} else {
+		fmt.Println("Secret scanner: none")
+	}
+	for _, pref := range p.Prefs {
+		fmt.Printf("Instruction: %s\n", pref)
+	}
+	return nil
+}
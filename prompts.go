@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// promptSet is one version of the built-in prompts. Output quality and
+// learned data (hints, rejection logs, metrics) are only comparable within
+// a version, so every change to these strings gets a new version, and old
+// versions stay available to -prompt-version for at least one major
+// release.
+type promptSet struct {
+	// System is the system prompt for message generation.
+	System string
+	// Message is the user prompt; it is formatted with the user's original
+	// message and the description of the changes.
+	Message string
+}
+
+// currentPromptVersion is the version used unless -prompt-version pins
+// another.
+const currentPromptVersion = "v1"
+
+var builtinPrompts = map[string]promptSet{
+	"v1": {
+		System: `You are a Git commit message assistant. If you need more context, ask exactly one clear question. 
+If you have enough context, provide ONLY the commit message without any explanations or questions. 
+The commit message should follow best practices and be wrapped in triple backticks.`,
+		Message: `Help me write a better git commit message. Here's my original message:
+"%s"
+
+%s`,
+	},
+}
+
+// promptVersion and activePrompt are the prompts in use for this run.
+var (
+	promptVersion = currentPromptVersion
+	activePrompt  = builtinPrompts[currentPromptVersion]
+)
+
+func promptVersions() []string {
+	versions := make([]string, 0, len(builtinPrompts))
+	for v := range builtinPrompts {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// usePromptVersion pins the run to a built-in prompt version.
+func usePromptVersion(version string) error {
+	p, ok := builtinPrompts[version]
+	if !ok {
+		return fmt.Errorf("unknown prompt version %q (available: %s)", version, strings.Join(promptVersions(), ", "))
+	}
+	promptVersion, activePrompt = version, p
+	return nil
+}
+
+// notePromptUpgrade prints a one-time notice when the built-in prompt
+// version differs from the one this repository last ran with, since
+// learned hints and earlier results came from the old prompts. Pinned
+// runs neither trigger nor record it.
+func notePromptUpgrade() {
+	if promptVersion != currentPromptVersion {
+		return
+	}
+	state, err := loadRepoState()
+	if err != nil || state.PromptVersion == currentPromptVersion {
+		return
+	}
+	if old := state.PromptVersion; old != "" {
+		fmt.Printf("Note: the built-in prompts changed from %s to %s in this version of gitcommit; learned hints and earlier results came from %s.\n", old, currentPromptVersion, old)
+		if _, ok := builtinPrompts[old]; ok {
+			fmt.Printf("Use -prompt-version %s to compare with or return to the old prompts.\n", old)
+		}
+	}
+	state.PromptVersion = currentPromptVersion
+	saveRepoState(state)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureStdout returns what f prints to standard output.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	defer func() { os.Stdout = old }()
+	f()
+	w.Close()
+	return <-done
+}
+
+func restorePrompt(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() { promptVersion, activePrompt = currentPromptVersion, builtinPrompts[currentPromptVersion] })
+}
+
+func TestBuiltinPromptVersions(t *testing.T) {
+	restorePrompt(t)
+	if _, ok := builtinPrompts[currentPromptVersion]; !ok {
+		t.Fatalf("current prompt version %q is not a built-in version", currentPromptVersion)
+	}
+	for _, v := range promptVersions() {
+		if err := usePromptVersion(v); err != nil {
+			t.Fatalf("usePromptVersion(%q): %v", v, err)
+		}
+		if promptVersion != v || activePrompt != builtinPrompts[v] {
+			t.Errorf("usePromptVersion(%q) left version %q", v, promptVersion)
+		}
+		if activePrompt.System == "" || strings.Count(activePrompt.Message, "%s") != 2 {
+			t.Errorf("%s: prompts are incomplete: %+v", v, activePrompt)
+		}
+
+		// Every record of the run carries the identifier.
+		m := &runMetrics{Start: time.Now(), Model: "m", PromptVersion: promptVersion, Outcome: "accepted"}
+		for _, line := range m.statsdLines() {
+			if !strings.Contains(line, ",prompt:"+v+",") {
+				t.Errorf("%s: statsd line %q has no prompt tag", v, line)
+			}
+		}
+		record := contextRecord{Sha: strings.Repeat("a", 40), Message: "Fix it", PromptVersion: promptVersion}
+		if !strings.Contains(record.markdown(), "Prompt version: "+v+"\n") {
+			t.Errorf("%s: exported context has no prompt version:\n%s", v, record.markdown())
+		}
+	}
+}
+
+func TestUsePromptVersionUnknown(t *testing.T) {
+	restorePrompt(t)
+	err := usePromptVersion("v0")
+	if err == nil || !strings.Contains(err.Error(), currentPromptVersion) {
+		t.Fatalf("usePromptVersion(v0) = %v, want an error listing the versions", err)
+	}
+	if promptVersion != currentPromptVersion {
+		t.Errorf("a failed pin changed the version to %q", promptVersion)
+	}
+}
+
+func TestNotePromptUpgrade(t *testing.T) {
+	testRepo(t)
+	restorePrompt(t)
+
+	// A first run only records the version.
+	if out := captureStdout(t, notePromptUpgrade); out != "" {
+		t.Errorf("first run printed %q", out)
+	}
+	state, err := loadRepoState()
+	if err != nil || state.PromptVersion != currentPromptVersion {
+		t.Fatalf("state after first run = %+v, %v", state, err)
+	}
+
+	// After an upgrade the notice appears once.
+	state.PromptVersion = "v0"
+	if err := saveRepoState(state); err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t, notePromptUpgrade)
+	if !strings.Contains(out, "changed from v0 to "+currentPromptVersion) {
+		t.Errorf("upgrade notice = %q", out)
+	}
+	if out := captureStdout(t, notePromptUpgrade); out != "" {
+		t.Errorf("second run printed %q", out)
+	}
+
+	// A pinned run neither prints nor records anything.
+	state.PromptVersion = "v0"
+	if err := saveRepoState(state); err != nil {
+		t.Fatal(err)
+	}
+	promptVersion = "v0"
+	if out := captureStdout(t, notePromptUpgrade); out != "" {
+		t.Errorf("pinned run printed %q", out)
+	}
+	if state, _ := loadRepoState(); state.PromptVersion != "v0" {
+		t.Errorf("pinned run recorded %q", state.PromptVersion)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// questionFile holds a clarifying question that came up when nobody could
+// answer it, such as in a hook or with stdin redirected.
+const questionFile = "GITCOMMIT_QUESTION"
+
+// exitQuestionPending is the exit status when gitcommit stopped to wait for
+// an answer, so scripts can tell it apart from errors.
+const exitQuestionPending = 3
+
+// questionSessionMarker separates the editable part of the question file
+// from the saved session.
+const questionSessionMarker = "# ---- saved session; keep everything below this line ----"
+
+// pendingQuestion is a run paused on a question. It is resumed by running
+// gitcommit again once the answer is in the file.
+type pendingQuestion struct {
+	// Args are the flags of the paused run, used by `gitcommit answer`.
+	Args []string `json:"args"`
+	// Key identifies HEAD and the staged tree; a different key means the
+	// changes moved on and the session no longer applies.
+	Key        string            `json:"key"`
+	Message    string            `json:"message"`
+	Prompt     string            `json:"prompt"`
+	Exchanges  []exchange        `json:"exchanges,omitempty"`
+	Question   string            `json:"question"`
+	KindValues map[string]string `json:"kind_values,omitempty"`
+
+	// Answer is read from the editable part of the file.
+	Answer string `json:"-"`
+}
+
+func questionPath() (string, error) {
+	output, err := gitCommand("rev-parse", "--git-path", questionFile).Output()
+	if err != nil {
+		return "", fmt.Errorf("error locating git directory: %v", err)
+	}
+	return filepath.Abs(strings.TrimSpace(string(output)))
+}
+
+// questionKey identifies the commit being written: HEAD and the staged tree.
+func questionKey() string {
+	head, _ := gitRun("rev-parse", "--verify", "--quiet", "HEAD")
+	tree, _ := gitRun("write-tree")
+	return head + " " + tree
+}
+
+// stdinIsTerminal reports whether questions can be asked interactively.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (q *pendingQuestion) render() (string, error) {
+	session, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding session: %v", err)
+	}
+	var b strings.Builder
+	b.WriteString("# gitcommit stopped at a question it could not ask interactively.\n")
+	b.WriteString("# Write your answer below \"Answer:\" and run gitcommit again, or run\n")
+	b.WriteString("#   gitcommit answer \"<your answer>\"\n\n")
+	fmt.Fprintf(&b, "Question:\n%s\n\nAnswer:\n", strings.TrimSpace(q.Question))
+	if q.Answer != "" {
+		b.WriteString(q.Answer + "\n")
+	}
+	fmt.Fprintf(&b, "\n%s\n%s\n", questionSessionMarker, session)
+	return b.String(), nil
+}
+
+func parsePendingQuestion(text string) (*pendingQuestion, error) {
+	notes, session, ok := strings.Cut(text, questionSessionMarker+"\n")
+	if !ok {
+		return nil, fmt.Errorf("the saved session is missing")
+	}
+	q := &pendingQuestion{}
+	if err := json.Unmarshal([]byte(session), q); err != nil {
+		return nil, fmt.Errorf("error parsing the saved session: %v", err)
+	}
+	// The question itself may contain anything, so the last Answer: line
+	// is the one gitcommit wrote.
+	if i := strings.LastIndex(notes, "\nAnswer:\n"); i >= 0 {
+		q.Answer = strings.TrimSpace(notes[i+len("\nAnswer:\n"):])
+	}
+	return q, nil
+}
+
+func savePendingQuestion(q *pendingQuestion) (string, error) {
+	p, err := questionPath()
+	if err != nil {
+		return "", err
+	}
+	text, err := q.render()
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(p, []byte(text), 0644); err != nil {
+		return "", fmt.Errorf("error writing %s: %v", questionFile, err)
+	}
+	return p, nil
+}
+
+// loadPendingQuestion returns the paused run, or nil if there is none.
+func loadPendingQuestion() (*pendingQuestion, error) {
+	p, err := questionPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", questionFile, err)
+	}
+	q, err := parsePendingQuestion(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", p, err)
+	}
+	return q, nil
+}
+
+func clearPendingQuestion() {
+	if p, err := questionPath(); err == nil {
+		os.Remove(p)
+	}
+}
+
+// stopForAnswer saves the run at its question and exits with
+// exitQuestionPending, after the usual abort cleanups.
+func stopForAnswer(q *pendingQuestion) {
+	p, err := savePendingQuestion(q)
+	if err != nil {
+		say("Error: %v\n", err)
+		os.Exit(1)
+	}
+	for i := len(atAbort) - 1; i >= 0; i-- {
+		atAbort[i]()
+	}
+	fmt.Printf("\nClaude asks: %s\n\n", strings.TrimSpace(q.Question))
+	fmt.Printf("There is no terminal to answer on, so the question was saved to\n  %s\n", p)
+	fmt.Printf("Answer it there and run gitcommit again, or run:\n  gitcommit answer \"<your answer>\"\n")
+	os.Exit(exitQuestionPending)
+}
+
+// resumeQuestion returns a paused run that is ready to continue, asking
+// its question now when there is a terminal. A run whose changes have
+// moved on is discarded; one still waiting for an answer without a
+// terminal stops again.
+func resumeQuestion() *pendingQuestion {
+	q, err := loadPendingQuestion()
+	if err != nil {
+		say("Warning: %v\n", err)
+		return nil
+	}
+	if q == nil {
+		return nil
+	}
+	if q.Key != questionKey() {
+		fmt.Printf("Warning: discarding the question saved in %s; the staged changes have changed since it was asked.\n", questionFile)
+		clearPendingQuestion()
+		return nil
+	}
+	if q.Answer == "" {
+		if !stdinIsTerminal() {
+			stopForAnswer(q)
+		}
+		q.Answer = ask("\nResuming an earlier run. Claude asks: %s\nYour response: ", strings.TrimSpace(q.Question))
+	} else {
+		fmt.Printf("Resuming an earlier run with your answer to: %s\n", strings.TrimSpace(q.Question))
+	}
+	clearPendingQuestion()
+	return q
+}
+
+// runAnswer implements `gitcommit answer <text>`: it records the answer
+// and runs gitcommit again with the paused run's flags to finish it.
+func runAnswer(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: gitcommit answer \"<your answer>\"")
+	}
+	q, err := loadPendingQuestion()
+	if err != nil {
+		return err
+	}
+	if q == nil {
+		return fmt.Errorf("no question is waiting for an answer")
+	}
+	q.Answer = strings.Join(args, " ")
+	if _, err := savePendingQuestion(q); err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error locating gitcommit: %v", err)
+	}
+	cmd := exec.Command(exe, q.Args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("error resuming: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// rejection is one entry in the rejection log, a JSON object per line.
+type rejection struct {
+	Time          time.Time `json:"time"`
+	Model         string    `json:"model"`
+	PromptVersion string    `json:"prompt_version"`
+	Suggestion    string    `json:"suggestion"`
+	Reason        string    `json:"reason"`
+}
+
+func rejectionLogPath() (string, error) {
+	dir, err := repoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rejections.jsonl"), nil
+}
+
+// logRejection appends a rejection to the repository's log. The log stays
+// local; nothing in it is sent anywhere.
+func logRejection(r rejection) error {
+	p, err := rejectionLogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("error creating state directory: %v", err)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("error encoding rejection: %v", err)
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening rejection log: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing rejection log: %v", err)
+	}
+	return nil
+}
+
+// explainRejection asks why a suggestion was rejected and logs the answer.
+// It returns the reason only if the user also wants it used as feedback
+// for the next suggestion; otherwise the reason never reaches the API.
+func explainRejection(suggestion string) string {
+	reason := getUserInput("Why didn't it fit? (optional, kept in a local log; Enter to skip): ")
+	if reason == "" {
+		return ""
+	}
+	if err := logRejection(rejection{Time: time.Now().UTC(), Model: claudeModel, PromptVersion: promptVersion, Suggestion: suggestion, Reason: reason}); err != nil {
+		say("Warning: %v\n", err)
+	}
+	if getUserInput("Also use this as feedback for the next suggestion? (y/n): ") != "y" {
+		return ""
+	}
+	return reason
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// repoFileName is the settings file a repository can commit for everyone
+// working in it.
+const repoFileName = ".gitcommit.toml"
+
+// readRepoFile returns the settings in the repository's .gitcommit.toml,
+// keyed like "lang" or "table.key", and the file's path. A missing file
+// gives no settings and no error.
+func readRepoFile() (map[string]string, string, error) {
+	root, err := gitRun("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, "", nil
+	}
+	p := filepath.Join(root, repoFileName)
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, p, nil
+	}
+	if err != nil {
+		return nil, p, fmt.Errorf("error reading %s: %v", repoFileName, err)
+	}
+	values, err := parseRepoFile(string(data))
+	if err != nil {
+		return nil, p, fmt.Errorf("%s: %v", repoFileName, err)
+	}
+	return values, p, nil
+}
+
+// parseRepoFile reads the small subset of TOML gitcommit needs: [tables],
+// and key = value lines with quoted strings, numbers or booleans.
+func parseRepoFile(text string) (map[string]string, error) {
+	values := map[string]string{}
+	table := ""
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated table header", i+1)
+			}
+			table = strings.TrimSpace(strings.Trim(line, "[]"))
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		key = strings.TrimSpace(key)
+		value, err := tomlValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// tomlValue unquotes a string value and drops a trailing comment.
+func tomlValue(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		for i := 1; i < len(v); i++ {
+			switch v[i] {
+			case '\\':
+				i++
+			case '"':
+				return strconv.Unquote(v[:i+1])
+			}
+		}
+		return "", fmt.Errorf("unterminated string")
+	case strings.HasPrefix(v, "'"):
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return v[1 : end+1], nil
+	}
+	v, _, _ = strings.Cut(v, "#")
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	return v, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ansiRe matches CSI sequences (colors, cursor movement) and OSC sequences
+// (terminal titles, hyperlinks) as pasted from a terminal.
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// zeroWidth are the invisible characters that only get into a message by
+// accident. The zero width joiner and non-joiner are left alone: emoji
+// sequences and Persian and Indic scripts need them.
+var zeroWidth = map[rune]bool{
+	'\u200b': true, // zero width space
+	'\u2060': true, // word joiner
+	'\ufeff': true, // byte order mark / zero width no-break space
+}
+
+// trimInvisible trims white space and zero-width characters from both ends
+// of s, so markup such as a fence is recognized without changing the text.
+func trimInvisible(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool { return unicode.IsSpace(r) || zeroWidth[r] })
+}
+
+var asciiPunct = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`,
+	"–", "-", "—", "--", "…", "...",
+)
+
+// sanitizeMessage cleans up text pasted from chat apps and terminals. It
+// strips ANSI escapes, zero-width spaces and control characters (keeping
+// newlines and tabs), replaces invalid UTF-8, and optionally normalizes
+// curly quotes and dashes to ASCII. It returns the cleaned message and a description of
+// each kind of change made.
+func sanitizeMessage(message string, normalizePunct bool) (string, []string) {
+	var changes []string
+
+	if !utf8.ValidString(message) {
+		message = strings.ToValidUTF8(message, "\uFFFD")
+		changes = append(changes, "replaced invalid UTF-8 byte sequences with U+FFFD")
+	}
+
+	if n := len(ansiRe.FindAllStringIndex(message, -1)); n > 0 {
+		message = ansiRe.ReplaceAllString(message, "")
+		changes = append(changes, fmt.Sprintf("removed %d ANSI escape sequence(s)", n))
+	}
+
+	// CRLF line endings are normalized silently; they are not worth a veto.
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+
+	var zw, ctrl int
+	message = strings.Map(func(r rune) rune {
+		switch {
+		case zeroWidth[r]:
+			zw++
+			return -1
+		case r == '\n' || r == '\t':
+			return r
+		case unicode.IsControl(r):
+			ctrl++
+			return -1
+		}
+		return r
+	}, message)
+	if zw > 0 {
+		changes = append(changes, fmt.Sprintf("removed %d zero-width character(s)", zw))
+	}
+	if ctrl > 0 {
+		changes = append(changes, fmt.Sprintf("removed %d control character(s)", ctrl))
+	}
+
+	if normalizePunct {
+		if normalized := asciiPunct.Replace(message); normalized != message {
+			message = normalized
+			changes = append(changes, "converted curly quotes and dashes to ASCII")
+		}
+	}
+
+	return message, changes
+}
+
+// offerSanitized sanitizes message and, if anything non-trivial changed,
+// shows the changes and lets the user veto them.
+func offerSanitized(message string, normalizePunct bool) string {
+	cleaned, changes := sanitizeMessage(message, normalizePunct)
+	if len(changes) == 0 {
+		return cleaned
+	}
+	fmt.Println("\nThe message was sanitized:")
+	for _, c := range changes {
+		fmt.Printf("  - %s\n", c)
+	}
+	fmt.Printf("\nSanitized message:\n%s\n", cleaned)
+	if getUserInput("Use the sanitized message? (y/n): ") != "y" {
+		return message
+	}
+	return cleaned
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeMessage(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+		punct          bool
+		changes        int
+	}{
+		{"plain", "Fix the parser\n\nIt was wrong.", "Fix the parser\n\nIt was wrong.", false, 0},
+		{"crlf", "Fix it\r\n\r\nBody", "Fix it\n\nBody", false, 0},
+		{"ansi", "\x1b[1;32mFix\x1b[0m it", "Fix it", false, 1},
+		{"zero width space", "Fix\u200b it\u2060\ufeff", "Fix it", false, 1},
+		{"emoji zwj sequence", "Add family 👨\u200d👩\u200d👧", "Add family 👨\u200d👩\u200d👧", false, 0},
+		{"persian zwnj", "می\u200cخواهم", "می\u200cخواهم", false, 0},
+		{"control", "Fix\x07 it\tnow", "Fix it\tnow", false, 1},
+		{"invalid utf-8", "Fix \xff it", "Fix � it", false, 1},
+		{"curly quotes kept", "Don’t “break” it…", "Don’t “break” it…", false, 0},
+		{"curly quotes normalized", "Don’t “break” it — ever…", `Don't "break" it -- ever...`, true, 1},
+	}
+	for _, tt := range tests {
+		got, changes := sanitizeMessage(tt.in, tt.punct)
+		if got != tt.want {
+			t.Errorf("%s: sanitizeMessage(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+		if len(changes) != tt.changes {
+			t.Errorf("%s: changes = %q, want %d", tt.name, changes, tt.changes)
+		}
+	}
+}
+
+func TestOfferSanitizedKeepsOriginal(t *testing.T) {
+	defer func(old bool) { assumeYes = old }(assumeYes)
+	assumeYes = false
+	message := "Fix\u200b it"
+	for _, answer := range []string{"n", "", "yes please", "N"} {
+		withInput(t, answer+"\n")
+		if got := offerSanitized(message, false); got != message {
+			t.Errorf("answer %q: got %q, want the original kept", answer, got)
+		}
+	}
+	withInput(t, "y\n")
+	if got := offerSanitized(message, false); got != "Fix it" {
+		t.Errorf("answer y: got %q, want the sanitized message", got)
+	}
+}
+
+// withInput makes the prompts read their answers from input for the rest
+// of the test.
+func withInput(t *testing.T, input string) {
+	t.Helper()
+	old := stdinReader
+	stdinReader = bufio.NewReader(strings.NewReader(input))
+	t.Cleanup(func() { stdinReader = old })
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Secret scanner policies.
+const (
+	// scanBlock refuses to send anything when the scanner reports findings
+	// or fails to run.
+	scanBlock = "block"
+	// scanRedact replaces the offending lines and fails closed like block.
+	scanRedact = "redact"
+	// scanWarn shows findings and continues; a scanner failure only warns.
+	scanWarn = "warn"
+)
+
+// Scanner output formats.
+const (
+	scanFormatGeneric  = "generic"
+	scanFormatGitleaks = "gitleaks"
+)
+
+const defaultScanTimeout = 30 * time.Second
+
+// scannerConfig configures an external secret scanner. The scanner receives
+// the content that would be sent to the API on stdin and prints findings as
+// JSON on stdout.
+type scannerConfig struct {
+	// Command is the scanner and its arguments; no shell is involved.
+	Command []string `json:"command"`
+	// Format is "generic" (default) or "gitleaks".
+	Format string `json:"format,omitempty"`
+	// Policy is "block" (default), "redact" or "warn".
+	Policy string `json:"policy,omitempty"`
+	// TimeoutSeconds bounds the scanner's run time (default 30).
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// scanFinding is one reported secret. This is also the generic schema other
+// scanners can target: a JSON array of these objects, where line is the
+// 1-based line number in the scanned input.
+type scanFinding struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Rule   string `json:"rule"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// gitleaksFinding is the subset of gitleaks' JSON report we use.
+type gitleaksFinding struct {
+	RuleID    string `json:"RuleID"`
+	File      string `json:"File"`
+	StartLine int    `json:"StartLine"`
+	Secret    string `json:"Secret"`
+}
+
+func (c *scannerConfig) policy() string {
+	if c.Policy == "" {
+		return scanBlock
+	}
+	return c.Policy
+}
+
+func (c *scannerConfig) validate() error {
+	if len(c.Command) == 0 {
+		return fmt.Errorf("secret scanner command is empty")
+	}
+	switch c.policy() {
+	case scanBlock, scanRedact, scanWarn:
+	default:
+		return fmt.Errorf("unknown secret scanner policy %q", c.Policy)
+	}
+	switch c.Format {
+	case "", scanFormatGeneric, scanFormatGitleaks:
+	default:
+		return fmt.Errorf("unknown secret scanner format %q", c.Format)
+	}
+	return nil
+}
+
+// parseFindings decodes scanner output in the configured format.
+func parseFindings(format string, output []byte) ([]scanFinding, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return nil, nil
+	}
+	if format == scanFormatGitleaks {
+		var leaks []gitleaksFinding
+		if err := json.Unmarshal(output, &leaks); err != nil {
+			return nil, err
+		}
+		findings := make([]scanFinding, 0, len(leaks))
+		for _, l := range leaks {
+			findings = append(findings, scanFinding{Path: l.File, Line: l.StartLine, Rule: l.RuleID, Secret: l.Secret})
+		}
+		return findings, nil
+	}
+	var findings []scanFinding
+	if err := json.Unmarshal(output, &findings); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// runScanner runs the scanner over content. Scanners such as gitleaks exit
+// non-zero when they find something, so a non-zero exit is only treated as
+// a failure when the output isn't valid findings JSON.
+func runScanner(c *scannerConfig, content string) ([]scanFinding, error) {
+	timeout := defaultScanTimeout
+	if c.TimeoutSeconds > 0 {
+		timeout = time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	cmd.Stdin = strings.NewReader(content)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, runErr := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("secret scanner timed out after %s", timeout)
+	}
+	if runErr != nil && len(bytes.TrimSpace(output)) == 0 {
+		return nil, scannerError(runErr, stderr.String())
+	}
+	findings, parseErr := parseFindings(c.Format, output)
+	if parseErr == nil {
+		return findings, nil
+	}
+	if runErr != nil {
+		return nil, scannerError(runErr, stderr.String())
+	}
+	return nil, fmt.Errorf("error parsing secret scanner output: %v", parseErr)
+}
+
+func scannerError(err error, stderr string) error {
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return fmt.Errorf("secret scanner failed: %v: %s", err, stderr)
+	}
+	return fmt.Errorf("secret scanner failed: %v", err)
+}
+
+// redactLines replaces every line with a finding, and any other occurrence
+// of a reported secret value.
+func redactLines(content string, findings []scanFinding) string {
+	lines := strings.Split(content, "\n")
+	for _, f := range findings {
+		if f.Line >= 1 && f.Line <= len(lines) {
+			lines[f.Line-1] = fmt.Sprintf("[line redacted by secret scanner: %s]", f.Rule)
+		}
+	}
+	content = strings.Join(lines, "\n")
+	for _, f := range findings {
+		if f.Secret != "" {
+			content = strings.ReplaceAll(content, f.Secret, "[REDACTED]")
+		}
+	}
+	return content
+}
+
+func printFindings(findings []scanFinding) {
+	fmt.Printf("Secret scanner reported %d finding(s):\n", len(findings))
+	for _, f := range findings {
+		where := fmt.Sprintf("line %d", f.Line)
+		if f.Path != "" {
+			where = fmt.Sprintf("%s:%d", f.Path, f.Line)
+		}
+		fmt.Printf("  - %s (%s)\n", where, f.Rule)
+	}
+}
+
+// scanContent applies the scanner policy to content that is about to be
+// sent. It returns the content to send, possibly redacted, or an error if
+// sending must be blocked. flagged is true unless the scanner ran and found
+// nothing, so callers know not to keep copies of the content.
+func scanContent(c *scannerConfig, content string) (result string, flagged bool, err error) {
+	if err := c.validate(); err != nil {
+		return "", true, err
+	}
+	findings, err := runScanner(c, content)
+	if err != nil {
+		if c.policy() == scanWarn {
+			fmt.Printf("\n!!! WARNING: %v\n!!! Continuing WITHOUT a secret scan (policy \"warn\").\n\n", err)
+			return content, true, nil
+		}
+		return "", true, fmt.Errorf("%v; nothing was sent (policy %q)", err, c.policy())
+	}
+	if len(findings) == 0 {
+		return content, false, nil
+	}
+
+	printFindings(findings)
+	switch c.policy() {
+	case scanWarn:
+		fmt.Println("Continuing anyway (policy \"warn\").")
+		return content, true, nil
+	case scanRedact:
+		fmt.Println("Redacted the affected lines before sending (policy \"redact\").")
+		return redactLines(content, findings), true, nil
+	}
+	return "", true, fmt.Errorf("secret scanner found possible secrets; nothing was sent (policy \"block\")")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// stubScanner writes a scanner script that reports a finding for a line
+// containing AKIA (exiting 1, like gitleaks), fails on CRASH, prints
+// garbage on GARBAGE and otherwise finds nothing.
+func stubScanner(t *testing.T) []string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub scanner is a shell script")
+	}
+	script := `#!/bin/sh
+input=$(cat)
+case "$input" in
+*CRASH*) echo "scanner exploded" >&2; exit 2 ;;
+*GARBAGE*) echo "not json"; exit 0 ;;
+*AKIA*)
+	line=$(printf '%s\n' "$input" | grep -n AKIA | head -n 1 | cut -d: -f1)
+	printf '[{"path":"config.go","line":%s,"rule":"aws-access-key","secret":"AKIA1234"}]\n' "$line"
+	exit 1 ;;
+esac
+exit 0
+`
+	path := filepath.Join(t.TempDir(), "scanner")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return []string{path}
+}
+
+func TestScanContent(t *testing.T) {
+	command := stubScanner(t)
+	const (
+		clean  = "diff --git a/x b/x\n+hello\n"
+		secret = "diff --git a/config.go b/config.go\n+key := \"AKIA1234\"\n+other := 1\n"
+	)
+	tests := []struct {
+		name, policy, content string
+		want                  string
+		flagged               bool
+		wantErr               string
+	}{
+		{"clean", scanBlock, clean, clean, false, ""},
+		{"hit blocks", scanBlock, secret, "", true, "found possible secrets"},
+		{"hit default policy blocks", "", secret, "", true, "found possible secrets"},
+		{"hit redacts", scanRedact, secret, "diff --git a/config.go b/config.go\n[line redacted by secret scanner: aws-access-key]\n+other := 1\n", true, ""},
+		{"hit warns", scanWarn, secret, secret, true, ""},
+		{"failure blocks", scanBlock, "CRASH", "", true, "scanner exploded"},
+		{"failure fails closed with redact", scanRedact, "CRASH", "", true, "nothing was sent"},
+		{"failure warns", scanWarn, "CRASH", "CRASH", true, ""},
+		{"bad output blocks", scanBlock, "GARBAGE", "", true, "error parsing secret scanner output"},
+	}
+	for _, tt := range tests {
+		got, flagged, err := scanContent(&scannerConfig{Command: command, Policy: tt.policy}, tt.content)
+		if flagged != tt.flagged {
+			t.Errorf("%s: flagged = %v, want %v", tt.name, flagged, tt.flagged)
+		}
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want one containing %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScannerMissing(t *testing.T) {
+	c := &scannerConfig{Command: []string{filepath.Join(t.TempDir(), "no-such-scanner")}}
+	if _, _, err := scanContent(c, "x"); err == nil || !strings.Contains(err.Error(), "secret scanner failed") {
+		t.Errorf("missing scanner: err = %v, want a failure", err)
+	}
+}
+
+func TestParseFindingsGitleaks(t *testing.T) {
+	output := `[{"RuleID":"generic-api-key","File":"a.env","StartLine":3,"Secret":"s3cr3t","Match":"KEY=s3cr3t"}]`
+	findings, err := parseFindings(scanFormatGitleaks, []byte(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := scanFinding{Path: "a.env", Line: 3, Rule: "generic-api-key", Secret: "s3cr3t"}
+	if len(findings) != 1 || findings[0] != want {
+		t.Errorf("findings = %+v, want %+v", findings, want)
+	}
+}
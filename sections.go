@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultSections are used by -sections when neither the config nor a
+// commit template names any.
+var defaultSections = []string{"What", "Why", "How"}
+
+// sectionRe matches a section heading such as "Why:" at the start of a line.
+var sectionRe = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9 _-]{0,30}):(?:\s|$)`)
+
+// section is a named part of a structured commit body. Optional sections
+// are written with a trailing "?" in the configuration, e.g. "Testing?".
+type section struct {
+	Name     string
+	Optional bool
+}
+
+func parseSections(names []string) []section {
+	var sections []section
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		optional := strings.HasSuffix(n, "?")
+		if n = strings.TrimSuffix(n, "?"); n != "" {
+			sections = append(sections, section{Name: n, Optional: optional})
+		}
+	}
+	return sections
+}
+
+// templateSections reads the section headings from the file configured as
+// git's commit.template, if any.
+func templateSections() []string {
+	path, err := gitRun("config", "--path", "commit.template")
+	if err != nil || path == "" {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		if top, err := gitRun("rev-parse", "--show-toplevel"); err == nil {
+			path = filepath.Join(top, path)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := sectionRe.FindStringSubmatch(line); m != nil {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// resolveSections picks the section list: the config first, then the commit
+// template, then the defaults.
+func resolveSections(cfg *userConfig) []section {
+	if len(cfg.Sections) > 0 {
+		return parseSections(cfg.Sections)
+	}
+	if names := templateSections(); len(names) > 0 {
+		return parseSections(names)
+	}
+	return parseSections(defaultSections)
+}
+
+func sectionsPrompt(sections []section) string {
+	var b strings.Builder
+	b.WriteString("Structure the commit message body as these sections, in this order, each starting on its own line with the section name and a colon:\n")
+	for _, s := range sections {
+		if s.Optional {
+			fmt.Fprintf(&b, "%s: (optional; leave it out if there is nothing to say)\n", s.Name)
+		} else {
+			fmt.Fprintf(&b, "%s: (required)\n", s.Name)
+		}
+	}
+	b.WriteString("Keep the usual short subject line above the sections and put nothing else in the body.")
+	return b.String()
+}
+
+// sectionsCheck requires every required section to be present.
+func sectionsCheck(sections []section) messageCheck {
+	return func(message string) []string {
+		if _, missing := assembleSections(message, sections); len(missing) > 0 {
+			return []string{"missing required section(s): " + strings.Join(missing, ", ")}
+		}
+		return nil
+	}
+}
+
+// assembleSections rebuilds message so its body holds exactly the
+// configured sections in order, dropping anything outside them. It returns
+// the names of required sections that are missing or empty.
+func assembleSections(message string, sections []section) (string, []string) {
+	subject, body := splitMessage(message)
+	found := map[string]string{}
+	var current string
+	for _, line := range strings.Split(body, "\n") {
+		if m := sectionRe.FindStringSubmatch(line); m != nil {
+			if name := matchSection(m[1], sections); name != "" {
+				current = name
+				found[current] = strings.TrimSpace(line[len(m[0]):])
+				continue
+			}
+		}
+		if current != "" {
+			found[current] += "\n" + line
+		}
+	}
+
+	var parts, missing []string
+	for _, s := range sections {
+		text := strings.TrimSpace(found[s.Name])
+		if text == "" {
+			if !s.Optional {
+				missing = append(missing, s.Name)
+			}
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(found[s.Name], " \t"), "\n") {
+			// The heading stood on its own line; keep the text below it.
+			parts = append(parts, s.Name+":\n"+text)
+		} else {
+			parts = append(parts, s.Name+": "+text)
+		}
+	}
+	return joinMessage(subject, strings.Join(parts, "\n\n")), missing
+}
+
+// matchSection returns the configured name that name refers to, ignoring
+// case, or "" if it isn't one of the sections.
+func matchSection(name string, sections []section) string {
+	for _, s := range sections {
+		if strings.EqualFold(strings.TrimSpace(name), s.Name) {
+			return s.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestSectionsCheck(t *testing.T) {
+	check := sectionsCheck(parseSections([]string{"Why", "Testing?"}))
+	tests := map[string]bool{
+		"Fix it\n\nWhy:\nIt broke.":                  true,
+		"Fix it\n\nWhy: It broke.\nTesting: ran it.": true,
+		"Fix it\n\nIt broke.":                        false,
+		"Fix it\n\nWhy:\n\nTesting:\nRan the suite.": false,
+		"Fix it": false,
+	}
+	for message, ok := range tests {
+		if problems := check(message); (len(problems) == 0) != ok {
+			t.Errorf("sectionsCheck(%q) = %q", message, problems)
+		}
+	}
+	if problems := check("Fix it"); len(problems) != 1 || problems[0] != "missing required section(s): Why" {
+		t.Errorf("the problem is %q", problems)
+	}
+}
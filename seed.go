@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// defaultSeedThreshold is the score at which a seed message is offered for
+// committing as-is instead of generating a new one.
+const defaultSeedThreshold = 80
+
+// Changes at least this large should explain themselves in a body.
+const (
+	seedBodyLines = 100
+	seedBodyFiles = 5
+)
+
+// genericSubjects are subjects that say nothing about the change.
+var genericSubjects = map[string]bool{
+	"fix": true, "fixes": true, "fixed": true, "update": true, "updates": true,
+	"updated": true, "change": true, "changes": true, "wip": true, "misc": true,
+	"stuff": true, "cleanup": true, "minor": true, "tweak": true, "tweaks": true,
+	"commit": true, "save": true, "temp": true, "test": true, "bug": true,
+	"bugs": true, "code": true, "some": true, "things": true, "more": true,
+}
+
+// isGenericSubject reports whether every word of the subject is generic,
+// as in "fix bug" or "misc changes".
+func isGenericSubject(words []string) bool {
+	for _, w := range words {
+		if !genericSubjects[strings.ToLower(strings.Trim(w, ".!:,"))] {
+			return false
+		}
+	}
+	return true
+}
+
+// scoreSeed rates the user's own message from 0 to 100 using local
+// heuristics only, so it costs no API call. It returns the score and the
+// reasons behind it, one per line.
+func scoreSeed(message string, stats []fileStat) (int, []string) {
+	subject, body := splitMessage(strings.TrimSpace(message))
+	if subject == "" {
+		return 0, []string{"message is empty"}
+	}
+
+	score := 100
+	var reasons []string
+	deduct := func(points int, reason string) {
+		score -= points
+		reasons = append(reasons, fmt.Sprintf("-%d: %s", points, reason))
+	}
+
+	// Strip a conventional commit prefix so "fix: typo" is judged on "typo".
+	description := subject
+	if m := conventionalRe.FindString(subject); m != "" {
+		description = strings.TrimPrefix(subject, m)
+	}
+	words := strings.Fields(description)
+	if len(words) == 0 {
+		return 0, []string{"subject has no description"}
+	}
+
+	switch n := len(subject); {
+	case n < 10:
+		deduct(30, fmt.Sprintf("subject is very short (%d characters)", n))
+	case n > maxSubjectLength:
+		deduct(15, fmt.Sprintf("subject is longer than %d characters", maxSubjectLength))
+	}
+	if isGenericSubject(words) {
+		deduct(40, "subject is too generic to describe the change")
+	}
+	if first := strings.ToLower(words[0]); len(words) > 1 && (strings.HasSuffix(first, "ed") || strings.HasSuffix(first, "ing")) {
+		deduct(10, fmt.Sprintf("subject should use the imperative mood (%q)", words[0]))
+	}
+	if strings.HasSuffix(subject, ".") {
+		deduct(5, "subject ends with a period")
+	}
+
+	lines := 0
+	for _, s := range stats {
+		lines += s.Added + s.Deleted
+	}
+	if body == "" && (lines >= seedBodyLines || len(stats) >= seedBodyFiles) {
+		deduct(20, fmt.Sprintf("no body explaining a change to %d file(s), %d line(s)", len(stats), lines))
+	}
+	if len(stats) > 0 && !mentionsChange(message, stats) {
+		deduct(10, "doesn't mention any of the changed files or directories")
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "specific, well-formed subject that matches the change")
+	}
+	return score, reasons
+}
+
+// mentionsChange reports whether message names any changed file (with or
+// without its extension) or directory.
+func mentionsChange(message string, stats []fileStat) bool {
+	lower := strings.ToLower(message)
+	for _, s := range stats {
+		base := filepath.Base(s.Path)
+		names := []string{base, strings.TrimSuffix(base, filepath.Ext(base))}
+		if dir := filepath.Base(filepath.Dir(s.Path)); dir != "." {
+			names = append(names, dir)
+		}
+		for _, name := range names {
+			if len(name) >= 3 && strings.Contains(lower, strings.ToLower(name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// offerSeed shows the seed's score and, when it clears threshold, asks
+// whether to commit it as-is. It reports whether the user accepted.
+func offerSeed(seed string, stats []fileStat, threshold int) bool {
+	score, reasons := scoreSeed(seed, stats)
+	fmt.Printf("\nYour message scored %d/100:\n", score)
+	for _, r := range reasons {
+		fmt.Printf("  %s\n", r)
+	}
+	if score < threshold {
+		fmt.Printf("Below the threshold of %d; generating a message.\n", threshold)
+		return false
+	}
+	return getUserInput("\nYour message looks good — commit as-is? (y to commit / g to generate anyway): ") == "y"
+}
+
+// changedLines returns the number of added and deleted lines in stats. ok
+// is false when a binary file or rename is involved, since line counts
+// don't tell how much those change.
+func changedLines(stats []fileStat) (n int, ok bool) {
+	for _, s := range stats {
+		if s.Binary || s.OldPath != "" {
+			return 0, false
+		}
+		n += s.churn()
+	}
+	return n, len(stats) > 0
+}
+
+// offerTrivial asks whether to commit the typed message as-is for a change
+// of only n lines, below -min-diff-lines.
+func offerTrivial(n, minLines int) bool {
+	return ask("\nOnly %d line(s) changed (below -min-diff-lines %d). Commit your message as-is? (y to commit / g to generate anyway): ", n, minLines) == "y"
+}
+
+// offerOriginal offers the message the user typed when the API failed to
+// produce one (-fallback-to-original), optionally edited first. It returns
+// false when the user declines.
+func offerOriginal(original string, settings *gitSettings) (string, bool) {
+	say("\nThe API request failed. Your original message was:\n%s\n", original)
+	switch getUserInput("Commit with your original message? (y/n/e to edit): ") {
+	case "y":
+		return original, true
+	case "e":
+		edited, err := editInEditor(settings.wrap(original))
+		if err != nil {
+			say("Error editing message: %v\n", err)
+			return "", false
+		}
+		edited = strings.TrimSpace(settings.unwrap(edited))
+		return edited, edited != ""
+	}
+	return "", false
+}
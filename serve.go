@@ -0,0 +1,702 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// serverProtocolVersion is checked in the handshake; bump it on any
+// incompatible change to the methods below.
+const serverProtocolVersion = 1
+
+const defaultIdleTimeout = 30 * time.Minute
+
+// socketEnv overrides the default socket path for both serve and
+// -use-server.
+const socketEnv = "GITCOMMIT_SOCKET"
+
+// defaultSocketPath is a per-user path in a private directory.
+func defaultSocketPath() string {
+	if p := os.Getenv(socketEnv); p != "" {
+		return p
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gitcommit", "server.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("gitcommit-%d", os.Getuid()), "server.sock")
+}
+
+// The protocol is one JSON object per line in each direction. Every
+// connection starts with a "hello" request carrying the protocol version.
+type rpcRequest struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	// Code is "version", "profile", "bad_request" or "failed".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *rpcError       `json:"error,omitempty"`
+}
+
+type helloParams struct {
+	Version int `json:"version"`
+}
+
+type helloResult struct {
+	Version int    `json:"version"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// generateParams asks for a message for the staged (or all) changes in
+// Repo. Context holds answers to earlier questions. Suggestion and
+// Feedback turn it into a refine request.
+type generateParams struct {
+	Repo       string   `json:"repo"`
+	Message    string   `json:"message"`
+	All        bool     `json:"all,omitempty"`
+	Context    []string `json:"context,omitempty"`
+	Suggestion string   `json:"suggestion,omitempty"`
+	Feedback   string   `json:"feedback,omitempty"`
+}
+
+// generateResult holds either a message or a clarifying question.
+type generateResult struct {
+	Message  string `json:"message,omitempty"`
+	Question string `json:"question,omitempty"`
+}
+
+type lintParams struct {
+	Message string `json:"message"`
+	// Repo, when set, also checks the message against the repository's
+	// changelog trailer schema.
+	Repo string `json:"repo,omitempty"`
+}
+
+type lintResult struct {
+	Problems []string `json:"problems"`
+}
+
+type commitParams struct {
+	Repo     string `json:"repo"`
+	Message  string `json:"message"`
+	All      bool   `json:"all,omitempty"`
+	NoVerify bool   `json:"no_verify,omitempty"`
+}
+
+type commitResult struct {
+	Output string `json:"output"`
+}
+
+// completeParams is a raw request from a CLI run with -use-server.
+type completeParams struct {
+	Profile     string    `json:"profile,omitempty"`
+	System      string    `json:"system"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+}
+
+type completeResult struct {
+	Text  string `json:"text"`
+	Usage Usage  `json:"usage"`
+}
+
+type server struct {
+	cfg    *userConfig
+	apiKey string
+	opts   apiOptions
+	// profile is the active profile's name; explicit is set when it was
+	// chosen by flag or environment rather than by this directory's remotes.
+	profile  string
+	explicit bool
+	// exe and args run gitcommit for work in a request's repository; see
+	// inRepo.
+	exe  string
+	args []string
+
+	idleMu sync.Mutex
+	active int
+	idle   time.Duration
+	timer  *time.Timer
+}
+
+// newServer sets up request handling for serve and batch with the
+// selected profile.
+func newServer(profileName, apiKey string, opts apiOptions) (*server, error) {
+	cfg, err := loadProfileConfig(profileName)
+	if err != nil {
+		return nil, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("error locating gitcommit: %v", err)
+	}
+	// The global flags, such as -config and -profile, apply to the runs in
+	// each repository too.
+	s := &server{cfg: cfg, apiKey: apiKey, opts: opts, exe: exe, args: flagArgs("")}
+	if cfg.active != nil {
+		s.profile = cfg.active.Name
+		s.explicit = profileName != "" || os.Getenv(profileEnv) != ""
+	}
+	return s, nil
+}
+
+// rpcFail is an error with a protocol error code.
+type rpcFail struct {
+	code string
+	err  error
+}
+
+func (e *rpcFail) Error() string { return e.err.Error() }
+
+func failf(code, format string, args ...any) error {
+	return &rpcFail{code, fmt.Errorf(format, args...)}
+}
+
+// repoRequest is what inRepo hands to `gitcommit serve-repo` on stdin.
+type repoRequest struct {
+	// Profile and Explicit are the server's, for checkProfile.
+	Profile  string          `json:"profile,omitempty"`
+	Explicit bool            `json:"explicit,omitempty"`
+	Params   json.RawMessage `json:"params"`
+}
+
+// inRepo runs method in repo through `gitcommit serve-repo`, started with
+// repo as its working directory, and decodes its result. Each request gets
+// its own process, so requests for different repositories run side by side
+// and none of them sees another's cached HEAD or git config. Only the API
+// connection, which is what is worth keeping warm, stays in the server.
+func (s *server) inRepo(repo, method string, params, result any) error {
+	if repo == "" || !filepath.IsAbs(repo) {
+		return failf("bad_request", "repo must be an absolute path")
+	}
+	if info, err := os.Stat(repo); err != nil || !info.IsDir() {
+		return failf("bad_request", "%s is not a directory", repo)
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	input, err := json.Marshal(repoRequest{Profile: s.profile, Explicit: s.explicit, Params: raw})
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(s.exe, append(append([]string{}, s.args...), "serve-repo", method)...)
+	cmd.Dir = repo
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	output, runErr := cmd.Output()
+	var resp rpcResponse
+	if err := json.Unmarshal(output, &resp); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("error running gitcommit in %s: %v", repo, runErr)
+		}
+		return fmt.Errorf("error reading result from gitcommit in %s: %v", repo, err)
+	}
+	if resp.Error != nil {
+		// batch tells a repository with nothing staged from a failure.
+		if *resp.Error == (rpcError{Code: "failed", Message: errNoStagedChanges.Error()}) {
+			return errNoStagedChanges
+		}
+		return &rpcFail{resp.Error.Code, errors.New(resp.Error.Message)}
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// runServeRepo implements `gitcommit serve-repo <method>`, which the server
+// runs in a request's repository (see inRepo); it is not meant to be run
+// by hand. The request is read from stdin and one rpcResponse is written to
+// stdout. Anything else printed along the way, such as secret scanner
+// findings, goes to stderr.
+func runServeRepo(args []string, profileName string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: gitcommit serve-repo <method>")
+	}
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = out }()
+
+	result, err := serveRepo(args[0], profileName)
+	resp := rpcResponse{}
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		code := "failed"
+		var f *rpcFail
+		if errors.As(err, &f) {
+			code = f.code
+		}
+		resp.Error = &rpcError{Code: code, Message: err.Error()}
+	}
+	return json.NewEncoder(out).Encode(resp)
+}
+
+func serveRepo(method, profileName string) (any, error) {
+	var req repoRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		return nil, failf("bad_request", "invalid request: %v", err)
+	}
+	decode := func(v any) error {
+		if err := json.Unmarshal(req.Params, v); err != nil {
+			return failf("bad_request", "invalid params: %v", err)
+		}
+		return nil
+	}
+	if _, err := gitRun("rev-parse", "--git-dir"); err != nil {
+		wd, _ := os.Getwd()
+		return nil, failf("bad_request", "%s is not a git repository", wd)
+	}
+	cfg, err := loadProfileConfig(profileName)
+	if err != nil {
+		return nil, err
+	}
+	s := &server{cfg: cfg, profile: req.Profile, explicit: req.Explicit}
+
+	switch method {
+	case "changes":
+		var p generateParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		return s.repoChanges(p.All)
+	case "commit":
+		var p commitParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		output, err := gitCommit(p.Message, commitOptions{All: p.All, NoVerify: p.NoVerify})
+		if err != nil {
+			return nil, err
+		}
+		return commitResult{Output: output}, nil
+	case "lint":
+		var p lintParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		schema, err := loadChangelogSchema(false)
+		if err != nil {
+			return nil, err
+		}
+		problems := []string{}
+		if schema != nil {
+			problems = append(problems, schema.problems(p.Message)...)
+		}
+		return lintResult{Problems: problems}, nil
+	}
+	return nil, failf("bad_request", "unknown method %q", method)
+}
+
+// checkProfile refuses repositories whose remotes select a different
+// profile than the one the server runs with, so their diffs never go out
+// with another account's key.
+func (s *server) checkProfile() error {
+	if s.explicit {
+		return nil
+	}
+	var name string
+	if p, _ := selectProfile(s.cfg, ""); p != nil {
+		name = p.Name
+	}
+	if name != s.profile {
+		return failf("profile", "this repository uses profile %q but the server runs with %q; start another server with -profile %s", name, s.profile, name)
+	}
+	return nil
+}
+
+// errNoStagedChanges is returned for a repository with nothing to commit.
+var errNoStagedChanges = failf("failed", "no staged changes found")
+
+// repoChanges builds the prompt context for the current repository with
+// the default limits.
+func (s *server) repoChanges(all bool) (string, error) {
+	if err := s.checkProfile(); err != nil {
+		return "", err
+	}
+	snap, err := gatherSnapshot(all, true)
+	if err != nil {
+		return "", err
+	}
+	if snap.Diff == "" {
+		return "", errNoStagedChanges
+	}
+	diff, _ := truncateDiff(snap.Diff, defaultMaxDiffBytes, strategyLargestFirst)
+	changes := "Here are the changes:\n" + diff
+	if section := changedFilesPrompt(snap.Changes); section != "" {
+		changes += "\n\n" + section
+	}
+	if section := historyPrompt(snap.History); section != "" {
+		changes += "\n\n" + section
+	}
+	if state, err := loadRepoState(); err == nil {
+		if section := hintsPrompt(state, changePaths(snap.Changes)); section != "" {
+			changes += "\n\n" + section
+		}
+	}
+	if section := fileHintsPrompt(s.cfg.FileHints, changePaths(snap.Changes)); section != "" {
+		changes += "\n\n" + section
+	}
+	if dependencyUpdateOnly(changePaths(snap.Changes)) {
+		if deps := dependencyChanges(snap.Diff); len(deps) > 0 {
+			changes += "\n\n" + dependencyPrompt(deps, false)
+		}
+	}
+	if s.cfg.Scanner != nil {
+		if changes, _, err = scanContent(s.cfg.Scanner, changes); err != nil {
+			return "", err
+		}
+	}
+	return changes, nil
+}
+
+func (s *server) generate(p generateParams) (any, error) {
+	var changes string
+	if err := s.inRepo(p.Repo, "changes", p, &changes); err != nil {
+		return nil, err
+	}
+	prompt := messagePrompt(p.Message, changes)
+	for _, c := range p.Context {
+		prompt += fmt.Sprintf("\n\nAdditional context: %s", c)
+	}
+	if p.Suggestion != "" {
+		prompt += fmt.Sprintf("\n\nYou suggested:\n```\n%s\n```\nRevise it. Feedback: %s", p.Suggestion, p.Feedback)
+	}
+	response, err := askClaude(prompt, s.apiKey, s.opts)
+	if err != nil {
+		return nil, err
+	}
+	// There is nobody to ask about borderline responses here; the editor
+	// shows a question, which the user can still answer with the message.
+	// Nor is there anyone to offer sanitizing to, and invisible characters
+	// wouldn't show in the editor, so the message is cleaned as it is.
+	if c := classifyResponse(response); c.Kind == responseMessage && c.Confidence >= minConfidence {
+		message, _ := sanitizeMessage(c.Message, false)
+		return generateResult{Message: message}, nil
+	}
+	return generateResult{Question: response}, nil
+}
+
+func (s *server) commit(p commitParams) (any, error) {
+	if strings.TrimSpace(p.Message) == "" {
+		return nil, failf("bad_request", "message is empty")
+	}
+	var result commitResult
+	if err := s.inRepo(p.Repo, "commit", p, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *server) complete(p completeParams) (any, error) {
+	if p.Profile != s.profile {
+		return nil, failf("profile", "the client uses profile %q but the server runs with %q", p.Profile, s.profile)
+	}
+	if len(p.Messages) == 0 {
+		return nil, failf("bad_request", "no messages")
+	}
+	var usage usageTotals
+	opts := s.opts
+	opts.System = p.System
+	opts.Prefs = nil // already part of System
+	opts.History = p.Messages[:len(p.Messages)-1]
+	opts.Temperature = p.Temperature
+	opts.Usage = &usage
+	text, err := askClaude(p.Messages[len(p.Messages)-1].Content, s.apiKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	return completeResult{Text: text, Usage: Usage{InputTokens: usage.InputTokens, OutputTokens: usage.OutputTokens}}, nil
+}
+
+func (s *server) dispatch(req rpcRequest) (any, error) {
+	decode := func(v any) error {
+		if err := json.Unmarshal(req.Params, v); err != nil {
+			return failf("bad_request", "invalid params: %v", err)
+		}
+		return nil
+	}
+	switch req.Method {
+	case "generate", "refine":
+		var p generateParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		return s.generate(p)
+	case "lint":
+		var p lintParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		problems := validateMessage(p.Message)
+		if p.Repo != "" {
+			var schema lintResult
+			if err := s.inRepo(p.Repo, "lint", p, &schema); err != nil {
+				return nil, err
+			}
+			problems = append(problems, schema.Problems...)
+		}
+		if problems == nil {
+			problems = []string{}
+		}
+		return lintResult{Problems: problems}, nil
+	case "commit":
+		var p commitParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		return s.commit(p)
+	case "complete":
+		var p completeParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		return s.complete(p)
+	}
+	return nil, failf("bad_request", "unknown method %q", req.Method)
+}
+
+// busy and done track in-flight requests for the idle shutdown.
+func (s *server) busy() {
+	s.idleMu.Lock()
+	s.active++
+	s.timer.Stop()
+	s.idleMu.Unlock()
+}
+
+func (s *server) done() {
+	s.idleMu.Lock()
+	if s.active--; s.active == 0 {
+		s.timer.Reset(s.idle)
+	}
+	s.idleMu.Unlock()
+}
+
+func (s *server) handle(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	reply := func(id int64, result any, err error) error {
+		resp := rpcResponse{ID: id}
+		if err != nil {
+			code := "failed"
+			var f *rpcFail
+			if errors.As(err, &f) {
+				code = f.code
+			}
+			resp.Error = &rpcError{Code: code, Message: err.Error()}
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			resp.Error = &rpcError{Code: "failed", Message: err.Error()}
+		}
+		return enc.Encode(resp)
+	}
+
+	greeted := false
+	for {
+		var req rpcRequest
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		if !greeted {
+			var p helloParams
+			if req.Method != "hello" || json.Unmarshal(req.Params, &p) != nil {
+				reply(req.ID, nil, failf("version", "expected hello with the protocol version first"))
+				return
+			}
+			if p.Version != serverProtocolVersion {
+				reply(req.ID, nil, failf("version", "protocol version %d is not supported (server speaks %d)", p.Version, serverProtocolVersion))
+				return
+			}
+			greeted = true
+			if reply(req.ID, helloResult{Version: serverProtocolVersion, Profile: s.profile}, nil) != nil {
+				return
+			}
+			continue
+		}
+		s.busy()
+		result, err := s.dispatch(req)
+		s.done()
+		if reply(req.ID, result, err) != nil {
+			return
+		}
+	}
+}
+
+// listenSocket creates the socket so only the current user can connect.
+// The directory must not be accessible by anyone else, which also closes
+// the window before the socket's own permissions are set.
+func listenSocket(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating socket directory: %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error checking socket directory: %v", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%s is accessible by other users; put the socket in a private directory", dir)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a server is already listening on %s", path)
+	}
+	os.Remove(path) // a stale socket from a server that didn't shut down
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %s: %v", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("error securing socket: %v", err)
+	}
+	return ln, nil
+}
+
+// runServe implements `gitcommit serve [--socket path] [--idle duration]`.
+func runServe(args []string, profileName string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	socket := fs.String("socket", defaultSocketPath(), "unix socket to listen on")
+	idle := fs.Duration("idle", defaultIdleTimeout, "shut down after this long without requests")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: gitcommit serve [--socket path] [--idle duration]")
+	}
+
+	apiKey, opts, err := subcommandAPI(profileName)
+	if err != nil {
+		return err
+	}
+	s, err := newServer(profileName, apiKey, opts)
+	if err != nil {
+		return err
+	}
+	s.idle = *idle
+
+	ln, err := listenSocket(*socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*socket)
+	stop := func() { ln.Close() }
+	s.timer = time.AfterFunc(*idle, stop)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		stop()
+	}()
+
+	fmt.Printf("Listening on %s (protocol %d); idle shutdown after %s.\n", *socket, serverProtocolVersion, *idle)
+	var wg sync.WaitGroup
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(conn)
+		}()
+	}
+	wg.Wait()
+	fmt.Println("Server stopped.")
+	return nil
+}
+
+// errServerUnavailable means the CLI should talk to the API directly.
+var errServerUnavailable = errors.New("gitcommit server unavailable")
+
+// serverClient is the CLI side of -use-server.
+type serverClient struct {
+	path    string
+	profile string
+}
+
+// call sends one request on a fresh connection. Connecting to a unix socket
+// is cheap; the server is what keeps the API connection warm.
+func (c *serverClient) call(method string, params, result any, timeout time.Duration) error {
+	conn, err := net.DialTimeout("unix", c.path, time.Second)
+	if err != nil {
+		return errServerUnavailable
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+
+	roundTrip := func(id int64, method string, params, result any) error {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(rpcRequest{ID: id, Method: method, Params: raw}); err != nil {
+			return errServerUnavailable
+		}
+		var resp rpcResponse
+		if err := dec.Decode(&resp); err != nil {
+			return errServerUnavailable
+		}
+		if resp.Error != nil {
+			if resp.Error.Code == "version" || resp.Error.Code == "profile" {
+				return fmt.Errorf("%w: %s", errServerUnavailable, resp.Error.Message)
+			}
+			return errors.New(resp.Error.Message)
+		}
+		return json.Unmarshal(resp.Result, result)
+	}
+	var hello helloResult
+	if err := roundTrip(1, "hello", helloParams{Version: serverProtocolVersion}, &hello); err != nil {
+		return err
+	}
+	return roundTrip(2, method, params, result)
+}
+
+// ping checks that a compatible server is running.
+func (c *serverClient) ping() error {
+	var result lintResult
+	return c.call("lint", lintParams{Message: "ping"}, &result, time.Second)
+}
+
+// apiServer is set when this run sends its API requests through a server.
+var apiServer *serverClient
+
+// completeViaServer sends a request through the server. It returns
+// errServerUnavailable when the request should be made directly instead.
+func completeViaServer(req MessagesRequest, opts apiOptions) (string, error) {
+	var result completeResult
+	params := completeParams{Profile: apiServer.profile, System: req.System, Messages: req.Messages, Temperature: req.Temperature}
+	if err := apiServer.call("complete", params, &result, opts.Deadline); err != nil {
+		return "", err
+	}
+	if opts.Usage != nil {
+		opts.Usage.Requests++
+		opts.Usage.InputTokens += result.Usage.InputTokens
+		opts.Usage.OutputTokens += result.Usage.OutputTokens
+	}
+	return result.Text, nil
+}
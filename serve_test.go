@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer returns a server whose per-repository runs are this test
+// binary acting as gitcommit, with an empty user config.
+func testServer(t *testing.T) *server {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITCOMMIT_TEST_MAIN", "1")
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config, []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &server{
+		cfg:   &userConfig{},
+		exe:   exe,
+		args:  []string{"-config=" + config},
+		idle:  time.Hour,
+		timer: time.AfterFunc(time.Hour, func() {}),
+	}
+}
+
+// fakeAPI answers every request with a message naming the .txt file in
+// the prompt's diff, so each answer shows which repository it was for.
+func fakeAPI(t *testing.T) {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req MessagesRequest
+		json.Unmarshal(body, &req)
+		prompt := req.Messages[len(req.Messages)-1].Content
+		file := "nothing"
+		for _, f := range []string{"alpha.txt", "beta.txt"} {
+			if strings.Contains(prompt, "b/"+f) {
+				file = f
+			}
+		}
+		json.NewEncoder(w).Encode(MessagesResponse{Content: []ContentBlock{{Type: "text", Text: "```\nUpdate " + file + "\n```"}}})
+	}))
+	t.Cleanup(api.Close)
+	old := apiURL
+	apiURL = api.URL
+	t.Cleanup(func() { apiURL = old })
+}
+
+func TestServeConcurrentRepos(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks are shell scripts")
+	}
+	fakeAPI(t)
+	s := testServer(t)
+
+	// Each repository's pre-commit hook waits for the other's to start, so
+	// the commits only succeed if they run at the same time.
+	barrier := t.TempDir()
+	repos := map[string]string{}
+	for name, other := range map[string]string{"alpha": "beta", "beta": "alpha"} {
+		dir := testRepo(t)
+		commitFile(t, name+".txt", "one\n", "Add "+name)
+		writeFile(t, name+".txt", "two\n")
+		mustGit(t, "add", name+".txt")
+		hook := fmt.Sprintf("#!/bin/sh\ntouch %q\ni=0\nwhile [ ! -e %q ]; do\n\ti=$((i+1))\n\t[ $i -gt 100 ] && exit 1\n\tsleep 0.1\ndone\n",
+			filepath.Join(barrier, name), filepath.Join(barrier, other))
+		writeFile(t, ".git/hooks/pre-commit", hook)
+		if err := os.Chmod(".git/hooks/pre-commit", 0755); err != nil {
+			t.Fatal(err)
+		}
+		repos[name] = dir
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for name, dir := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := s.generate(generateParams{Repo: dir})
+			if err != nil {
+				errs <- fmt.Errorf("generate in %s: %v", name, err)
+				return
+			}
+			message := res.(generateResult).Message
+			if want := "Update " + name + ".txt"; message != want {
+				errs <- fmt.Errorf("generate in %s: message %q, want %q", name, message, want)
+				return
+			}
+			if _, err := s.commit(commitParams{Repo: dir, Message: message}); err != nil {
+				errs <- fmt.Errorf("commit in %s: %v", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	for name, dir := range repos {
+		if got := mustGit(t, "-C", dir, "log", "-1", "--format=%s"); got != "Update "+name+".txt" {
+			t.Errorf("%s: last commit is %q", name, got)
+		}
+	}
+}
+
+func TestServeBadRepo(t *testing.T) {
+	s := testServer(t)
+	if _, err := s.commit(commitParams{Repo: "relative/path", Message: "x"}); err == nil || !strings.Contains(err.Error(), "absolute") {
+		t.Errorf("relative repo: err = %v", err)
+	}
+	dir := t.TempDir()
+	_, err := s.commit(commitParams{Repo: dir, Message: "x"})
+	if err == nil || !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("not a repository: err = %v", err)
+	}
+	testRepo(t)
+	if _, err := s.generate(generateParams{Repo: mustGit(t, "rev-parse", "--show-toplevel")}); err != errNoStagedChanges {
+		t.Errorf("nothing staged: err = %v, want errNoStagedChanges", err)
+	}
+}
+
+// rpc sends one request on conn and returns the response.
+func rpc(t *testing.T, conn net.Conn, method string, params any) rpcResponse {
+	t.Helper()
+	raw, _ := json.Marshal(params)
+	if err := json.NewEncoder(conn).Encode(rpcRequest{ID: 1, Method: method, Params: raw}); err != nil {
+		t.Fatal(err)
+	}
+	var resp rpcResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	return resp
+}
+
+func TestServeProtocolVersion(t *testing.T) {
+	s := testServer(t)
+
+	client, conn := net.Pipe()
+	go s.handle(conn)
+	resp := rpc(t, client, "hello", helloParams{Version: serverProtocolVersion + 1})
+	if resp.Error == nil || resp.Error.Code != "version" {
+		t.Fatalf("newer protocol: response %+v, want a version error", resp)
+	}
+	// The server hangs up after a failed handshake.
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("after a version mismatch: read err = %v, want EOF", err)
+	}
+
+	client, conn = net.Pipe()
+	defer client.Close()
+	go s.handle(conn)
+	if resp := rpc(t, client, "lint", lintParams{Message: "x"}); resp.Error == nil || resp.Error.Code != "version" {
+		t.Errorf("no hello: response %+v, want a version error", resp)
+	}
+
+	client, conn = net.Pipe()
+	defer client.Close()
+	go s.handle(conn)
+	if resp := rpc(t, client, "hello", helloParams{Version: serverProtocolVersion}); resp.Error != nil {
+		t.Fatalf("hello: %v", resp.Error.Message)
+	}
+	resp = rpc(t, client, "lint", lintParams{Message: "Fix the thing"})
+	var lint lintResult
+	if resp.Error != nil || json.Unmarshal(resp.Result, &lint) != nil {
+		t.Errorf("lint after hello: response %+v", resp)
+	}
+}
+
+func TestListenSocketPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix permission bits")
+	}
+	// Socket paths are limited to about 100 bytes, so keep it short.
+	base, err := os.MkdirTemp("", "gc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	shared := filepath.Join(base, "shared")
+	if err := os.Mkdir(shared, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(shared, 0755) // whatever the umask
+	if ln, err := listenSocket(filepath.Join(shared, "s.sock")); err == nil {
+		ln.Close()
+		t.Error("listened in a directory other users can read")
+	} else if !strings.Contains(err.Error(), "accessible by other users") {
+		t.Errorf("shared directory: err = %v", err)
+	}
+
+	path := filepath.Join(base, "private", "s.sock")
+	ln, err := listenSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	for p, want := range map[string]os.FileMode{filepath.Dir(path): 0700, path: 0600} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s: mode %v, want %v", p, got, want)
+		}
+	}
+	if _, err := listenSocket(path); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("second server: err = %v", err)
+	}
+}
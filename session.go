@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of session steps.
+const (
+	stepSuggested = "suggested" // a message came back from the API
+	stepQuestion  = "question"  // a clarifying question and the answer
+	stepRejected  = "rejected"  // the suggestion was turned down
+	stepEdited    = "edited"    // the suggestion was edited before use
+)
+
+// sessionStep is one thing that happened on the way to the message.
+type sessionStep struct {
+	Kind string `json:"kind"`
+	// Text is the suggestion or the question; Answer is the reply to a
+	// question.
+	Text   string `json:"text"`
+	Answer string `json:"answer,omitempty"`
+}
+
+// runSession records the steps of one run in order, so the end of a long
+// session can be summed up in a recap.
+type runSession struct {
+	Intent string        `json:"intent,omitempty"`
+	Steps  []sessionStep `json:"steps,omitempty"`
+}
+
+func (s *runSession) add(kind, text string) {
+	s.Steps = append(s.Steps, sessionStep{Kind: kind, Text: text})
+}
+
+// asked records a clarifying question and its answer.
+func (s *runSession) asked(question, answer string) {
+	s.Steps = append(s.Steps, sessionStep{Kind: stepQuestion, Text: question, Answer: answer})
+}
+
+// resumeExchanges adds the questions answered before a run was resumed.
+func (s *runSession) resumeExchanges(exchanges []exchange) {
+	for _, e := range exchanges {
+		s.asked(e.Question, e.Answer)
+	}
+}
+
+// exchanges returns the questions asked so far and their answers.
+func (s *runSession) exchanges() []exchange {
+	var out []exchange
+	for _, step := range s.Steps {
+		if step.Kind == stepQuestion {
+			out = append(out, exchange{Question: step.Text, Answer: step.Answer})
+		}
+	}
+	return out
+}
+
+func (s *runSession) count(kind string) int {
+	n := 0
+	for _, step := range s.Steps {
+		if step.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// sessionRecap is the summary shown before committing with -recap.
+type sessionRecap struct {
+	Intent    string     `json:"intent,omitempty"`
+	Rounds    int        `json:"rounds"`
+	Rejected  int        `json:"rejected"`
+	Edited    int        `json:"edited"`
+	Exchanges []exchange `json:"exchanges,omitempty"`
+	Checks    []string   `json:"checks,omitempty"`
+	Trailers  []string   `json:"trailers,omitempty"`
+	Message   string     `json:"message"`
+}
+
+// recap sums up the session for the final message and the results of the
+// checks run on it.
+func (s *runSession) recap(message string, checks []string) sessionRecap {
+	r := sessionRecap{
+		Intent:    s.Intent,
+		Rounds:    s.count(stepSuggested),
+		Rejected:  s.count(stepRejected),
+		Edited:    s.count(stepEdited),
+		Exchanges: s.exchanges(),
+		Checks:    checks,
+		Message:   message,
+	}
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	if last := paragraphs[len(paragraphs)-1]; len(paragraphs) > 1 && isTrailerBlock(last) {
+		r.Trailers = strings.Split(last, "\n")
+	}
+	return r
+}
+
+// recapLineWidth keeps each recap line on one terminal line.
+const recapLineWidth = 76
+
+// oneLine squeezes text onto a single line of at most width runes.
+func oneLine(text string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+	return text
+}
+
+// print shows the recap compactly enough to fit on one screen.
+func (r sessionRecap) print() {
+	fmt.Println("\n=== Recap ===")
+	if r.Intent != "" {
+		fmt.Printf("Intent:   %s\n", oneLine(r.Intent, recapLineWidth-10))
+	}
+	fmt.Printf("Rounds:   %d generated, %d rejected, %d edited\n", r.Rounds, r.Rejected, r.Edited)
+	for _, e := range r.Exchanges {
+		fmt.Printf("Q: %s\n", oneLine(e.Question, recapLineWidth-3))
+		fmt.Printf("A: %s\n", oneLine(e.Answer, recapLineWidth-3))
+	}
+	for _, c := range r.Checks {
+		fmt.Printf("Check:    %s\n", oneLine(c, recapLineWidth-10))
+	}
+	if len(r.Trailers) > 0 {
+		fmt.Printf("Trailers: %s\n", oneLine(strings.Join(r.Trailers, "; "), recapLineWidth-10))
+	}
+	fmt.Printf("Message:\n%s\n", r.Message)
+	fmt.Println("=============")
+}
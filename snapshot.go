@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repoSnapshot holds the read-only repository queries gathered before the
+// first prompt. For staged commits it is cached across runs, keyed on HEAD
+// and the index, so repeat runs on large repositories skip the git work.
+type repoSnapshot struct {
+	Key     string          `json:"key"`
+	Diff    string          `json:"diff"`
+	Changes []fileChange    `json:"changes"`
+	History []historyCommit `json:"history,omitempty"`
+	// Oversized are files left out of Diff for their size, and Truncated
+	// says the diff was cut at maxDiffReadBytes.
+	Oversized []oversizedFile `json:"oversized,omitempty"`
+	Truncated bool            `json:"truncated,omitempty"`
+
+	// timings is a breakdown of where gathering spent its time.
+	timings []stepTiming
+	cached  bool
+}
+
+type stepTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// snapshotKey identifies the state the snapshot was taken from: HEAD and
+// a hash of the staged entries' modes, blobs and paths. Those, unlike the
+// index file's timestamp, are unchanged by anything that only rewrites the
+// index's cached stat data, such as the pre-commit framework setting
+// unstaged changes aside and back while its hooks run. Unlike write-tree,
+// listing them writes nothing to the object store, so -read-only can cache.
+func snapshotKey(history bool) (string, error) {
+	head, _ := gitCommand("rev-parse", "--verify", "--quiet", "HEAD").Output()
+	cmd := gitCommand("ls-files", "--stage", "-z")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("error listing the staged files: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("error listing the staged files: %v", err)
+	}
+	h := sha256.New()
+	_, copyErr := io.Copy(h, stdout)
+	if err := cmd.Wait(); err != nil || copyErr != nil {
+		return "", fmt.Errorf("error listing the staged files: %v", errors.Join(err, copyErr))
+	}
+	return fmt.Sprintf("v2 head=%s index=%x history=%t", strings.TrimSpace(string(head)), h.Sum(nil), history), nil
+}
+
+func snapshotCachePath() (string, error) {
+	dir, err := repoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snapshot.json"), nil
+}
+
+func loadCachedSnapshot(key string) *repoSnapshot {
+	p, err := snapshotCachePath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil
+	}
+	var snap repoSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil || snap.Key != key {
+		return nil
+	}
+	snap.cached = true
+	return &snap
+}
+
+// cache stores a freshly gathered staged snapshot for the next run. It is
+// called once the secret scanner has passed the diff, since the cache
+// holds it.
+func (s *repoSnapshot) cache() {
+	if s.Key != "" && !s.cached {
+		saveCachedSnapshot(s)
+	}
+}
+
+// saveCachedSnapshot stores the snapshot; failures only cost speed next
+// time. It holds the staged diff, so only the user may read it.
+func saveCachedSnapshot(snap *repoSnapshot) {
+	p, err := snapshotCachePath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return
+	}
+	// WriteFile only applies the mode to a new file.
+	if err := os.Chmod(p, 0600); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	os.WriteFile(p, data, 0600)
+}
+
+// gatherSnapshot runs the independent git queries concurrently. Caching is
+// only used for staged commits: with -a the work tree is part of the diff
+// and can change without touching the index.
+func gatherSnapshot(all, history bool) (*repoSnapshot, error) {
+	var key string
+	if !all {
+		start := time.Now()
+		if k, err := snapshotKey(history); err == nil {
+			key = k
+			if snap := loadCachedSnapshot(key); snap != nil {
+				snap.timings = []stepTiming{{"cache", time.Since(start)}}
+				return snap, nil
+			}
+		}
+	}
+
+	snap := &repoSnapshot{Key: key}
+	// Sizes come first, so huge files are never read.
+	oversized, err := findOversized(all)
+	if err != nil {
+		return nil, err
+	}
+	snap.Oversized = oversized
+	var (
+		wg                 sync.WaitGroup
+		mu                 sync.Mutex
+		diffErr, statusErr error
+	)
+	record := func(name string, start time.Time) {
+		mu.Lock()
+		snap.timings = append(snap.timings, stepTiming{name, time.Since(start)})
+		mu.Unlock()
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		snap.Diff, snap.Truncated, diffErr = getDiff(all, excludePathspecs(oversized))
+		record("diff", start)
+	}()
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		snap.Changes, statusErr = getNameStatus(all)
+		record("status", start)
+		if statusErr != nil || !history || !hasHead() {
+			return
+		}
+		start = time.Now()
+		snap.History = getFileHistory(changePaths(snap.Changes))
+		record("log", start)
+	}()
+	wg.Wait()
+
+	if diffErr != nil {
+		return nil, diffErr
+	}
+	if statusErr != nil {
+		return nil, statusErr
+	}
+	return snap, nil
+}
+
+// printTimings prints the gathering breakdown for verbose mode.
+func (s *repoSnapshot) printTimings() {
+	parts := make([]string, 0, len(s.timings))
+	for _, t := range s.timings {
+		parts = append(parts, fmt.Sprintf("%s %.2fs", t.Name, t.Duration.Seconds()))
+	}
+	source := "gathered"
+	if s.cached {
+		source = "loaded from cache"
+	}
+	fmt.Printf("Repository snapshot %s: %s\n", source, strings.Join(parts, ", "))
+}
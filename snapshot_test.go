@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSnapshotKey(t *testing.T) {
+	testRepo(t)
+	commitFile(t, "a.txt", "one\n", "Add a")
+	writeFile(t, "a.txt", "one\ntwo\n")
+	mustGit(t, "add", "a.txt")
+	key, err := snapshotKey(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Refreshed stat data rewrites the index but not what is staged.
+	later := time.Now().Add(time.Hour)
+	os.Chtimes("a.txt", later, later)
+	mustGit(t, "update-index", "--refresh")
+	if again, err := snapshotKey(false); err != nil || again != key {
+		t.Errorf("after a refresh the key is %q (%v), want %q", again, err, key)
+	}
+	if other, _ := snapshotKey(true); other == key {
+		t.Error("the key doesn't depend on history")
+	}
+
+	writeFile(t, "a.txt", "one\nthree\n")
+	mustGit(t, "add", "a.txt")
+	changed, err := snapshotKey(false)
+	if err != nil || changed == key {
+		t.Errorf("a new staged change kept the key %q (%v)", changed, err)
+	}
+
+	// The key is computed without writing objects, even in read-only mode.
+	defer func(old bool) { readOnly = old }(readOnly)
+	readOnly = true
+	before := mustGit(t, "count-objects", "-v")
+	if got, err := snapshotKey(false); err != nil || got != changed {
+		t.Errorf("in read-only mode snapshotKey = %q, %v; want %q", got, err, changed)
+	}
+	if after := mustGit(t, "count-objects", "-v"); after != before {
+		t.Errorf("snapshotKey wrote objects:\n%s\nthen\n%s", before, after)
+	}
+}
+
+func TestSnapshotCache(t *testing.T) {
+	testRepo(t)
+	commitFile(t, "a.txt", "one\n", "Add a")
+	p, err := snapshotCachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A cache written before it was private is tightened on the next save.
+	writeFile(t, p, "{}")
+	os.Chmod(p, 0644)
+
+	saveCachedSnapshot(&repoSnapshot{Key: "k", Diff: "+password = hunter2\n"})
+	if snap := loadCachedSnapshot("k"); snap == nil || snap.Diff != "+password = hunter2\n" || !snap.cached {
+		t.Errorf("loadCachedSnapshot = %+v", snap)
+	}
+	if snap := loadCachedSnapshot("other"); snap != nil {
+		t.Errorf("a different key loaded %+v", snap)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); runtime.GOOS != "windows" && mode != 0600 {
+		t.Errorf("the cache has mode %v, want 0600", mode)
+	}
+}
+
+// TestSnapshotCachedAfterScan checks that gathering alone stores nothing:
+// the snapshot is only cached once the secret scanner has passed it.
+func TestSnapshotCachedAfterScan(t *testing.T) {
+	testRepo(t)
+	commitFile(t, "a.txt", "one\n", "Add a")
+	writeFile(t, "a.txt", "one\ntwo\n")
+	mustGit(t, "add", "a.txt")
+	p, err := snapshotCachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := gatherSnapshot(false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("gathering wrote the cache (%v)", err)
+	}
+	snap.cache()
+	again, err := gatherSnapshot(false, false)
+	if err != nil || !again.cached || again.Diff != snap.Diff {
+		t.Errorf("the second gather wasn't served from the cache: %+v, %v", again, err)
+	}
+
+	// With -a nothing is cached.
+	all, err := gatherSnapshot(true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(p)
+	all.cache()
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("a -a snapshot was cached (%v)", err)
+	}
+}
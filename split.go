@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"sort"
+	"strings"
+)
+
+// dirGroup is the staged changes under one directory, committed together
+// by -split-by-dir.
+type dirGroup struct {
+	Dir   string
+	Paths []string
+}
+
+// dirKey returns the directory of p cut to depth components. Files above
+// that depth are grouped by their own directory, and files at the root of
+// the repository under ".".
+func dirKey(p string, depth int) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return "."
+	}
+	parts := strings.Split(dir, "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// groupByDir groups staged changes by directory, in path order. A rename
+// goes with its new path and takes the deletion of the old path along, so
+// it is never split into an unrelated add and delete.
+func groupByDir(changes []fileChange, depth int) []dirGroup {
+	byDir := map[string]*dirGroup{}
+	var dirs []string
+	for _, c := range changes {
+		key := dirKey(c.Path, depth)
+		g, ok := byDir[key]
+		if !ok {
+			g = &dirGroup{Dir: key}
+			byDir[key] = g
+			dirs = append(dirs, key)
+		}
+		if c.Status == 'R' {
+			g.Paths = append(g.Paths, c.OldPath)
+		}
+		g.Paths = append(g.Paths, c.Path)
+	}
+	sort.Strings(dirs)
+	groups := make([]dirGroup, 0, len(dirs))
+	for _, d := range dirs {
+		groups = append(groups, *byDir[d])
+	}
+	return groups
+}
+
+// restrictIndex makes the index hold HEAD plus the staged versions of
+// paths, taken from the tree written before splitting. The work tree is
+// not touched.
+func restrictIndex(staged string, paths []string) error {
+	if hasHead() {
+		if _, err := gitRun("read-tree", "HEAD"); err != nil {
+			return err
+		}
+	} else if _, err := gitRun("read-tree", "--empty"); err != nil {
+		return err
+	}
+	cmd := gitCommandEnv([]string{"GIT_LITERAL_PATHSPECS=1"}, append([]string{"reset", "-q", staged, "--"}, paths...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error staging %s: %v\n%s", strings.Join(paths, ", "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// runSplitByDir commits the staged changes one directory group at a time.
+// Each group runs as a normal gitcommit invocation with args, with only
+// that group staged. Between groups, and whenever a group is aborted or
+// fails, the index goes back to the changes staged at the start, so
+// whatever hasn't been committed is staged exactly as it was.
+func runSplitByDir(depth int, args []string) error {
+	changes, err := getNameStatus(false)
+	if err != nil {
+		return err
+	}
+	groups := groupByDir(changes, depth)
+	if len(groups) == 0 {
+		return fmt.Errorf("no staged changes found")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error locating gitcommit: %v", err)
+	}
+	staged, err := gitRun("write-tree")
+	if err != nil {
+		return fmt.Errorf("error recording the staged changes: %v", err)
+	}
+	backup, err := createBackup()
+	if err != nil {
+		return err
+	}
+	restore := func() error {
+		if _, err := gitRun("read-tree", staged); err != nil {
+			return fmt.Errorf("error restoring the staged changes (gitcommit restore-snapshot recovers them): %v", err)
+		}
+		return nil
+	}
+
+	// Ctrl-C reaches the group's run too; it ends that run, and we restore
+	// the index instead of dying with only one group staged.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	var committed, skipped []string
+	for i, g := range groups {
+		fmt.Printf("\n=== %d/%d: %s (%d file(s)) ===\n", i+1, len(groups), g.Dir, len(g.Paths))
+		if err := restrictIndex(staged, g.Paths); err != nil {
+			if rerr := restore(); rerr != nil {
+				return rerr
+			}
+			return err
+		}
+		resetHeadCache()
+		before, _ := gitRun("rev-parse", "--verify", "--quiet", "HEAD")
+
+		cmd := exec.Command(exe, args...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		runErr := cmd.Run()
+		if err := restore(); err != nil {
+			return err
+		}
+		if runErr != nil {
+			dropBackup(backup)
+			if len(committed) > 0 {
+				fmt.Printf("Committed %s; the rest is staged as before.\n", strings.Join(committed, ", "))
+			}
+			return fmt.Errorf("stopped at %s", g.Dir)
+		}
+		if after, _ := gitRun("rev-parse", "--verify", "--quiet", "HEAD"); after != before {
+			committed = append(committed, g.Dir)
+		} else {
+			skipped = append(skipped, g.Dir)
+		}
+	}
+	dropBackup(backup)
+	fmt.Printf("\nCommitted %d of %d group(s).\n", len(committed), len(groups))
+	if len(skipped) > 0 {
+		fmt.Printf("Still staged: %s\n", strings.Join(skipped, ", "))
+	}
+	return nil
+}
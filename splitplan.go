@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const splitSystemPrompt = `You help keep Git history atomic. Given a staged change, decide whether it
+mixes unrelated changes and, if so, how to break it into logical commits,
+each of which makes sense on its own. Respond with ONLY a JSON object wrapped
+in triple backticks, with this field:
+  "commits": a list, in the order they should be committed, of
+             {"message": "<commit message>", "files": ["<path>", ...],
+              "note": "<which hunks belong here>"}
+Use the paths exactly as they appear in the diff. Put every changed file in
+at least one commit. A file belongs in more than one commit only when its
+hunks are unrelated; then say in "note" which hunks go where, and leave
+"note" empty otherwise. When the change is already one logical commit,
+return a single entry.`
+
+// splitPart is one commit of a suggested split.
+type splitPart struct {
+	Message string   `json:"message"`
+	Files   []string `json:"files"`
+	Note    string   `json:"note,omitempty"`
+}
+
+type splitPlan struct {
+	Commits []splitPart `json:"commits"`
+}
+
+// suggestSplit asks how to break the staged change into commits. Paths
+// the diff doesn't touch are dropped, with a warning, and changed paths
+// the plan forgets are returned as left over.
+func suggestSplit(changes string, files []fileChange, apiKey string, opts apiOptions) (*splitPlan, []string, error) {
+	opts.System = splitSystemPrompt
+	response, err := askClaude("Suggest how to split this staged change into commits.\n\n"+changes, apiKey, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var plan splitPlan
+	if err := json.Unmarshal([]byte(extractCommitMessage(response)), &plan); err != nil {
+		return nil, nil, fmt.Errorf("error parsing suggestion: %v", err)
+	}
+	changed := map[string]bool{}
+	for _, f := range files {
+		changed[f.Path] = true
+	}
+	covered := map[string]bool{}
+	var parts []splitPart
+	for _, p := range plan.Commits {
+		var kept []string
+		for _, f := range p.Files {
+			if !changed[f] {
+				say("Warning: the suggestion names %s, which has no staged changes\n", f)
+				continue
+			}
+			kept = append(kept, f)
+			covered[f] = true
+		}
+		if len(kept) > 0 && strings.TrimSpace(p.Message) != "" {
+			p.Files = kept
+			p.Message = strings.TrimSpace(p.Message)
+			parts = append(parts, p)
+		}
+	}
+	plan.Commits = parts
+	var left []string
+	for _, f := range files {
+		if !covered[f.Path] {
+			left = append(left, f.Path)
+		}
+	}
+	return &plan, left, nil
+}
+
+// printSplitPlan shows the suggested commits.
+func printSplitPlan(plan *splitPlan, left []string) {
+	if len(plan.Commits) == 1 {
+		fmt.Println("\nThis looks like one logical change; no split is suggested. Its message would be:")
+		fmt.Printf("%s\n", plan.Commits[0].Message)
+		return
+	}
+	fmt.Printf("\nSuggested split into %d commits (nothing has been changed):\n", len(plan.Commits))
+	for i, p := range plan.Commits {
+		subject, body := splitMessage(p.Message)
+		fmt.Printf("\n%d. %s\n", i+1, subject)
+		if body = strings.TrimSpace(body); body != "" {
+			fmt.Printf("   %s\n", strings.ReplaceAll(body, "\n", "\n   "))
+		}
+		fmt.Printf("   files: %s\n", strings.Join(p.Files, ", "))
+		if p.Note != "" {
+			fmt.Printf("   hunks: %s\n", p.Note)
+		}
+	}
+	if len(left) > 0 {
+		fmt.Printf("\nNot in any commit: %s\n", strings.Join(left, ", "))
+	}
+}
+
+// splitsByHunk reports whether any file is shared between commits, which
+// takes staging by hunk (git add -p) and can't be done file by file.
+func (plan *splitPlan) splitsByHunk() bool {
+	seen := map[string]bool{}
+	for _, p := range plan.Commits {
+		for _, f := range p.Files {
+			if seen[f] {
+				return true
+			}
+			seen[f] = true
+		}
+	}
+	return false
+}
+
+// commitSplitPlan stages and commits each part in turn, after confirming
+// or editing its message. As with -split-by-dir, the index goes back to
+// the changes staged at the start after every part, so whatever hasn't
+// been committed stays staged as it was.
+func commitSplitPlan(plan *splitPlan, files []fileChange, opts commitOptions, settings *gitSettings) error {
+	staged, err := gitRun("write-tree")
+	if err != nil {
+		return fmt.Errorf("error recording the staged changes: %v", err)
+	}
+	backup, err := createBackup()
+	if err != nil {
+		return err
+	}
+	restore := func() error {
+		if _, err := gitRun("read-tree", staged); err != nil {
+			return fmt.Errorf("error restoring the staged changes (gitcommit restore-snapshot recovers them): %v", err)
+		}
+		return nil
+	}
+	oldPaths := map[string]string{}
+	for _, f := range files {
+		if f.Status == 'R' {
+			oldPaths[f.Path] = f.OldPath
+		}
+	}
+
+	committed := 0
+	for i, p := range plan.Commits {
+		fmt.Printf("\n=== %d/%d: %s ===\n%s\n", i+1, len(plan.Commits), strings.Join(p.Files, ", "), p.Message)
+		message := p.Message
+		switch getUserInput("Commit this part? (y/n/e to edit): ") {
+		case "y":
+		case "e":
+			edited, err := editInEditor(settings.wrap(message))
+			if err != nil {
+				say("Error editing message: %v\n", err)
+				continue
+			}
+			message = strings.TrimSpace(settings.unwrap(edited))
+		default:
+			fmt.Println("Skipped; those changes stay staged.")
+			continue
+		}
+		paths := append([]string{}, p.Files...)
+		for _, f := range p.Files {
+			if old, ok := oldPaths[f]; ok {
+				paths = append(paths, old)
+			}
+		}
+		if err := restrictIndex(staged, paths); err != nil {
+			if rerr := restore(); rerr != nil {
+				return rerr
+			}
+			return err
+		}
+		_, err := gitCommit(message, opts)
+		if rerr := restore(); rerr != nil {
+			return rerr
+		}
+		if err != nil {
+			dropBackup(backup)
+			return err
+		}
+		resetHeadCache()
+		committed++
+	}
+	dropBackup(backup)
+	fmt.Printf("\nCommitted %d of %d part(s).\n", committed, len(plan.Commits))
+	return nil
+}
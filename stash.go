@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// resolveStash turns a stash reference ("stash@{1}", or just "1") into a
+// ref git accepts and checks that it names a stash entry.
+func resolveStash(ref string) (string, error) {
+	if _, err := strconv.Atoi(ref); err == nil {
+		ref = "stash@{" + ref + "}"
+	}
+	sha, err := gitRun("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil || sha == "" {
+		return "", fmt.Errorf("stash %s not found (see git stash list)", ref)
+	}
+	// Stash commits always record the index as a second parent.
+	if _, err := gitRun("rev-parse", "--verify", "--quiet", sha+"^2"); err != nil {
+		return "", fmt.Errorf("%s is not a stash entry", ref)
+	}
+	return ref, nil
+}
+
+// gatherStashSnapshot collects the same information as gatherSnapshot, but
+// for the changes recorded in a stash instead of the index.
+func gatherStashSnapshot(ref string, history bool) (*repoSnapshot, error) {
+	diff, truncated, err := readCapped(gitCommand("stash", "show", "-p", ref), maxDiffReadBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stash diff: %v", err)
+	}
+	snap := &repoSnapshot{Diff: diff, Truncated: truncated}
+	output, err := gitCommand("stash", "show", "--name-status", "-z", "-M", ref).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting changed files: %v", err)
+	}
+	snap.Changes = parseNameStatus(string(output))
+	if history {
+		snap.History = getFileHistory(changePaths(snap.Changes))
+	}
+	return snap, nil
+}
+
+// commitStash applies the stash to the work tree, stages exactly the paths
+// it touches and commits them. The stash itself is kept so it can be
+// dropped once the commit looks right.
+func commitStash(ref, message string, changes []fileChange, opts commitOptions) (string, error) {
+	if _, err := gitRun("diff", "--cached", "--quiet"); err != nil {
+		return "", fmt.Errorf("the index already has staged changes; commit or unstage them before applying %s", ref)
+	}
+	backup, err := createBackup()
+	if err != nil {
+		return "", err
+	}
+	if _, err := gitRun("stash", "apply", ref); err != nil {
+		return "", fmt.Errorf("error applying %s: %v", ref, err)
+	}
+	var paths []string
+	for _, c := range changes {
+		if c.OldPath != "" {
+			paths = append(paths, c.OldPath)
+		}
+		paths = append(paths, c.Path)
+	}
+	if _, err := gitRun(append([]string{"add", "-A", "--"}, paths...)...); err != nil {
+		return "", fmt.Errorf("error staging %s: %v", ref, err)
+	}
+	output, err := gitCommit(message, opts)
+	if err != nil {
+		return output, err
+	}
+	dropBackup(backup)
+	fmt.Printf("Applied %s. Once you're happy with the commit, remove it with:\n  git stash drop %s\n", ref, ref)
+	return output, nil
+}
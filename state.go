@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// repoState is gitcommit's per-repository state, stored inside the git
+// directory so it is never committed.
+type repoState struct {
+	// Hints maps a directory prefix ("internal/auth/") to counts of the
+	// conventional "type(scope)" pairs used for commits touching it.
+	Hints map[string]map[string]int `json:"hints,omitempty"`
+	// PromptVersion is the built-in prompt version of the last run, for
+	// the notice when an upgrade changes it.
+	PromptVersion string `json:"prompt_version,omitempty"`
+}
+
+// repoStateDir returns the directory holding gitcommit's repo-local files.
+func repoStateDir() (string, error) {
+	output, err := gitCommand("rev-parse", "--git-path", "gitcommit").Output()
+	if err != nil {
+		return "", fmt.Errorf("error locating git directory: %v", err)
+	}
+	dir, err := filepath.Abs(strings.TrimSpace(string(output)))
+	if err != nil {
+		return "", fmt.Errorf("error locating git directory: %v", err)
+	}
+	return dir, nil
+}
+
+func repoStatePath() (string, error) {
+	dir, err := repoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "state.json"), nil
+}
+
+func loadRepoState() (*repoState, error) {
+	p, err := repoStatePath()
+	if err != nil {
+		return nil, err
+	}
+	state := &repoState{}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error parsing state %s: %v", p, err)
+	}
+	return state, nil
+}
+
+func saveRepoState(state *repoState) error {
+	p, err := repoStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("error creating state directory: %v", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding state: %v", err)
+	}
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		return fmt.Errorf("error writing state: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default timeouts. The deadline is deliberately generous: it only exists
+// so a stuck generation eventually ends, while the connect timeout catches
+// an unreachable endpoint quickly.
+const (
+	defaultRequestTimeout = 5 * time.Minute
+	defaultConnectTimeout = 10 * time.Second
+)
+
+// minSalvageBytes is how much text an interrupted stream must have produced
+// before it is worth trying to salvage a message from it.
+const minSalvageBytes = 40
+
+// apiClients are reused so connections to the API stay warm across the
+// requests of a run, or of a server's lifetime.
+var (
+	apiClientsMu sync.Mutex
+	apiClients   = map[time.Duration]*http.Client{}
+)
+
+// apiClient returns an HTTP client whose timeout covers only connecting and
+// the TLS handshake, so long generations are bounded by the request's
+// deadline instead.
+func apiClient(connectTimeout time.Duration) *http.Client {
+	apiClientsMu.Lock()
+	defer apiClientsMu.Unlock()
+	if c, ok := apiClients[connectTimeout]; ok {
+		return c
+	}
+	c := &http.Client{}
+	if connectTimeout > 0 {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = (&net.Dialer{Timeout: connectTimeout}).DialContext
+		transport.TLSHandshakeTimeout = connectTimeout
+		c.Transport = transport
+	}
+	apiClients[connectTimeout] = c
+	return c
+}
+
+// requestError explains a failed request, naming the deadline when that is
+// what ended it. Partial stream results are kept so they can be salvaged.
+func requestError(ctx context.Context, deadline time.Duration, err error) error {
+	var partial *partialResponseError
+	if errors.As(err, &partial) {
+		partial.Err = requestError(ctx, deadline, partial.Err)
+		return partial
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("request timed out after %s (raise it with -timeout)", deadline)
+	}
+	return fmt.Errorf("error making request: %v", err)
+}
+
+// partialResponseError is returned when a stream fails after producing a
+// substantial amount of text.
+type partialResponseError struct {
+	Text string
+	Err  error
+}
+
+func (e *partialResponseError) Error() string {
+	return fmt.Sprintf("response interrupted after %d bytes: %v", len(e.Text), e.Err)
+}
+
+func (e *partialResponseError) Unwrap() error {
+	return e.Err
+}
+
+// streamEvent is the subset of the server-sent events we use.
+type streamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage Usage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage Usage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readStream reads a streamed response, copying text to w as it arrives,
+// and returns the full text. On failure it returns the text that did
+// arrive along with the error.
+func readStream(body io.Reader, w io.Writer, usage *usageTotals) (string, error) {
+	var (
+		text          strings.Builder
+		input, output int
+	)
+	record := func() {
+		if usage != nil {
+			usage.Requests++
+			usage.InputTokens += input
+			usage.OutputTokens += output
+		}
+	}
+	fail := func(err error) (string, error) {
+		if output == 0 {
+			// The final usage never arrived; estimate what was generated
+			// so a stopped request still shows in the usage stats.
+			output = (text.Len() + 3) / 4
+		}
+		record()
+		fmt.Fprintln(w)
+		if text.Len() >= minSalvageBytes {
+			return text.String(), &partialResponseError{Text: text.String(), Err: err}
+		}
+		return text.String(), err
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var ev streamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+			return fail(fmt.Errorf("error decoding stream event: %v", err))
+		}
+		switch ev.Type {
+		case "message_start":
+			input = ev.Message.Usage.InputTokens
+		case "content_block_delta":
+			if ev.Delta.Type == "text_delta" {
+				text.WriteString(ev.Delta.Text)
+				fmt.Fprint(w, ev.Delta.Text)
+			}
+		case "message_delta":
+			output = ev.Usage.OutputTokens
+		case "error":
+			return fail(fmt.Errorf("API error: %s - %s", ev.Error.Type, ev.Error.Message))
+		case "message_stop":
+			record()
+			fmt.Fprintln(w)
+			if text.Len() == 0 {
+				return "", fmt.Errorf("empty response from API")
+			}
+			return text.String(), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fail(err)
+	}
+	return fail(fmt.Errorf("stream ended before the response was complete"))
+}
+
+// salvageMessage looks for a plausible complete commit message in the
+// partial text of an interrupted response: a closed fenced block, or an
+// unclosed one that already holds a subject, a blank line and a body.
+func salvageMessage(text string) (string, bool) {
+	if message := extractCommitMessage(text); message != "" {
+		return message, true
+	}
+	_, rest, ok := strings.Cut(strings.ReplaceAll(text, "\r\n", "\n"), "```")
+	if !ok {
+		return "", false
+	}
+	// Drop the rest of the fence line (an info string, if any).
+	if _, after, ok := strings.Cut(rest, "\n"); ok {
+		rest = after
+	}
+	subject, body := splitMessage(strings.TrimSpace(rest))
+	if subject == "" || len(subject) > maxSubjectLength || strings.TrimSpace(body) == "" {
+		return "", false
+	}
+	return joinMessage(subject, strings.TrimSpace(body)), true
+}
+
+// salvagePartial returns a message recovered from an interrupted stream, or
+// false if err isn't one or nothing plausible was produced.
+func salvagePartial(err error) (string, bool) {
+	var partial *partialResponseError
+	if !errors.As(err, &partial) {
+		return "", false
+	}
+	message, ok := salvageMessage(partial.Text)
+	if ok {
+		say("Warning: %v\n", err)
+	}
+	return message, ok
+}
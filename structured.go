@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// commitMessageTool is the tool -structured makes the model call, so the
+// message arrives as JSON fields instead of a fenced block in free text.
+var commitMessageTool = Tool{
+	Name:        "commit_message",
+	Description: "Give the commit message for the change, or, if it can't be written without more information, the one question to ask instead.",
+	InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "subject": {"type": "string", "description": "The subject line, without a trailing period."},
+    "body": {"type": "string", "description": "The body, wrapped as it should appear; empty when the subject says it all."},
+    "question": {"type": "string", "description": "Only when more information is needed: the question to ask. Leave subject and body empty then."}
+  }
+}`),
+}
+
+// structuredReply is the input of a commit_message tool call.
+type structuredReply struct {
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	Question string `json:"question"`
+}
+
+// parseStructured reads a commit_message tool call. It is an error for it
+// to carry neither a subject nor a question.
+func parseStructured(input string) (structuredReply, error) {
+	var r structuredReply
+	if err := json.Unmarshal([]byte(input), &r); err != nil {
+		return r, fmt.Errorf("error decoding structured response: %v", err)
+	}
+	r.Subject = strings.TrimSpace(r.Subject)
+	r.Body = strings.TrimSpace(r.Body)
+	r.Question = strings.TrimSpace(r.Question)
+	if r.Subject == "" && r.Question == "" {
+		return r, fmt.Errorf("structured response has neither a subject nor a question")
+	}
+	return r, nil
+}
+
+// message returns the commit message, or "" when the reply is a question.
+func (r structuredReply) message() string {
+	if r.Subject == "" {
+		return ""
+	}
+	return joinMessage(r.Subject, r.Body)
+}
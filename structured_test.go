@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseStructured sends each reply as the input of a tool_use block
+// and reads it back the way -structured does.
+func TestParseStructured(t *testing.T) {
+	var sent MessagesRequest
+	var input string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		json.NewEncoder(w).Encode(MessagesResponse{Content: []ContentBlock{
+			{Type: "text", Text: "Calling the tool."},
+			{Type: "tool_use", Input: json.RawMessage(input)},
+		}})
+	}))
+	defer api.Close()
+	old := apiURL
+	apiURL = api.URL
+	t.Cleanup(func() { apiURL = old })
+
+	tests := []struct {
+		name, input, message, question string
+		fails                          bool
+	}{
+		{"subject and body", `{"subject": " Fix the parser ", "body": "It dropped the last line.\n"}`, "Fix the parser\n\nIt dropped the last line.", "", false},
+		{"subject only", `{"subject": "Fix the parser", "body": "  "}`, "Fix the parser", "", false},
+		{"question", `{"subject": "", "question": "Which bug does this fix? "}`, "", "Which bug does this fix?", false},
+		{"wrong type", `{"subject": 12}`, "", "", true},
+		{"neither field", `{"body": "It dropped the last line."}`, "", "", true},
+	}
+	for _, tt := range tests {
+		input = tt.input
+		response, err := askClaude("diff", "test", apiOptions{Tool: &commitMessageTool})
+		if err != nil {
+			t.Fatalf("%s: askClaude: %v", tt.name, err)
+		}
+		reply, err := parseStructured(response)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%s: parseStructured(%s) = %+v, want an error", tt.name, response, reply)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: parseStructured(%s): %v", tt.name, response, err)
+			continue
+		}
+		if got := reply.message(); got != tt.message {
+			t.Errorf("%s: message() = %q, want %q", tt.name, got, tt.message)
+		}
+		if reply.Question != tt.question {
+			t.Errorf("%s: question %q, want %q", tt.name, reply.Question, tt.question)
+		}
+	}
+	// A tool_use block always carries a JSON object, so malformed input
+	// can only reach the parser directly.
+	if reply, err := parseStructured(`{"subject": "Fix the parser"`); err == nil {
+		t.Errorf("malformed JSON gave %+v", reply)
+	}
+	if len(sent.Tools) != 1 || sent.Tools[0].Name != "commit_message" || sent.ToolChoice == nil || sent.ToolChoice.Name != "commit_message" {
+		t.Errorf("the request didn't require the commit_message tool: %+v, %+v", sent.Tools, sent.ToolChoice)
+	}
+}
+
+func TestAskClaudeWithoutToolCall(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(MessagesResponse{Content: []ContentBlock{{Type: "text", Text: "Fix the parser"}}})
+	}))
+	defer api.Close()
+	old := apiURL
+	apiURL = api.URL
+	t.Cleanup(func() { apiURL = old })
+
+	if _, err := askClaude("diff", "test", apiOptions{Tool: &commitMessageTool}); err == nil || !strings.Contains(err.Error(), "commit_message") {
+		t.Errorf("a reply without the tool call gave %v", err)
+	}
+}
+
+// TestStructuredCommit runs gitcommit -structured against an API that
+// answers with a tool_use block and checks the fields become the commit.
+func TestStructuredCommit(t *testing.T) {
+	testRepo(t)
+	writeFile(t, "README.md", "# Demo\n")
+	mustGit(t, "add", ".")
+
+	reply := []ContentBlock{{Type: "tool_use", Input: json.RawMessage(`{"subject": "Add a README", "body": "It says what the demo is."}`)}}
+	out := runGitcommitReply(t, reply, "readme\ny\n", []string{"NO_COLOR=1"}, "-structured")
+	if got, want := mustGit(t, "log", "-1", "--format=%B"), "Add a README\n\nIt says what the demo is."; got != want {
+		t.Errorf("committed message = %q, want %q\noutput:\n%s", got, want, out)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// maxSubmoduleCommits caps how many of a submodule's new commits are listed.
+const maxSubmoduleCommits = 10
+
+// submoduleChange is a change to a submodule's recorded commit, which the
+// diff only shows as "Subproject commit <sha>" lines.
+type submoduleChange struct {
+	Path     string
+	Old, New string // empty when the submodule is added or removed
+	// Commits are the subjects of the commits between Old and New, newest
+	// first, when the submodule is checked out with them; Rewound says New
+	// is behind Old rather than ahead of it.
+	Commits []string
+	More    int
+	Rewound bool
+	// Subject is New's subject, for an added submodule or when the commits
+	// in between aren't available.
+	Subject string
+}
+
+// findSubmoduleChanges returns the submodule entries among the raw changes,
+// described from the submodules' own history where it is checked out.
+func findSubmoduleChanges(raw []rawChange) []submoduleChange {
+	top, _ := gitRun("rev-parse", "--show-toplevel")
+	var changes []submoduleChange
+	for _, ch := range raw {
+		if ch.OldMode != modeGitlink && ch.NewMode != modeGitlink {
+			continue
+		}
+		dir := filepath.Join(top, ch.Path)
+		s := submoduleChange{Path: ch.Path}
+		if ch.OldMode == modeGitlink {
+			s.Old = ch.OldSha
+		}
+		if ch.NewMode == modeGitlink {
+			s.New = ch.NewSha
+			// With -a the new commit is only in the work tree: it is
+			// whatever the submodule has checked out.
+			if isNullSha(s.New) {
+				s.New, _ = submoduleGit(dir, "rev-parse", "HEAD")
+			}
+		}
+		if s.Old != "" && s.New != "" && s.Old != s.New {
+			s.Commits, s.More, s.Rewound = submoduleLog(dir, s.Old, s.New)
+		}
+		if s.New != "" && len(s.Commits) == 0 {
+			s.Subject, _ = submoduleGit(dir, "log", "-1", "--format=%s", s.New)
+		}
+		changes = append(changes, s)
+	}
+	return changes
+}
+
+// submoduleGit runs git in a submodule's work tree. GIT_DIR and the like
+// are dropped, since in a hook they point at the superproject.
+func submoduleGit(dir string, args ...string) (string, error) {
+	cmd := gitCommand(append([]string{"-C", dir}, args...)...)
+	env := cmd.Env[:0]
+	for _, kv := range cmd.Env {
+		name, _, _ := strings.Cut(kv, "=")
+		switch name {
+		case "GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE", "GIT_OBJECT_DIRECTORY", "GIT_COMMON_DIR", "GIT_PREFIX":
+			continue
+		}
+		env = append(env, kv)
+	}
+	cmd.Env = env
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// submoduleLog lists the subjects of the commits from old to new, or from
+// new back to old for a submodule moved to an earlier commit. Either fails
+// quietly when the submodule isn't checked out or lacks the commits.
+func submoduleLog(dir, old, new string) (subjects []string, more int, rewound bool) {
+	if _, err := submoduleGit(dir, "merge-base", "--is-ancestor", old, new); err != nil {
+		if _, err := submoduleGit(dir, "merge-base", "--is-ancestor", new, old); err != nil {
+			return nil, 0, false
+		}
+		old, new, rewound = new, old, true
+	}
+	output, err := submoduleGit(dir, "log", "--format=%s", "--no-merges", old+".."+new)
+	if err != nil || output == "" {
+		return nil, 0, rewound
+	}
+	subjects = strings.Split(output, "\n")
+	if len(subjects) > maxSubmoduleCommits {
+		more = len(subjects) - maxSubmoduleCommits
+		subjects = subjects[:maxSubmoduleCommits]
+	}
+	return subjects, more, rewound
+}
+
+func shortSha(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// fact describes the change in plain words.
+func (s submoduleChange) fact() string {
+	switch {
+	case s.Old == "":
+		fact := fmt.Sprintf("add submodule %s at %s", s.Path, shortSha(s.New))
+		if s.Subject != "" {
+			fact += fmt.Sprintf(" (%q)", s.Subject)
+		}
+		return fact
+	case s.New == "":
+		return fmt.Sprintf("remove submodule %s (was at %s)", s.Path, shortSha(s.Old))
+	case s.Old == s.New:
+		return fmt.Sprintf("submodule %s has changes of its own that aren't committed in it", s.Path)
+	}
+	verb := "update"
+	if s.Rewound {
+		verb = "move back"
+	}
+	fact := fmt.Sprintf("%s submodule %s from %s to %s", verb, s.Path, shortSha(s.Old), shortSha(s.New))
+	switch {
+	case len(s.Commits) > 0:
+		what := "new commits"
+		if s.Rewound {
+			what = "commits no longer included"
+		}
+		fact += fmt.Sprintf(", %d %s:", len(s.Commits)+s.More, what)
+		for _, c := range s.Commits {
+			fact += "\n  - " + c
+		}
+		if s.More > 0 {
+			fact += fmt.Sprintf("\n  - ... and %d more", s.More)
+		}
+	case s.Subject != "":
+		fact += fmt.Sprintf(" (%q)", s.Subject)
+	default:
+		fact += " (its commits aren't available locally)"
+	}
+	return fact
+}
+
+// submodulePrompt explains the "Subproject commit" lines of the diff.
+func submodulePrompt(changes []submoduleChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Submodule changes (the \"Subproject commit\" lines in the diff; describe what the submodule update brings in, not the commit ids):\n")
+	for _, s := range changes {
+		fmt.Fprintf(&b, "- %s\n", s.fact())
+	}
+	return b.String()
+}
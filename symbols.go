@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// hunkLines records which lines a file's diff touches: deleted lines by
+// their number in the old file, added lines by their number in the new one.
+type hunkLines struct {
+	Old map[int]bool
+	New map[int]bool
+}
+
+// parseHunkLines reads the changed line numbers out of one file's diff.
+func parseHunkLines(text string) hunkLines {
+	h := hunkLines{Old: map[int]bool{}, New: map[int]bool{}}
+	var oldLine, newLine int
+	inHunk := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			// @@ -oldStart[,n] +newStart[,n] @@
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				inHunk = false
+				continue
+			}
+			oldLine = hunkStart(fields[1])
+			newLine = hunkStart(fields[2])
+			inHunk = true
+			continue
+		}
+		if !inHunk {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+"):
+			h.New[newLine] = true
+			newLine++
+		case strings.HasPrefix(line, "-"):
+			h.Old[oldLine] = true
+			oldLine++
+		case strings.HasPrefix(line, " "):
+			oldLine++
+			newLine++
+		}
+	}
+	return h
+}
+
+func hunkStart(field string) int {
+	start, _, _ := strings.Cut(strings.TrimLeft(field, "-+"), ",")
+	n, _ := strconv.Atoi(start)
+	return n
+}
+
+// goDecl is a top-level Go declaration and the lines it spans.
+type goDecl struct {
+	Name       string
+	Start, End int
+}
+
+// goDecls lists the top-level declarations in src. Parse errors are
+// tolerated as far as go/parser allows.
+func goDecls(src []byte) []goDecl {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+	var decls []goDecl
+	add := func(name string, node ast.Node) {
+		decls = append(decls, goDecl{name, fset.Position(node.Pos()).Line, fset.Position(node.End()).Line})
+	}
+	for _, d := range file.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			name := "func " + d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = fmt.Sprintf("method (%s).%s", exprString(d.Recv.List[0].Type), d.Name.Name)
+			}
+			add(name, d)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				// A lone spec covers its whole declaration, including the
+				// keyword and doc comment position.
+				var node ast.Node = spec
+				if len(d.Specs) == 1 {
+					node = d
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					add("type "+spec.Name.Name, node)
+				case *ast.ValueSpec:
+					for _, n := range spec.Names {
+						if n.Name != "_" {
+							add(d.Tok.String()+" "+n.Name, node)
+						}
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+func exprString(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	case *ast.Ident:
+		return e.Name
+	case *ast.IndexExpr:
+		return exprString(e.X)
+	case *ast.IndexListExpr:
+		return exprString(e.X)
+	}
+	return "?"
+}
+
+// touched returns the names of declarations that contain a changed line.
+func touched(decls []goDecl, lines map[int]bool) map[string]bool {
+	names := map[string]bool{}
+	for _, d := range decls {
+		for l := d.Start; l <= d.End; l++ {
+			if lines[l] {
+				names[d.Name] = true
+				break
+			}
+		}
+	}
+	return names
+}
+
+// fileSymbols describes how a file's top-level declarations changed.
+type fileSymbols struct {
+	Path                     string
+	Added, Removed, Modified []string
+}
+
+// changedGoSymbols compares the declarations touched on each side of the
+// diff. A declaration only in the new version was added, one only in the
+// old version was removed, and one on both sides was modified.
+func changedGoSymbols(path string, oldSrc, newSrc []byte, h hunkLines) fileSymbols {
+	oldDecls, newDecls := goDecls(oldSrc), goDecls(newSrc)
+	inOld, inNew := map[string]bool{}, map[string]bool{}
+	for _, d := range oldDecls {
+		inOld[d.Name] = true
+	}
+	for _, d := range newDecls {
+		inNew[d.Name] = true
+	}
+
+	fs := fileSymbols{Path: path}
+	seen := map[string]bool{}
+	touchedNew := touched(newDecls, h.New)
+	for _, d := range newDecls {
+		if !touchedNew[d.Name] || seen[d.Name] {
+			continue
+		}
+		seen[d.Name] = true
+		if inOld[d.Name] {
+			fs.Modified = append(fs.Modified, d.Name)
+		} else {
+			fs.Added = append(fs.Added, d.Name)
+		}
+	}
+	touchedOld := touched(oldDecls, h.Old)
+	for _, d := range oldDecls {
+		if !touchedOld[d.Name] || seen[d.Name] {
+			continue
+		}
+		seen[d.Name] = true
+		if inNew[d.Name] {
+			fs.Modified = append(fs.Modified, d.Name)
+		} else {
+			fs.Removed = append(fs.Removed, d.Name)
+		}
+	}
+	return fs
+}
+
+// fileVersions returns the old and new content of a changed file for the
+// kind of commit being made. Missing versions (added or deleted files) are
+// returned as nil.
+func fileVersions(path, oldPath string, all bool, stash string) (oldSrc, newSrc []byte) {
+	if oldPath == "" {
+		oldPath = path
+	}
+	show := func(rev string) []byte {
+		output, err := gitCommand("show", rev).Output()
+		if err != nil {
+			return nil
+		}
+		return output
+	}
+	switch {
+	case stash != "":
+		return show(stash + "^1:" + oldPath), show(stash + ":" + path)
+	case all:
+		newSrc, _ = os.ReadFile(path)
+	default:
+		newSrc = show(":" + path)
+	}
+	if hasHead() {
+		oldSrc = show("HEAD:" + oldPath)
+	}
+	return oldSrc, newSrc
+}
+
+// changedSymbols lists the top-level declarations changed in each Go file
+// of the diff. It is best-effort: files that can't be read or parsed are
+// skipped.
+func changedSymbols(diff string, changes []fileChange, all bool, stash string) []fileSymbols {
+	oldPaths := map[string]string{}
+	for _, c := range changes {
+		oldPaths[c.Path] = c.OldPath
+	}
+	var result []fileSymbols
+	for _, f := range splitDiff(diff) {
+		if !strings.HasSuffix(f.Path, ".go") || !hasHunks(f.Text) {
+			continue
+		}
+		oldSrc, newSrc := fileVersions(f.Path, oldPaths[f.Path], all, stash)
+		fs := changedGoSymbols(f.Path, oldSrc, newSrc, parseHunkLines(f.Text))
+		if len(fs.Added)+len(fs.Removed)+len(fs.Modified) > 0 {
+			result = append(result, fs)
+		}
+	}
+	return result
+}
+
+func symbolsPrompt(files []fileSymbols) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Changed top-level declarations:\n")
+	for _, f := range files {
+		var parts []string
+		for _, group := range []struct {
+			label string
+			names []string
+		}{{"modified", f.Modified}, {"added", f.Added}, {"removed", f.Removed}} {
+			if len(group.names) > 0 {
+				parts = append(parts, group.label+": "+strings.Join(group.names, ", "))
+			}
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", f.Path, strings.Join(parts, "; "))
+	}
+	return b.String()
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// commitTemplate is a named message for a repetitive kind of commit, such
+// as a release, selected with -t. Placeholders in braces ({version}) are
+// filled from flags given after -t (--version 1.2.3) or asked for.
+type commitTemplate struct {
+	Message string `json:"message"`
+	// Placeholders describe the values the message needs. Any placeholder
+	// not described is still required, and asked for by name.
+	Placeholders []templatePlaceholder `json:"placeholders,omitempty"`
+	// Generate sends the filled message to the API as the starting point
+	// for the commit message rather than committing it as it is.
+	Generate bool `json:"generate,omitempty"`
+}
+
+// templatePlaceholder describes one value a template needs.
+type templatePlaceholder struct {
+	Name string `json:"name"`
+	// Default is used when the value isn't given; without one the value is
+	// required.
+	Default string `json:"default,omitempty"`
+	// Pattern, when set, is a regular expression the value must match.
+	Pattern string `json:"pattern,omitempty"`
+	// Question is asked for the value; the default names the placeholder.
+	Question string `json:"question,omitempty"`
+}
+
+// placeholderRe matches a {name} placeholder in a template message.
+var placeholderRe = regexp.MustCompile(`\{([A-Za-z][A-Za-z0-9_-]*)\}`)
+
+// templateValues are the placeholder values taken off the command line by
+// splitTemplateArgs.
+var templateValues map[string]string
+
+// activeTemplate is the template selected with -t and its values so far.
+type activeTemplate struct {
+	Name string
+	*commitTemplate
+	Values map[string]string
+}
+
+// splitTemplateArgs takes the values for -t's placeholders out of the
+// command line, so the flag package doesn't reject them as unknown flags.
+// Any -name value or -name=value that isn't one of gitcommit's own flags is
+// a placeholder value; the command line is left alone without -t.
+func splitTemplateArgs(args []string) ([]string, map[string]string, error) {
+	var rest []string
+	values := map[string]string{}
+	selected := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-") {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if f := flag.CommandLine.Lookup(name); f != nil {
+			rest = append(rest, arg)
+			if name == "t" {
+				selected = true
+			}
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && b.IsBoolFlag()) && i+1 < len(args) {
+				rest = append(rest, args[i+1])
+				i++
+			}
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("--%s needs a value", name)
+			}
+			value = args[i+1]
+			i++
+		}
+		values[name] = value
+	}
+	if !selected {
+		return args, nil, nil
+	}
+	return rest, values, nil
+}
+
+// templateArgs returns placeholder values as flags, for running gitcommit
+// again with them.
+func templateArgs(values map[string]string) []string {
+	var args []string
+	for name, value := range values {
+		args = append(args, "--"+name+"="+value)
+	}
+	sort.Strings(args)
+	return args
+}
+
+// selectTemplate looks up the template named by -t and checks the values
+// given for it, so mistakes are reported before any work is done.
+func selectTemplate(templates map[string]*commitTemplate, name string, values map[string]string) (*activeTemplate, error) {
+	t, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q", name)
+	}
+	if err := t.validate(name); err != nil {
+		return nil, err
+	}
+	names := t.placeholders()
+	for given := range values {
+		if !containsString(names, given) {
+			return nil, fmt.Errorf("template %s has no placeholder {%s}", name, given)
+		}
+	}
+	a := &activeTemplate{Name: name, commitTemplate: t, Values: map[string]string{}}
+	for _, p := range names {
+		value, ok := values[p]
+		if !ok {
+			value, ok = a.placeholder(p).Default, a.placeholder(p).Default != ""
+		}
+		if !ok {
+			continue
+		}
+		if err := a.check(p, value); err != nil {
+			return nil, err
+		}
+		a.Values[p] = value
+	}
+	return a, nil
+}
+
+// validate checks that the template has a message and that its patterns
+// compile.
+func (t *commitTemplate) validate(name string) error {
+	if strings.TrimSpace(t.Message) == "" {
+		return fmt.Errorf("template %s has no message", name)
+	}
+	names := t.placeholders()
+	for _, p := range t.Placeholders {
+		if !containsString(names, p.Name) {
+			return fmt.Errorf("template %s: placeholder %s isn't used in the message", name, p.Name)
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("template %s, placeholder %s: bad pattern: %v", name, p.Name, err)
+		}
+	}
+	return nil
+}
+
+// placeholders returns the names used in the message, in order.
+func (t *commitTemplate) placeholders() []string {
+	var names []string
+	for _, m := range placeholderRe.FindAllStringSubmatch(t.Message, -1) {
+		if !containsString(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+func (t *commitTemplate) placeholder(name string) templatePlaceholder {
+	for _, p := range t.Placeholders {
+		if p.Name == name {
+			return p
+		}
+	}
+	return templatePlaceholder{Name: name}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *activeTemplate) check(name, value string) error {
+	p := a.placeholder(name)
+	if value == "" {
+		return fmt.Errorf("template %s needs a value for {%s}", a.Name, name)
+	}
+	if !regexp.MustCompile(p.Pattern).MatchString(value) {
+		return fmt.Errorf("%q doesn't look like a valid %s (expected to match %s)", value, name, p.Pattern)
+	}
+	return nil
+}
+
+// missing returns the placeholders with no value yet, as a list of the
+// flags that would give them.
+func (a *activeTemplate) missing() []string {
+	var flags []string
+	for _, p := range a.placeholders() {
+		if _, ok := a.Values[p]; !ok {
+			flags = append(flags, "--"+p)
+		}
+	}
+	return flags
+}
+
+// askMissing asks for each value not given until it is valid.
+func (a *activeTemplate) askMissing() {
+	for _, name := range a.placeholders() {
+		if _, ok := a.Values[name]; ok {
+			continue
+		}
+		question := a.placeholder(name).Question
+		if question == "" {
+			question = fmt.Sprintf("%s (for the %s template)", name, a.Name)
+		}
+		for {
+			value := getUserInput(question + ": ")
+			if err := a.check(name, value); err != nil {
+				fmt.Printf("%v.\n", err)
+				continue
+			}
+			a.Values[name] = value
+			break
+		}
+	}
+}
+
+// message returns the template with its placeholders filled in.
+func (a *activeTemplate) message() string {
+	return placeholderRe.ReplaceAllStringFunc(a.Message, func(m string) string {
+		return a.Values[m[1:len(m)-1]]
+	})
+}
+
+func (a *activeTemplate) prompt() string {
+	return fmt.Sprintf("This is a %s commit, and the message must follow this template: keep its wording and values as given, and fill in anything it leaves open from the changes:\n```\n%s\n```", a.Name, a.message())
+}
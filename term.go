@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Everything that depends on what the terminal can do goes through here,
+// so dumb terminals and slow links get plain line-based output: no raw
+// key reading, no full-screen editor and no escape sequences.
+
+// dumbTerminal reports whether TERM says the terminal can't handle
+// cursor movement or colors.
+func dumbTerminal() bool {
+	return os.Getenv("TERM") == "dumb"
+}
+
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// rawKeysSupported reports whether single keys can be read without
+// waiting for Enter: an interactive, capable terminal outside Windows.
+func rawKeysSupported() bool {
+	return runtime.GOOS != "windows" && stdinIsTerminal() && stdoutIsTerminal() && !dumbTerminal()
+}
+
+// fullScreenSupported reports whether a full-screen editor such as vim can
+// be run; otherwise text is edited line by line.
+func fullScreenSupported() bool {
+	return !dumbTerminal()
+}
+
+// colorMode is -color: "auto", "always" or "never".
+var colorMode = "auto"
+
+// setColorMode applies -color, or -no-color as "never".
+func setColorMode(mode string) error {
+	switch mode {
+	case "auto", "always", "never":
+		colorMode = mode
+		return nil
+	}
+	return fmt.Errorf("-color must be auto, always or never, not %q", mode)
+}
+
+// colorEnabled reports whether output may carry colors and other escape
+// sequences. In auto mode that takes a capable terminal and no NO_COLOR
+// (https://no-color.org) in the environment; -color always and never
+// override both.
+func colorEnabled() bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && !dumbTerminal() && stdoutIsTerminal()
+}
+
+// plainOutput reports whether escape sequences must be kept out of the
+// output: whenever colors are off.
+func plainOutput() bool {
+	return !colorEnabled()
+}
+
+const (
+	colorRed   = "31"
+	colorGreen = "32"
+)
+
+// colorize wraps s in the given SGR color when colors are enabled.
+func colorize(color, s string) string {
+	if !colorEnabled() || s == "" {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// colorDiffLine colors a lineDiff line by whether it was removed or added.
+func colorDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "- "):
+		return colorize(colorRed, line)
+	case strings.HasPrefix(line, "+ "):
+		return colorize(colorGreen, line)
+	}
+	return line
+}
+
+// plainWriter drops escape sequences from text written through it, such
+// as ones in a streamed response.
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p plainWriter) Write(b []byte) (int, error) {
+	s := strings.ReplaceAll(ansiRe.ReplaceAllString(string(b), ""), "\x1b", "")
+	if _, err := io.WriteString(p.w, s); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// terminalOutput is where text from outside gitcommit, such as a streamed
+// response, is shown.
+func terminalOutput() io.Writer {
+	if plainOutput() {
+		return plainWriter{os.Stdout}
+	}
+	return os.Stdout
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// strategyImportance keeps the most important hunks when the diff is over
+// budget and summarizes the rest; see rankHunk.
+const strategyImportance = "importance"
+
+// smallFileBytes is the diff size below which a file's hunks are kept or
+// summarized together, since splitting them saves little and loses a lot.
+const smallFileBytes = 2048
+
+// Triage actions for -show-triage.
+const (
+	triageKept       = "kept"
+	triageSummarized = "summarized"
+	triageDropped    = "dropped"
+)
+
+// hunkTriage is the decision about one hunk (or one small file).
+type hunkTriage struct {
+	Path    string
+	Header  string
+	Score   int
+	Reasons []string
+	Action  string
+	text    string
+}
+
+var (
+	controlFlowRe = regexp.MustCompile(`\b(if|else|for|while|switch|case|select|go|defer|return|break|continue|goto)\b`)
+	errorRe       = regexp.MustCompile(`\berr\b|\berror\b|\bErr[A-Z]\w*|\b(try|catch|except|raise|throw|panic|recover|finally)\b`)
+	importLineRe  = regexp.MustCompile(`^\s*(import\b|from \S+ import\b|#include\b|require\b|use\b|using\b|"[^"]*"$|\)$|\($)`)
+)
+
+// configExts are files whose changes matter less than code.
+var configExts = map[string]bool{
+	".json": true, ".yaml": true, ".yml": true, ".toml": true, ".ini": true,
+	".cfg": true, ".conf": true, ".xml": true, ".properties": true, ".env": true,
+}
+
+// isGeneratedPath reports whether a file is a lock file or otherwise
+// machine-written, whose hunks are dropped before anything else.
+func isGeneratedPath(p string) bool {
+	base := path.Base(p)
+	return base == "go.sum" || strings.HasSuffix(base, ".lock") || base == "package-lock.json" ||
+		base == "pnpm-lock.yaml" || strings.Contains(base, ".pb.") || strings.HasSuffix(base, ".min.js")
+}
+
+// splitHunks splits one file's diff into its header and its hunks, as
+// slices of text.
+func splitHunks(text string) (header string, hunks []string) {
+	start := -1
+	if strings.HasPrefix(text, "@@") {
+		start = 0
+	} else if i := strings.Index(text, "\n@@"); i >= 0 {
+		start = i + 1
+	}
+	if start < 0 {
+		return text, nil
+	}
+	header, rest := text[:start], text[start:]
+	for rest != "" {
+		end := len(rest)
+		if i := strings.Index(rest, "\n@@"); i >= 0 {
+			end = i + 1
+		}
+		hunks = append(hunks, rest[:end])
+		rest = rest[end:]
+	}
+	return header, hunks
+}
+
+// rankHunk scores how much a hunk matters for describing the change:
+// non-test code over tests, code over config and docs, new control flow
+// and error handling over pure deletions and import shuffles.
+func rankHunk(p, hunk string) (int, []string) {
+	score := 10
+	var reasons []string
+	switch {
+	case isGeneratedPath(p):
+		return -10, []string{"generated or lock file"}
+	case isTestPath(p):
+		score -= 4
+		reasons = append(reasons, "test")
+	case isDocsPath(p):
+		score -= 3
+		reasons = append(reasons, "docs")
+	case configExts[path.Ext(p)]:
+		score -= 3
+		reasons = append(reasons, "config")
+	}
+
+	var added, deleted, imports int
+	flow, errs := false, false
+	for _, line := range strings.Split(hunk, "\n") {
+		if len(line) == 0 || (line[0] != '+' && line[0] != '-') {
+			continue
+		}
+		body := line[1:]
+		if line[0] == '+' {
+			added++
+			flow = flow || controlFlowRe.MatchString(body)
+			errs = errs || errorRe.MatchString(body)
+		} else {
+			deleted++
+		}
+		if importLineRe.MatchString(body) || strings.TrimSpace(body) == "" {
+			imports++
+		}
+	}
+	switch {
+	case added+deleted > 0 && imports == added+deleted:
+		score -= 5
+		reasons = append(reasons, "imports or blank lines only")
+	case added == 0:
+		score -= 2
+		reasons = append(reasons, "deletion only")
+	}
+	if flow {
+		score += 3
+		reasons = append(reasons, "new control flow")
+	}
+	if errs {
+		score += 2
+		reasons = append(reasons, "error handling")
+	}
+	return score, reasons
+}
+
+// triageHunks ranks every hunk of the diff. The hunks of a small file are
+// merged into one, ranked by the best of them, so the file is kept or
+// summarized whole.
+func triageHunks(files []fileDiff) (headers []string, units [][]*hunkTriage) {
+	for _, f := range files {
+		header, hunks := splitHunks(f.Text)
+		headers = append(headers, header)
+		var unit []*hunkTriage
+		for _, h := range hunks {
+			score, reasons := rankHunk(f.Path, h)
+			first, _, _ := strings.Cut(h, "\n")
+			unit = append(unit, &hunkTriage{Path: f.Path, Header: first, Score: score, Reasons: reasons, text: h})
+		}
+		if len(f.Text) < smallFileBytes && len(unit) > 1 {
+			best := unit[0]
+			for _, h := range unit[1:] {
+				if h.Score > best.Score {
+					best = h
+				}
+			}
+			whole := &hunkTriage{Path: f.Path, Header: fmt.Sprintf("%s (and %d more hunk(s))", unit[0].Header, len(unit)-1), Score: best.Score}
+			whole.Reasons = append([]string{"small file kept whole"}, best.Reasons...)
+			for _, h := range unit {
+				whole.text += h.text
+			}
+			unit = []*hunkTriage{whole}
+		}
+		units = append(units, unit)
+	}
+	return headers, units
+}
+
+// summarizeHunk replaces a hunk with a one-line note.
+func summarizeHunk(h *hunkTriage) string {
+	var added, deleted int
+	for _, line := range strings.Split(h.text, "\n")[1:] {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			deleted++
+		}
+	}
+	first, _, _ := strings.Cut(h.text, "\n")
+	return fmt.Sprintf("%s\n[hunk omitted to fit the size limit: +%d -%d lines]\n", first, added, deleted)
+}
+
+// triageDiff shortens a diff to roughly budget bytes hunk by hunk: the
+// highest-ranked hunks are kept in full, others are summarized in place,
+// and generated files are dropped to a one-line summary. The prompt is told
+// how the diff was trimmed. The decisions are returned for -show-triage.
+func triageDiff(diff string, budget int) (string, []*hunkTriage) {
+	files := splitDiff(diff)
+	headers, units := triageHunks(files)
+	var all []*hunkTriage
+	for _, unit := range units {
+		all = append(all, unit...)
+	}
+	if budget <= 0 || len(diff) <= budget {
+		for _, h := range all {
+			h.Action = triageKept
+		}
+		return diff, all
+	}
+
+	// Start from everything summarized or dropped, then keep hunks in rank
+	// order while the budget allows.
+	used := 0
+	for i, unit := range units {
+		if len(unit) > 0 && isGeneratedPath(files[i].Path) {
+			used += len(summarizeFileDiff(files[i]))
+			for _, h := range unit {
+				h.Action = triageDropped
+			}
+			continue
+		}
+		used += len(headers[i])
+		for _, h := range unit {
+			h.Action = triageSummarized
+			used += len(summarizeHunk(h))
+		}
+	}
+	order := make([]*hunkTriage, 0, len(all))
+	for _, h := range all {
+		if h.Action == triageSummarized {
+			order = append(order, h)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return order[a].Score > order[b].Score })
+	for _, h := range order {
+		extra := len(h.text) - len(summarizeHunk(h))
+		if used+extra > budget {
+			continue
+		}
+		h.Action = triageKept
+		used += extra
+	}
+
+	kept, dropped := 0, 0
+	var b strings.Builder
+	for i, unit := range units {
+		if len(unit) > 0 && unit[0].Action == triageDropped {
+			b.WriteString(summarizeFileDiff(files[i]))
+			dropped++
+			continue
+		}
+		b.WriteString(headers[i])
+		for _, h := range unit {
+			if h.Action == triageKept {
+				b.WriteString(h.text)
+				kept++
+			} else {
+				b.WriteString(summarizeHunk(h))
+			}
+		}
+	}
+	note := fmt.Sprintf("[The diff was trimmed to fit the size limit. Hunks were ranked by importance (code over tests, config and docs; new control flow and error handling over deletions and imports): %d of %d hunks are shown in full and the rest are summarized in place", kept, len(all))
+	if dropped > 0 {
+		note += fmt.Sprintf("; %d generated or lock file(s) are omitted", dropped)
+	}
+	return note + ".]\n\n" + b.String(), all
+}
+
+// trimmedHunks counts the hunks that were not kept in full.
+func trimmedHunks(triage []*hunkTriage) int {
+	n := 0
+	for _, h := range triage {
+		if h.Action != triageKept {
+			n++
+		}
+	}
+	return n
+}
+
+// printTriage implements -show-triage.
+func printTriage(triage []*hunkTriage) {
+	if trimmedHunks(triage) == 0 {
+		fmt.Println("Hunk triage: the diff fits the size limit; every hunk is sent in full.")
+		return
+	}
+	fmt.Println("Hunk triage (score, action, hunk):")
+	for _, h := range triage {
+		fmt.Printf("  %3d  %-10s %s %s", h.Score, h.Action, h.Path, h.Header)
+		if len(h.Reasons) > 0 {
+			fmt.Printf("  (%s)", strings.Join(h.Reasons, ", "))
+		}
+		fmt.Println()
+	}
+}
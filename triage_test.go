@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// hunkDiff is a one-hunk diff of path adding (or, with a leading "-",
+// deleting) the given lines.
+func hunkDiff(path string, lines ...string) string {
+	var b strings.Builder
+	b.WriteString("diff --git a/" + path + " b/" + path + "\n--- a/" + path + "\n+++ b/" + path + "\n@@ -1,1 +1,9 @@\n")
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "-") {
+			line = "+" + line
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func TestRankHunkOrder(t *testing.T) {
+	// From most to least important.
+	hunks := []struct{ name, path, diff string }{
+		{"error handling", "server.go", hunkDiff("server.go", "if err != nil {", "\treturn err", "}")},
+		{"control flow", "server.go", hunkDiff("server.go", "for _, c := range conns {", "\tc.Close()", "}")},
+		{"plain code", "server.go", hunkDiff("server.go", "x := 1")},
+		{"deletion", "server.go", hunkDiff("server.go", "-x := 1")},
+		{"config", "deploy.yaml", hunkDiff("deploy.yaml", "replicas: 3")},
+		{"test", "server_test.go", hunkDiff("server_test.go", "x := 1")},
+		{"imports", "server.go", hunkDiff("server.go", `import "fmt"`)},
+		{"lock file", "go.sum", hunkDiff("go.sum", "example.com/m v1.0.0 h1:abc=")},
+	}
+	prev := 1 << 30
+	prevName := ""
+	for _, h := range hunks {
+		_, parts := splitHunks(h.diff)
+		score, reasons := rankHunk(h.path, parts[0])
+		if score >= prev {
+			t.Errorf("%s (%d, %v) ranks at least as high as %s (%d)", h.name, score, reasons, prevName, prev)
+		}
+		prev, prevName = score, h.name
+	}
+}
+
+func TestTriageDiffKeepsHighestRanked(t *testing.T) {
+	pad := strings.Repeat("x", smallFileBytes)
+	important := hunkDiff("server.go", "if err != nil {", "\treturn err", "}", "// "+pad)
+	minor := hunkDiff("README.md", "More words. "+pad)
+	lock := hunkDiff("go.sum", "example.com/m v1.0.0 h1:"+pad)
+	diff := minor + lock + important
+
+	// Room for one hunk in full.
+	out, triage := triageDiff(diff, len(important)+600)
+	actions := map[string]string{}
+	for _, h := range triage {
+		actions[h.Path] = h.Action
+	}
+	want := map[string]string{"server.go": triageKept, "README.md": triageSummarized, "go.sum": triageDropped}
+	for p, a := range want {
+		if actions[p] != a {
+			t.Errorf("%s: action %q, want %q", p, actions[p], a)
+		}
+	}
+	if !strings.Contains(out, "return err") || strings.Contains(out, "More words") || strings.Contains(out, "h1:") {
+		t.Errorf("trimmed diff has the wrong hunks:\n%s", out)
+	}
+	if !strings.HasPrefix(out, "[The diff was trimmed") || !strings.Contains(out, "1 of 3 hunks") || !strings.Contains(out, "1 generated or lock file(s)") {
+		t.Errorf("trimmed diff note is wrong:\n%.300s", out)
+	}
+	// Files stay in their original order.
+	if strings.Index(out, "README.md") > strings.Index(out, "server.go") {
+		t.Error("the triaged diff reordered the files")
+	}
+}
+
+func TestTriageDiffUnderBudget(t *testing.T) {
+	diff := hunkDiff("a.go", "x := 1") + hunkDiff("go.sum", "m v1 h1:abc=")
+	out, triage := triageDiff(diff, len(diff))
+	if out != diff || trimmedHunks(triage) != 0 {
+		t.Errorf("a diff within the budget was changed: %q", out)
+	}
+}
+
+func TestTriageSmallFileKeptWhole(t *testing.T) {
+	diff := hunkDiff("a.go", "x := 1") + "@@ -20,1 +20,2 @@\n+if x {\n+}\n"
+	_, units := triageHunks(splitDiff(diff))
+	if len(units) != 1 || len(units[0]) != 1 {
+		t.Fatalf("units = %v, want one merged unit", units)
+	}
+	if h := units[0][0]; !strings.Contains(h.Header, "1 more hunk") || h.Reasons[0] != "small file kept whole" {
+		t.Errorf("merged unit = %+v", h)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultMaxDiffBytes keeps the diff comfortably within the model's context
+// window while leaving room for the rest of the prompt.
+const defaultMaxDiffBytes = 100000
+
+// Truncation strategies for diffs over the size budget.
+const (
+	// strategyLargestFirst summarizes the largest files first, keeping as
+	// many small files in full as possible.
+	strategyLargestFirst = "largest-first"
+	// strategyPathOrder keeps files in path order until the budget runs out.
+	strategyPathOrder = "path-order"
+)
+
+// fileDiff is the part of a unified diff belonging to one file.
+type fileDiff struct {
+	Path string
+	Text string
+}
+
+// splitDiff splits a `git diff` into per-file chunks. The chunks are
+// slices of diff, so splitting a large diff costs no copying.
+func splitDiff(diff string) []fileDiff {
+	if diff == "" {
+		return []fileDiff{{}}
+	}
+	var files []fileDiff
+	for rest := diff; rest != ""; {
+		end := len(rest)
+		if i := strings.Index(rest, "\ndiff --git "); i >= 0 {
+			end = i + 1
+		}
+		header, _, _ := strings.Cut(rest[:end], "\n")
+		files = append(files, fileDiff{Path: diffPath(header), Text: rest[:end]})
+		rest = rest[end:]
+	}
+	return files
+}
+
+// diffPath extracts the destination path from a "diff --git a/x b/x" line.
+func diffPath(header string) string {
+	header = strings.TrimSpace(strings.TrimPrefix(header, "diff --git "))
+	if i := strings.LastIndex(header, " b/"); i >= 0 {
+		return header[i+3:]
+	}
+	return header
+}
+
+// summarizeFileDiff replaces a file's diff with a one-line summary.
+func summarizeFileDiff(f fileDiff) string {
+	var added, deleted int
+	for _, line := range strings.Split(f.Text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			deleted++
+		}
+	}
+	return fmt.Sprintf("diff --git a/%s b/%s\n[diff omitted to fit the size limit: +%d -%d lines]\n", f.Path, f.Path, added, deleted)
+}
+
+// selectFileDiffs decides which files keep their full diff within budget
+// bytes. The returned set is indexed like files.
+func selectFileDiffs(files []fileDiff, budget int, strategy string) []bool {
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	if strategy == strategyLargestFirst {
+		sort.SliceStable(order, func(a, b int) bool {
+			return len(files[order[a]].Text) < len(files[order[b]].Text)
+		})
+	}
+
+	keep := make([]bool, len(files))
+	used := 0
+	for _, i := range order {
+		if used+len(files[i].Text) > budget {
+			if strategy == strategyPathOrder {
+				// Keep a prefix in path order; everything after the first
+				// file that doesn't fit is summarized.
+				break
+			}
+			continue
+		}
+		keep[i] = true
+		used += len(files[i].Text)
+	}
+	return keep
+}
+
+// truncateDiff shortens a diff to roughly budget bytes by summarizing whole
+// files according to strategy, or hunks for strategyImportance. It reports
+// how many files (or hunks) were summarized.
+func truncateDiff(diff string, budget int, strategy string) (string, int) {
+	if budget <= 0 || len(diff) <= budget {
+		return diff, 0
+	}
+	if strategy == strategyImportance {
+		trimmed, triage := triageDiff(diff, budget)
+		return trimmed, trimmedHunks(triage)
+	}
+	files := splitDiff(diff)
+	keep := selectFileDiffs(files, budget, strategy)
+
+	var b strings.Builder
+	summarized := 0
+	for i, f := range files {
+		if keep[i] {
+			b.WriteString(f.Text)
+		} else {
+			b.WriteString(summarizeFileDiff(f))
+			summarized++
+		}
+	}
+	return b.String(), summarized
+}
+
+func validTruncateStrategy(s string) bool {
+	return s == strategyImportance || s == strategyLargestFirst || s == strategyPathOrder
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeFileDiff returns a diff for path adding n lines.
+func fakeFileDiff(path string, n int) string {
+	var b strings.Builder
+	b.WriteString("diff --git a/" + path + " b/" + path + "\n--- a/" + path + "\n+++ b/" + path + "\n@@ -0,0 +1 @@\n")
+	for i := 0; i < n; i++ {
+		b.WriteString("+line\n")
+	}
+	return b.String()
+}
+
+func TestSplitDiff(t *testing.T) {
+	diff := fakeFileDiff("a.go", 1) + fakeFileDiff("dir/b c.go", 2)
+	files := splitDiff(diff)
+	if len(files) != 2 || files[0].Path != "a.go" || files[1].Path != "dir/b c.go" {
+		t.Fatalf("splitDiff paths = %v", files)
+	}
+	if files[0].Text+files[1].Text != diff {
+		t.Error("the chunks don't add up to the diff")
+	}
+}
+
+func TestSelectFileDiffs(t *testing.T) {
+	sizes := func(n ...int) []fileDiff {
+		var files []fileDiff
+		for _, size := range n {
+			files = append(files, fileDiff{Text: strings.Repeat("x", size)})
+		}
+		return files
+	}
+	tests := []struct {
+		name     string
+		files    []fileDiff
+		budget   int
+		strategy string
+		want     []bool
+	}{
+		{"all fit", sizes(10, 20, 30), 60, strategyLargestFirst, []bool{true, true, true}},
+		{"largest summarized", sizes(10, 50, 20), 40, strategyLargestFirst, []bool{true, false, true}},
+		{"smallest kept first", sizes(30, 5, 30, 5), 45, strategyLargestFirst, []bool{true, true, false, true}},
+		{"nothing fits", sizes(50, 60), 40, strategyLargestFirst, []bool{false, false}},
+		{"ties keep path order", sizes(20, 20, 20), 40, strategyLargestFirst, []bool{true, true, false}},
+		{"path order prefix", sizes(10, 50, 20), 40, strategyPathOrder, []bool{true, false, false}},
+		{"path order all fit", sizes(10, 20), 30, strategyPathOrder, []bool{true, true}},
+		{"path order first too big", sizes(50, 1), 40, strategyPathOrder, []bool{false, false}},
+	}
+	for _, tt := range tests {
+		if got := selectFileDiffs(tt.files, tt.budget, tt.strategy); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: selectFileDiffs = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	small, big := fakeFileDiff("small.go", 2), fakeFileDiff("big.go", 200)
+	diff := big + small
+
+	if got, n := truncateDiff(diff, len(diff), strategyLargestFirst); got != diff || n != 0 {
+		t.Errorf("within budget: changed the diff (%d summarized)", n)
+	}
+
+	got, n := truncateDiff(diff, len(small)+100, strategyLargestFirst)
+	if n != 1 {
+		t.Errorf("largest-first: %d summarized, want 1", n)
+	}
+	if !strings.Contains(got, small) {
+		t.Errorf("largest-first: small.go is not kept in full:\n%s", got)
+	}
+	if !strings.Contains(got, "diff --git a/big.go b/big.go\n[diff omitted to fit the size limit: +200 -0 lines]\n") {
+		t.Errorf("largest-first: big.go is not summarized:\n%s", got)
+	}
+
+	// big.go comes first and doesn't fit, so path order summarizes both.
+	if _, n := truncateDiff(diff, len(small)+100, strategyPathOrder); n != 2 {
+		t.Errorf("path-order: %d summarized, want 2", n)
+	}
+}
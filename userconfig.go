@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// userConfig is gitcommit's per-user configuration, shared by all
+// repositories.
+type userConfig struct {
+	// Prefs are standing instructions appended to the system prompt.
+	Prefs []string `json:"prefs,omitempty"`
+	// ReadOnly turns on -read-only for every run.
+	ReadOnly bool `json:"read_only,omitempty"`
+	// StatsdAddr, when set, enables per-run metrics sent to this
+	// host:port. Metrics are strictly opt-in.
+	StatsdAddr string `json:"statsd_addr,omitempty"`
+	// Webhook, when set, is posted to after every commit; see webhook.go.
+	Webhook *webhookConfig `json:"webhook,omitempty"`
+	// Keyring reads the API key from the OS credential store.
+	Keyring bool `json:"keyring,omitempty"`
+	// NotesRef is the notes ref used by -notes.
+	NotesRef string `json:"notes_ref,omitempty"`
+	// Scanner, when set, runs an external secret scanner over everything
+	// before it is sent to the API.
+	Scanner *scannerConfig `json:"secret_scanner,omitempty"`
+	// Sections are the body sections used by -sections, in order.
+	Sections []string `json:"sections,omitempty"`
+	// APIKeys are several keys to fail over between; see keys.go.
+	APIKeys []string `json:"api_keys,omitempty"`
+	// KeyRotation is "failover" (default) or "round-robin".
+	KeyRotation string `json:"key_rotation,omitempty"`
+	// AbortPhrases replace the default words (":q", "abort") that end the
+	// run when typed at a prompt.
+	AbortPhrases []string `json:"abort_phrases,omitempty"`
+	// SubjectPrefix is the default -subject-prefix template.
+	SubjectPrefix string `json:"subject_prefix,omitempty"`
+	// PrefixOrder is the default -prefix-order.
+	PrefixOrder string `json:"prefix_order,omitempty"`
+	// BlankLines is the default -blank-lines rule list.
+	BlankLines string `json:"blank_lines,omitempty"`
+	// FileHints map file patterns (.sql, _test.go, migrations/*) to notes
+	// added to the prompt when the change touches matching files.
+	FileHints map[string]string `json:"file_hints,omitempty"`
+	// BodyLimits cap the message body for small changes; see
+	// bodylimit.go. An empty list turns the caps off.
+	BodyLimits []bodyLimit `json:"body_limits,omitempty"`
+	// Language overrides the locale for prompts and messages (de, es, ja,
+	// zh); see i18n.go.
+	Language string `json:"language,omitempty"`
+	// Kinds are user-defined commit kinds; see kinds.go.
+	Kinds map[string]*commitKind `json:"kinds,omitempty"`
+	// Templates are named messages for -t; see templates.go.
+	Templates map[string]*commitTemplate `json:"templates,omitempty"`
+	// Profiles are named bundles of account settings; see profile.go.
+	Profiles map[string]*profile `json:"profiles,omitempty"`
+
+	// active is the profile applied by applyProfile, if any.
+	active *activeProfile
+}
+
+func (c *userConfig) notesRef() string {
+	if c.NotesRef != "" {
+		return c.NotesRef
+	}
+	return defaultNotesRef
+}
+
+// notesRefFromConfig returns the configured notes ref for subcommands.
+func notesRefFromConfig() string {
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return defaultNotesRef
+	}
+	return cfg.notesRef()
+}
+
+// configOverride is the config file named by -config; it replaces the
+// default location for both reading and saving.
+var configOverride string
+
+func userConfigPath() (string, error) {
+	if configOverride != "" {
+		return configOverride, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating config directory: %v", err)
+	}
+	return filepath.Join(dir, "gitcommit", "config.json"), nil
+}
+
+func loadUserConfig() (*userConfig, error) {
+	p, err := userConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &userConfig{}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %v", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %v", p, err)
+	}
+	return cfg, nil
+}
+
+// useConfigFile makes path the user config for this run. Unlike the
+// default location, the file must exist and parse.
+func useConfigFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("-config: %v", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("-config: %s is a directory", path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	configOverride = path
+	_, err = loadUserConfig()
+	return err
+}
+
+// saveUserConfig writes the config readable only by its owner, since it
+// can hold API keys. A file left readable by an earlier version is
+// tightened on the way.
+func saveUserConfig(cfg *userConfig) error {
+	p, err := userConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return fmt.Errorf("error creating config directory: %v", err)
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding config: %v", err)
+	}
+	// WriteFile only applies the mode to a new file, so an existing one is
+	// tightened before the keys are written to it.
+	if err := os.Chmod(p, 0600); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error restricting config permissions: %v", err)
+	}
+	if err := os.WriteFile(p, data, 0600); err != nil {
+		return fmt.Errorf("error writing config: %v", err)
+	}
+	return nil
+}
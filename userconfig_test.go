@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSaveUserConfigPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no Unix permission bits")
+	}
+	defer func(old string) { configOverride = old }(configOverride)
+	dir := filepath.Join(t.TempDir(), "gitcommit")
+	configOverride = filepath.Join(dir, "config.json")
+
+	if err := saveUserConfig(&userConfig{APIKeys: []string{"sk-one", "sk-two"}}); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]os.FileMode{dir: 0700, configOverride: 0600} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s: mode %v, want %v", path, got, want)
+		}
+	}
+
+	// A config written world-readable before is tightened on save.
+	if err := os.Chmod(configOverride, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveUserConfig(&userConfig{Prefs: []string{"Be brief"}}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(configOverride)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("existing config: mode %v, want 0600", got)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds the whole webhook delivery, secret lookup
+	// included. Like metrics, it is fire-and-forget: a slow or failing
+	// endpoint never holds up or fails a commit.
+	webhookTimeout = 2 * time.Second
+	// defaultWebhookAccount is the keyring account holding the shared
+	// secret, as stored by `gitcommit keyring set webhook`.
+	defaultWebhookAccount = "GITCOMMIT_WEBHOOK_SECRET"
+	// webhookSignatureHeader carries the hex HMAC-SHA256 of the request
+	// body, in the "sha256=..." form GitHub's webhooks use.
+	webhookSignatureHeader = "X-Gitcommit-Signature"
+)
+
+// webhookConfig is the "webhook" table of the user config.
+type webhookConfig struct {
+	URL string `json:"url"`
+	// SecretAccount overrides the keyring account the secret is read from.
+	SecretAccount string `json:"secret_account,omitempty"`
+	// IncludeMessage and IncludeDiff add the commit message and the
+	// committed diff to the payload. Both are off unless set.
+	IncludeMessage bool `json:"include_message,omitempty"`
+	IncludeDiff    bool `json:"include_diff,omitempty"`
+}
+
+func (w *webhookConfig) secretAccount() string {
+	if w.SecretAccount != "" {
+		return w.SecretAccount
+	}
+	return defaultWebhookAccount
+}
+
+// webhookPayload is what is posted after a commit. The message and diff
+// are only there when the config asks for them.
+type webhookPayload struct {
+	Repo          string       `json:"repo"`
+	Branch        string       `json:"branch"`
+	Commit        string       `json:"commit"`
+	Model         string       `json:"model"`
+	PromptVersion string       `json:"prompt_version"`
+	Usage         webhookUsage `json:"usage"`
+	Time          string       `json:"time"`
+	Message       string       `json:"message,omitempty"`
+	Diff          string       `json:"diff,omitempty"`
+}
+
+type webhookUsage struct {
+	Requests     int `json:"requests"`
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// newWebhookPayload describes the commit just made at HEAD.
+func newWebhookPayload(w *webhookConfig, m *runMetrics, message string) webhookPayload {
+	p := webhookPayload{
+		Branch:        currentBranch(),
+		Model:         m.Model,
+		PromptVersion: m.PromptVersion,
+		Usage:         webhookUsage{m.Usage.Requests, m.Usage.InputTokens, m.Usage.OutputTokens},
+		Time:          time.Now().UTC().Format(time.RFC3339),
+	}
+	if url, err := gitRun("remote", "get-url", "origin"); err == nil {
+		// normalizeRemote drops any credentials in the URL.
+		p.Repo = normalizeRemote(url)
+	}
+	p.Commit, _ = gitRun("rev-parse", "HEAD")
+	if w.IncludeMessage {
+		p.Message = message
+	}
+	if w.IncludeDiff {
+		p.Diff, _, _ = readCapped(gitCommand("show", "--format=", "HEAD"), maxDiffReadBytes)
+	}
+	return p
+}
+
+// signWebhook returns the signature header value for body.
+func signWebhook(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook signs and posts the payload.
+func postWebhook(w *webhookConfig, p webhookPayload) error {
+	secret, err := keyringGet(w.secretAccount())
+	if err != nil {
+		return fmt.Errorf("webhook not sent, no secret: %v", err)
+	}
+	if secret == "" {
+		return fmt.Errorf("webhook not sent, no secret in the keyring (run gitcommit keyring set webhook)")
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("error encoding webhook payload: %v", err)
+	}
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gitcommit")
+	req.Header.Set(webhookSignatureHeader, signWebhook(body, secret))
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending webhook: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sendWebhook starts delivering the webhook in the background and returns
+// a function that waits for it, at most webhookTimeout from now, so the
+// rest of the run goes on meanwhile and a hung endpoint can't keep
+// gitcommit from exiting.
+func sendWebhook(w *webhookConfig, m *runMetrics, message string) (wait func()) {
+	deadline := time.Now().Add(webhookTimeout)
+	p := newWebhookPayload(w, m, message)
+	done := make(chan error, 1)
+	go func() { done <- postWebhook(w, p) }()
+	return func() {
+		select {
+		case err := <-done:
+			if err != nil {
+				say("Warning: %v\n", err)
+			}
+		case <-time.After(time.Until(deadline)):
+			say("Warning: webhook not delivered within %s\n", webhookTimeout)
+		}
+	}
+}